@@ -0,0 +1,57 @@
+// Command fakepay is a stand-in payment provider for local development. It
+// serves the API payment.HTTPGateway talks to and posts signed webhooks
+// for every capture, void, refund and expiry to the app.
+//
+// Run the app with PAYMENT_GATEWAY=http, PAYMENT_GATEWAY_URL pointing here
+// and the same PAYMENT_API_KEY and PAYMENT_WEBHOOK_SECRET.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	webhookURL := flag.String("webhook-url", envOr("FAKEPAY_WEBHOOK_URL", "http://localhost:8000/payments/webhook"), "where to post webhooks")
+	flag.Parse()
+
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Fatal("PAYMENT_WEBHOOK_SECRET is required")
+	}
+
+	gw := payment.NewFakeGateway(secret)
+	client := &http.Client{Timeout: 5 * time.Second}
+	gw.OnEvent = func(payload []byte, signature string) {
+		// deliver after the API call returns, like a real provider
+		go func() {
+			req, _ := http.NewRequest(http.MethodPost, *webhookURL, bytes.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(payment.SignatureHeader, signature)
+			res, err := client.Do(req)
+			if err != nil {
+				log.Printf("webhook delivery failed: %v", err)
+				return
+			}
+			res.Body.Close()
+			log.Printf("webhook %s -> %s", payload, res.Status)
+		}()
+	}
+
+	log.Printf("fakepay listening on %s, webhooks to %s", *addr, *webhookURL)
+	log.Fatal(http.ListenAndServe(*addr, payment.NewFakeServer(gw, os.Getenv("PAYMENT_API_KEY"))))
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,51 @@
+// Command imagegc deletes listing images that no live listing refers to.
+//
+//	go run ./cmd/imagegc -dry-run
+//
+// It reads the same DATABASE_URL and IMAGE_STORE settings as the server.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/albus-droid/Capstone-Project-Backend/internal/db"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/image_store"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+)
+
+func main() {
+	opts := listing.GCOptionsFromEnv()
+	flag.BoolVar(&opts.DryRun, "dry-run", opts.DryRun, "report orphans without deleting them")
+	flag.DurationVar(&opts.Grace, "grace", opts.Grace, "keep orphans younger than this")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	flag.Parse()
+
+	store, err := image_store.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ image store: %v", err)
+	}
+	svc := listing.NewPostgresService(db.Init())
+
+	report, err := listing.CollectOrphanedImages(context.Background(), svc, store, opts)
+	if err != nil {
+		log.Fatalf("❌ image gc: %v", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, key := range report.Orphans {
+			fmt.Println(key)
+		}
+		fmt.Println(report)
+	}
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
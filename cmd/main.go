@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/cart"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/event"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/idempotency"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/ledger"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/mailer"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/order"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/receipt"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/review"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/tax"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/user"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/db"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/auth"
@@ -18,10 +30,16 @@ func main() {
 	r := gin.Default()
 	db := db.Init()
 	redisStore := auth.NewRedisStore("redis:6379", "", 0)
-	minioClient, err := image_store.NewMinioClientFromEnv()
-    if err != nil {
-        panic(err)
-    }
+	idemStore := idempotency.NewRedisStore("redis:6379", "", 0)
+	imageStore, err := image_store.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ image store: %v", err)
+	}
+	image_store.RegisterRoutes(r, imageStore)
+	mail, err := mailer.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ mailer: %v", err)
+	}
 
 	// user routes
 	user.Migrate(db) // optional for dev
@@ -36,18 +54,65 @@ func main() {
 	// Listing routes
 	listing.Migrate(db) // optional for dev
 	lsvc := listing.NewPostgresService(db)
-	listing.RegisterRoutes(r, lsvc, minioClient)
+	listing.RegisterRoutes(r, lsvc, imageStore, ssvc)
+	listing.StartUploadJanitor(context.Background(), lsvc, imageStore, listing.UploadJanitorIntervalFromEnv())
+	listing.StartScheduler(context.Background(), lsvc, listing.SchedulerIntervalFromEnv())
+	listing.StartImageGC(context.Background(), lsvc, imageStore, listing.ImageGCIntervalFromEnv(), listing.GCOptionsFromEnv())
+
+	// Pickup windows
+	pickup.Migrate(db) // optional for dev
+	psvc := pickup.NewPostgresService(db)
+	pickup.RegisterRoutes(r, psvc, ssvc, lsvc)
+
+	// Payments
+	gateway, err := payment.GatewayFromEnv()
+	if err != nil {
+		log.Fatalf("❌ payment gateway: %v", err)
+	}
+	payment.Migrate(db) // optional for dev
+	paysvc := payment.NewPostgresService(db, gateway)
+	payment.RegisterRoutes(r, paysvc)
+
+	// Tax
+	tax.Migrate(db) // optional for dev
+	taxsvc := tax.NewPostgresService(db)
+	tax.RegisterRoutes(r, taxsvc, ssvc)
+
+	// Promotions
+	promo.Migrate(db) // optional for dev
+	promosvc := promo.NewPostgresService(db)
+	promo.RegisterRoutes(r, promosvc, ssvc)
+
+	// Ledger
+	ledger.Migrate(db) // optional for dev
+	books := ledger.NewPostgresService(db, ledger.RatesFromEnv())
+	ledger.RegisterRoutes(r, books, ssvc)
+	ledger.StartPayoutWorker(context.Background(), books, ledger.PayoutIntervalFromEnv())
 
 	// Order
 	order.Migrate(db) // optional for dev
-	osvc := order.NewPostgresService(db)
-	order.RegisterRoutes(r, osvc)
+	osvc := order.NewPostgresService(db, paysvc, books)
+	order.RegisterRoutes(r, osvc, ssvc, idemStore)
+	order.StartExpiryWorker(context.Background(), osvc, order.TimeoutsFromEnv(), order.ExpiryIntervalFromEnv())
+
+	// Cart
+	cartsvc := cart.NewService(cart.NewRedisStore("redis:6379", "", 0), lsvc, osvc, cart.TTLFromEnv())
+	cart.RegisterRoutes(r, cartsvc, idemStore)
+
+	// Receipts
+	receipts := receipt.NewService(osvc, ssvc, mail)
+	receipt.RegisterRoutes(r, receipts, osvc, ssvc)
 
-	startNotificationListener()
+	// Reviews
+	review.Migrate(db) // optional for dev
+	reviews := review.NewPostgresService(db)
+	review.RegisterRoutes(r, reviews, ssvc)
+
+	startNotificationListener(receipts)
 	r.Run(":8000") // http://localhost:8080
 }
 
-func startNotificationListener() {
+func startNotificationListener(receipts receipt.Service) {
 	go func() {
 		for e := range event.Bus {
 			switch e.Type {
@@ -59,6 +124,69 @@ func startNotificationListener() {
 			case "OrderAccepted":
 				order := e.Data.(order.Order)
 				fmt.Printf("📬 Notify user %s that order %s was accepted\n", order.UserEmail, order.ID)
+
+			case "OrderCancelled":
+				order := e.Data.(order.Order)
+				fmt.Printf("📭 Notify seller %s that order %s was cancelled\n", order.SellerID, order.ID)
+
+			case "OrderCompleted":
+				order := e.Data.(order.Order)
+				fmt.Printf("🧾 Email user %s the receipt for order %s\n", order.UserEmail, order.ID)
+				go func(id string) {
+					if err := receipts.Email(context.Background(), id); err != nil {
+						log.Printf("receipt for order %s: %v", id, err)
+					}
+				}(order.ID)
+
+			case "OrderReady":
+				order := e.Data.(order.Order)
+				fmt.Printf("🛎️ Notify user %s that order %s is ready for pickup\n", order.UserEmail, order.ID)
+
+			case "OrderExpired":
+				order := e.Data.(order.Order)
+				fmt.Printf("⏰ Notify user %s and seller %s that order %s expired unaccepted\n", order.UserEmail, order.SellerID, order.ID)
+
+			case "OrderNoShow":
+				order := e.Data.(order.Order)
+				fmt.Printf("🕳️ Notify seller %s that order %s was not picked up\n", order.SellerID, order.ID)
+
+			case "OrderRejected":
+				order := e.Data.(order.Order)
+				fmt.Printf("📪 Notify user %s that order %s was rejected\n", order.UserEmail, order.ID)
+
+			case "OrderRefunded":
+				n := e.Data.(order.RefundNotice)
+				fmt.Printf("💸 Notify user %s of a %s refund on order %s\n", n.Order.UserEmail, n.Refund.Amount, n.Order.ID)
+
+			case "ReviewPosted":
+				rv := e.Data.(review.Review)
+				fmt.Printf("⭐ Notify seller %s of a %d-star review on order %s\n", rv.SellerID, rv.Rating, rv.OrderID)
+
+			case "ReviewReplied":
+				rv := e.Data.(review.Review)
+				fmt.Printf("💬 Notify user %s that the seller replied to their review of order %s\n", rv.UserEmail, rv.OrderID)
+
+			case "PaymentUpdated":
+				p := e.Data.(payment.Payment)
+				fmt.Printf("💳 Payment for order %s is now %s\n", p.OrderID, p.Status)
+
+			case "ListingPublished":
+				l := e.Data.(listing.Listing)
+				fmt.Printf("🍽️ Listing %s (%s) is now available\n", l.ID, l.Title)
+
+			case "ListingExpired":
+				l := e.Data.(listing.Listing)
+				fmt.Printf("⌛ Listing %s (%s) has expired\n", l.ID, l.Title)
+
+			case "ListingSoldOut":
+				l := e.Data.(listing.Listing)
+				fmt.Printf("🚫 Notify seller %s that listing %s (%s) sold out\n", l.SellerID, l.ID, l.Title)
+
+			case "ListingRestocked":
+				n := e.Data.(listing.RestockNotice)
+				for _, email := range n.Subscribers {
+					fmt.Printf("🔔 Notify %s that %s is back in stock\n", email, n.Listing.Title)
+				}
 			}
 		}
 	}()
@@ -12,6 +12,7 @@ import (
 	"io"                // <--- ADD THIS
     "mime/multipart"     // <--- ADD THIS
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -382,6 +383,22 @@ func TestListings(t *testing.T) {
 		}
 	}
 
+	// 6b) GET /listings/:id/images (gallery holds the upload as primary)
+	{
+		res, err := http.Get(fmt.Sprintf("%s/listings/%s/images", baseURL, createResp.ID))
+		if err != nil {
+			t.Fatalf("GET /listings/%s/images: %v", createResp.ID, err)
+		}
+		var gallery []struct {
+			ID      string `json:"id"`
+			Primary bool   `json:"primary"`
+		}
+		mustDecode(t, res, &gallery)
+		if len(gallery) != 1 || !gallery[0].Primary {
+			t.Fatalf("gallery after upload = %+v; want one primary image", gallery)
+		}
+	}
+
 	// 7) GET /listings (all) -- unchanged
 	{
 		req, _ := http.NewRequest("GET", baseURL+"/listings", nil)
@@ -395,6 +412,29 @@ func TestListings(t *testing.T) {
 		}
 	}
 
+	// 8) GET /listings/search (full-text, scoped to this seller)
+	{
+		url := fmt.Sprintf("%s/listings/search?q=%s&sellerId=%s", baseURL, "freshly+made", sellerID)
+		res, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET /listings/search: %v", err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("GET /listings/search: expected 200, got %d", res.StatusCode)
+		}
+		var results []struct {
+			ID      string `json:"id"`
+			Snippet string `json:"snippet"`
+		}
+		mustDecode(t, res, &results)
+		if len(results) != 2 {
+			t.Fatalf("GET /listings/search: expected 2 results, got %d", len(results))
+		}
+		if !strings.Contains(results[0].Snippet, "<mark>") {
+			t.Fatalf("search snippet not highlighted: %q", results[0].Snippet)
+		}
+	}
+
     // 5) GET /listings/:id
     {
         url := fmt.Sprintf("%s/listings/%s", baseURL, createResp.ID)
@@ -540,13 +580,58 @@ func TestOrders(t *testing.T) {
 		}
 	}
 
+	// 4b. Add a pickup window and pick its next slot
+	var slot struct {
+		WindowID string `json:"windowId"`
+		Date     string `json:"date"`
+	}
+	{
+		payload := map[string]interface{}{
+			"weekday":  int(time.Now().AddDate(0, 0, 2).Weekday()),
+			"start":    "00:00",
+			"end":      "23:59",
+			"capacity": 5,
+		}
+		b, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", baseURL+"/sellers/me/pickup-windows", bytes.NewReader(b))
+		req.Header.Set("Authorization", sellerAuth)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("POST /sellers/me/pickup-windows failed: %v / %d", err, res.StatusCode)
+		}
+		res.Body.Close()
+
+		res, err = http.Get(baseURL + "/listings/" + listingCreateResp.ID + "/pickup-slots")
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("GET /listings/%s/pickup-slots failed: %v / %d", listingCreateResp.ID, err, res.StatusCode)
+		}
+		var slots []struct {
+			WindowID  string `json:"windowId"`
+			Date      string `json:"date"`
+			Remaining int    `json:"remaining"`
+		}
+		mustDecode(t, res, &slots)
+		if len(slots) == 0 || slots[0].Remaining != 5 {
+			t.Fatalf("pickup slots = %+v; want an open slot with 5 remaining", slots)
+		}
+		slot.WindowID, slot.Date = slots[0].WindowID, slots[0].Date
+	}
+
 	// 5. Place order as user
+	type amount struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}
 	var orderResp struct {
 		ID         string   `json:"id"`
 		UserEmail  string   `json:"user_email"`
 		SellerID   string   `json:"sellerId"`
 		ListingIDs []string `json:"listingIds"`
-		Total      float64  `json:"total"`
+		Total      amount   `json:"total"`
+		Payment    struct {
+			Status string `json:"status"`
+		} `json:"payment"`
 		CreatedAt  int64    `json:"createdAt"`
 		Status     string   `json:"status"`
 	}
@@ -555,22 +640,46 @@ func TestOrders(t *testing.T) {
 			"listingIds": []string{listingCreateResp.ID},
 			"sellerId":   sid,
 			"total":      15.0,
+
+			"pickupWindowId": slot.WindowID,
+			"pickupDate":     slot.Date,
 		}
 		b, _ := json.Marshal(payload)
-		req, _ := http.NewRequest("POST", baseURL+"/orders", bytes.NewReader(b))
-		req.Header.Set("Authorization", "Bearer "+userLogin.Token)
-		req.Header.Set("Content-Type", "application/json")
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			t.Fatalf("POST /orders: %v", err)
+		idemKey := fmt.Sprintf("order-%d", time.Now().UnixNano())
+		post := func() *http.Response {
+			req, _ := http.NewRequest("POST", baseURL+"/orders", bytes.NewReader(b))
+			req.Header.Set("Authorization", "Bearer "+userLogin.Token)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", idemKey)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST /orders: %v", err)
+			}
+			if res.StatusCode != http.StatusCreated {
+				t.Fatalf("POST /orders: expected 201, got %d", res.StatusCode)
+			}
+			return res
 		}
-		if res.StatusCode != http.StatusCreated {
-			t.Fatalf("POST /orders: expected 201, got %d", res.StatusCode)
+		mustDecode(t, post(), &orderResp)
+
+		// a retry with the same key replays the order instead of placing another
+		retry := post()
+		var replayed struct {
+			ID string `json:"id"`
+		}
+		mustDecode(t, retry, &replayed)
+		if replayed.ID != orderResp.ID || retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Fatalf("retried POST /orders returned order %s (replayed=%q); want %s", replayed.ID, retry.Header.Get("Idempotent-Replayed"), orderResp.ID)
 		}
-		mustDecode(t, res, &orderResp)
 		if orderResp.Status != "pending" {
 			t.Fatalf("new order status=%q; want pending", orderResp.Status)
 		}
+		if orderResp.Total != (amount{"15.00", "USD"}) {
+			t.Fatalf("new order total=%+v; want 15.00 USD", orderResp.Total)
+		}
+		if orderResp.Payment.Status != "authorized" {
+			t.Fatalf("new order payment status=%q; want authorized", orderResp.Payment.Status)
+		}
 	}
 
 	// 6. Fetch listing before and after accepting order
@@ -579,7 +688,7 @@ func TestOrders(t *testing.T) {
 		SellerID    string  `json:"sellerId"`
 		Title       string  `json:"title"`
 		Description string  `json:"description"`
-		Price       float64 `json:"price"`
+		Price       amount  `json:"price"`
 		Available   bool    `json:"available"`
 		PortionSize int     `json:"portionSize"`
 		LeftSize    int     `json:"leftSize"`
@@ -598,10 +707,10 @@ func TestOrders(t *testing.T) {
 		mustDecode(t, res, &before)
 	}
 
-	// 7. Accept order as user (you can use seller in your logic if needed)
+	// 7. Accept order as its seller
 	{
 		req, _ := http.NewRequest("PATCH", baseURL+"/orders/"+orderResp.ID+"/accept", nil)
-		req.Header.Set("Authorization", "Bearer "+userLogin.Token)
+		req.Header.Set("Authorization", sellerAuth)
 		res, _ := http.DefaultClient.Do(req)
 		if res.StatusCode != http.StatusOK {
 			t.Fatalf("PATCH /orders/%s/accept: expected 200, got %d", orderResp.ID, res.StatusCode)
@@ -631,15 +740,49 @@ func TestOrders(t *testing.T) {
 		if orderResp.Status != "accepted" {
 			t.Fatalf("order status after accept=%q; want accepted", orderResp.Status)
 		}
+		if orderResp.Payment.Status != "captured" {
+			t.Fatalf("payment status after accept=%q; want captured", orderResp.Payment.Status)
+		}
 	}
 
-	// 9. Complete Order
+	// 9. Complete Order: the buyer gets a pickup code, the seller enters it
 	{
-		req, _ := http.NewRequest("PATCH", baseURL+"/orders/"+orderResp.ID+"/complete", nil)
+		req, _ := http.NewRequest("GET", baseURL+"/orders/"+orderResp.ID+"/pickup-code", nil)
 		req.Header.Set("Authorization", "Bearer "+userLogin.Token)
-		res, _ := http.DefaultClient.Do(req)
-		if res.StatusCode != http.StatusOK {
-			t.Fatalf("PATCH /orders/%s/complete: expected 200, got %d", orderResp.ID, res.StatusCode)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("GET /orders/%s/pickup-code: %v / %d", orderResp.ID, err, res.StatusCode)
+		}
+		var code struct {
+			Code         string `json:"code"`
+			QRPayload    string `json:"qrPayload"`
+			AttemptsLeft int    `json:"attemptsLeft"`
+		}
+		mustDecode(t, res, &code)
+		if len(code.Code) != 6 || code.QRPayload == "" || code.AttemptsLeft != 5 {
+			t.Fatalf("pickup code = %+v; want a 6-digit code with 5 attempts left", code)
+		}
+
+		handoff := func(c string) *http.Response {
+			b, _ := json.Marshal(map[string]string{"code": c})
+			req, _ := http.NewRequest("POST", baseURL+"/orders/"+orderResp.ID+"/handoff", bytes.NewReader(b))
+			req.Header.Set("Authorization", sellerAuth)
+			req.Header.Set("Content-Type", "application/json")
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST /orders/%s/handoff: %v", orderResp.ID, err)
+			}
+			return res
+		}
+		wrong := "000000"
+		if code.Code == wrong {
+			wrong = "111111"
+		}
+		if res := handoff(wrong); res.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("handoff with a wrong code: expected 422, got %d", res.StatusCode)
+		}
+		if res := handoff(code.QRPayload); res.StatusCode != http.StatusOK {
+			t.Fatalf("handoff with the QR payload: expected 200, got %d", res.StatusCode)
 		}
 	}
 
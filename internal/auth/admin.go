@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IsAdmin reports whether email is listed in ADMIN_EMAILS (comma-separated,
+// case-insensitive).
+func IsAdmin(email string) bool {
+	if email == "" {
+		return false
+	}
+	for _, a := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if strings.EqualFold(strings.TrimSpace(a), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin rejects callers who aren't admins. It must run after
+// Middleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c.GetString(string(CtxEmailKey))) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,273 @@
+package cart
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "sort"
+    "time"
+
+    "gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+)
+
+type service struct {
+    store    Store
+    listings Listings
+    orders   Orders
+    ttl      time.Duration
+    now      func() time.Time
+}
+
+// NewService returns a cart Service keeping carts in store for ttl after
+// their last change.
+func NewService(store Store, listings Listings, orders Orders, ttl time.Duration) Service {
+    return &service{store: store, listings: listings, orders: orders, ttl: ttl, now: time.Now}
+}
+
+func (s *service) View(ctx context.Context, owner string) (*Cart, error) {
+    c, _, err := s.load(ctx, owner)
+    return c, err
+}
+
+// load revalidates the stored items, saving any fixes (dropped listings,
+// new prices, lowered quantities), and returns the cart with the listings
+// it was checked against.
+func (s *service) load(ctx context.Context, owner string) (*Cart, map[string]listing.Listing, error) {
+    items, err := s.store.Items(ctx, owner)
+    if err != nil {
+        return nil, nil, err
+    }
+    ids := make([]string, 0, len(items))
+    for id := range items {
+        ids = append(ids, id)
+    }
+    found, err := s.listings.GetByIDs(ids)
+    if err != nil {
+        return nil, nil, err
+    }
+    byID := make(map[string]listing.Listing, len(found))
+    for _, l := range found {
+        byID[l.ID] = l
+    }
+
+    c, fixed, removed := revalidate(items, byID)
+    if err := s.store.Remove(ctx, owner, removed...); err != nil {
+        return nil, nil, err
+    }
+    if err := s.store.Put(ctx, owner, fixed, s.ttl); err != nil {
+        return nil, nil, err
+    }
+    return c, byID, nil
+}
+
+// revalidate checks items against their listings. It returns the cart,
+// the items that need saving with a new price or quantity, and the IDs of
+// deleted listings to drop.
+func revalidate(items map[string]Item, listings map[string]listing.Listing) (*Cart, []Item, []string) {
+    // oldest first, so the cart keeps the order things were added in
+    ordered := make([]Item, 0, len(items))
+    for _, it := range items {
+        ordered = append(ordered, it)
+    }
+    sort.Slice(ordered, func(i, j int) bool {
+        if !ordered[i].AddedAt.Equal(ordered[j].AddedAt) {
+            return ordered[i].AddedAt.Before(ordered[j].AddedAt)
+        }
+        return ordered[i].ListingID < ordered[j].ListingID
+    })
+
+    c := &Cart{Items: []Line{}}
+    var fixed []Item
+    var removed []string
+    warn := func(id, code, format string, args ...interface{}) {
+        c.Warnings = append(c.Warnings, Warning{ListingID: id, Code: code, Message: fmt.Sprintf(format, args...)})
+    }
+    for _, it := range ordered {
+        l, ok := listings[it.ListingID]
+        if !ok {
+            removed = append(removed, it.ListingID)
+            warn(it.ListingID, WarnRemoved, "a listing in your cart is no longer offered and was removed")
+            continue
+        }
+        changed := false
+        switch {
+        case l.SoldOut:
+            warn(l.ID, WarnSoldOut, "%s is sold out", l.Title)
+        case !l.Available:
+            warn(l.ID, WarnUnavailable, "%s is not available right now", l.Title)
+        case l.LeftSize < it.Quantity:
+            warn(l.ID, WarnLowStock, "only %d of %s left; quantity lowered from %d", l.LeftSize, l.Title, it.Quantity)
+            it.Quantity, changed = l.LeftSize, true
+        }
+        if it.Price != l.Price {
+            warn(l.ID, WarnPriceChanged, "%s now costs %s (was %s)", l.Title, l.Price, it.Price)
+            it.Price, changed = l.Price, true
+        }
+        if changed {
+            fixed = append(fixed, it)
+        }
+        c.Items = append(c.Items, Line{
+            ListingID: l.ID,
+            SellerID:  l.SellerID,
+            Title:     l.Title,
+            Image:     l.Image,
+            Quantity:  it.Quantity,
+            UnitPrice: l.Price,
+            Total:     l.Price.Mul(int64(it.Quantity)),
+            Available: l.Available && !l.SoldOut,
+            LeftSize:  l.LeftSize,
+        })
+    }
+
+    totals := make([]money.Money, len(c.Items))
+    for i, line := range c.Items {
+        totals[i] = line.Total
+    }
+    if sum, err := money.Sum(totals...); err == nil && len(totals) > 0 {
+        c.Subtotal = &sum
+    }
+    return c, fixed, removed
+}
+
+func (s *service) Add(ctx context.Context, owner, listingID string, quantity int) (*Cart, error) {
+    if quantity < 1 {
+        return nil, ErrInvalidQuantity
+    }
+    items, err := s.store.Items(ctx, owner)
+    if err != nil {
+        return nil, err
+    }
+    it, ok := items[listingID]
+    if !ok {
+        it = Item{ListingID: listingID, AddedAt: s.now()}
+    }
+    return s.put(ctx, owner, it, it.Quantity+quantity)
+}
+
+func (s *service) SetQuantity(ctx context.Context, owner, listingID string, quantity int) (*Cart, error) {
+    if quantity < 1 {
+        return nil, ErrInvalidQuantity
+    }
+    items, err := s.store.Items(ctx, owner)
+    if err != nil {
+        return nil, err
+    }
+    it, ok := items[listingID]
+    if !ok {
+        return nil, ErrNotInCart
+    }
+    return s.put(ctx, owner, it, quantity)
+}
+
+// put stores it with quantity after checking the listing can be ordered
+// in that quantity now.
+func (s *service) put(ctx context.Context, owner string, it Item, quantity int) (*Cart, error) {
+    found, err := s.listings.GetByIDs([]string{it.ListingID})
+    if err != nil {
+        return nil, err
+    }
+    if len(found) == 0 {
+        return nil, fmt.Errorf("%w: listing %s not found", ErrUnavailable, it.ListingID)
+    }
+    l := found[0]
+    if !l.Available || l.SoldOut {
+        return nil, fmt.Errorf("%w: %s", ErrUnavailable, l.Title)
+    }
+    if quantity > l.LeftSize {
+        return nil, fmt.Errorf("%w: only %d of %s left", ErrUnavailable, l.LeftSize, l.Title)
+    }
+    it.Quantity, it.Price = quantity, l.Price
+    if err := s.store.Put(ctx, owner, []Item{it}, s.ttl); err != nil {
+        return nil, err
+    }
+    return s.View(ctx, owner)
+}
+
+func (s *service) Remove(ctx context.Context, owner, listingID string) (*Cart, error) {
+    if err := s.store.Remove(ctx, owner, listingID); err != nil {
+        return nil, err
+    }
+    return s.View(ctx, owner)
+}
+
+func (s *service) Clear(ctx context.Context, owner string) error {
+    return s.store.Clear(ctx, owner)
+}
+
+func (s *service) Checkout(ctx context.Context, owner string, in CheckoutInput) (*order.Group, *Cart, error) {
+    c, _, err := s.load(ctx, owner)
+    if err != nil {
+        return nil, nil, err
+    }
+    if len(c.Items) == 0 {
+        return nil, c, ErrEmptyCart
+    }
+    if len(c.Warnings) > 0 {
+        return nil, c, ErrNeedsReview
+    }
+    if c.Subtotal == nil {
+        // one payment covers the whole cart
+        return nil, c, order.ErrMixedCurrency
+    }
+    pickups := make(map[string]Pickup, len(in.Pickups))
+    for _, p := range in.Pickups {
+        pickups[p.SellerID] = p
+    }
+
+    // one order per seller, in the order sellers first appear in the cart
+    var sellers []string
+    bySeller := make(map[string][]Line)
+    for _, line := range c.Items {
+        if _, ok := bySeller[line.SellerID]; !ok {
+            if _, ok := pickups[line.SellerID]; !ok {
+                return nil, c, fmt.Errorf("%w: missing seller %s", ErrMissingPickup, line.SellerID)
+            }
+            sellers = append(sellers, line.SellerID)
+        }
+        bySeller[line.SellerID] = append(bySeller[line.SellerID], line)
+    }
+    g := &order.Group{UserEmail: owner, PaymentMethod: in.PaymentMethod, PromoCode: in.PromoCode}
+    for _, sellerID := range sellers {
+        g.Orders = append(g.Orders, newOrder(sellerID, bySeller[sellerID], pickups[sellerID]))
+    }
+    if err := s.orders.CreateGroup(g); err != nil {
+        return nil, c, err
+    }
+
+    var ordered []string
+    for _, line := range c.Items {
+        ordered = append(ordered, line.ListingID)
+    }
+    if err := s.store.Remove(ctx, owner, ordered...); err != nil {
+        log.Printf("cart checkout: emptying cart of %s: %v", owner, err)
+    }
+    return g, c, nil
+}
+
+// newOrder builds the order for one seller's lines. Orders repeat a
+// listing ID once per portion, and carry the subtotal the buyer saw so a
+// price change in between is caught; taxes are added when it's placed.
+func newOrder(sellerID string, lines []Line, p Pickup) order.Order {
+    var ids []string
+    totals := make([]money.Money, len(lines))
+    for i, line := range lines {
+        for n := 0; n < line.Quantity; n++ {
+            ids = append(ids, line.ListingID)
+        }
+        totals[i] = line.Total
+    }
+    subtotal, _ := money.Sum(totals...) // one currency, as the cart has a subtotal
+    raw, _ := json.Marshal(ids)
+    return order.Order{
+        SellerID:       sellerID,
+        ListingIDs:     datatypes.JSON(raw),
+        Subtotal:       subtotal,
+        PickupWindowID: p.PickupWindowID,
+        PickupDate:     p.PickupDate,
+    }
+}
@@ -0,0 +1,204 @@
+package cart
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+)
+
+type fakeListings map[string]listing.Listing
+
+func (f fakeListings) GetByIDs(ids []string) ([]listing.Listing, error) {
+    var out []listing.Listing
+    for _, id := range ids {
+        if l, ok := f[id]; ok {
+            out = append(out, l)
+        }
+    }
+    return out, nil
+}
+
+type fakeOrders struct {
+    groups []order.Group
+    fail   error
+}
+
+func (f *fakeOrders) CreateGroup(g *order.Group) error {
+    if f.fail != nil {
+        return f.fail
+    }
+    g.ID = fmt.Sprintf("group-%d", len(f.groups)+1)
+    f.groups = append(f.groups, *g)
+    return nil
+}
+
+func usd(cents int64) money.Money { return money.New(cents, "USD") }
+
+func newTestCart(t *testing.T) (Service, fakeListings, *fakeOrders, *MemoryStore) {
+    t.Helper()
+    listings := fakeListings{
+        "soup":  {ID: "soup", SellerID: "s1", Title: "Soup", Price: usd(500), Available: true, LeftSize: 5},
+        "bread": {ID: "bread", SellerID: "s1", Title: "Bread", Price: usd(250), Available: true, LeftSize: 5},
+        "curry": {ID: "curry", SellerID: "s2", Title: "Curry", Price: usd(1200), Available: true, LeftSize: 2},
+    }
+    orders := &fakeOrders{}
+    store := NewMemoryStore()
+    svc := NewService(store, listings, orders, DefaultTTL)
+    // a clock that ticks on every read keeps items in the order they were added
+    clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+    svc.(*service).now = func() time.Time {
+        clock = clock.Add(time.Second)
+        return clock
+    }
+    return svc, listings, orders, store
+}
+
+func TestViewRevalidates(t *testing.T) {
+    ctx := context.Background()
+    svc, listings, _, store := newTestCart(t)
+    for id, qty := range map[string]int{"soup": 2, "bread": 1, "curry": 2} {
+        if _, err := svc.Add(ctx, "a@x", id, qty); err != nil {
+            t.Fatalf("add %s: %v", id, err)
+        }
+    }
+
+    soup := listings["soup"]
+    soup.Price = usd(600)
+    listings["soup"] = soup
+    curry := listings["curry"]
+    curry.LeftSize = 1
+    listings["curry"] = curry
+    delete(listings, "bread")
+
+    c, err := svc.View(ctx, "a@x")
+    if err != nil {
+        t.Fatal(err)
+    }
+    codes := map[string]string{}
+    for _, w := range c.Warnings {
+        codes[w.ListingID] = w.Code
+    }
+    want := map[string]string{"soup": WarnPriceChanged, "curry": WarnLowStock, "bread": WarnRemoved}
+    for id, code := range want {
+        if codes[id] != code {
+            t.Errorf("warning for %s = %q, want %q", id, codes[id], code)
+        }
+    }
+    if len(c.Items) != 2 {
+        t.Fatalf("items = %d, want 2", len(c.Items))
+    }
+    if c.Subtotal == nil || *c.Subtotal != usd(2*600+1200) {
+        t.Errorf("subtotal = %v, want 24.00 USD", c.Subtotal)
+    }
+
+    // the fixes were saved, so the next read is clean
+    items, _ := store.Items(ctx, "a@x")
+    if _, ok := items["bread"]; ok {
+        t.Error("removed listing still stored")
+    }
+    if items["curry"].Quantity != 1 || items["soup"].Price != usd(600) {
+        t.Errorf("stored items not updated: %+v", items)
+    }
+    if c, _ = svc.View(ctx, "a@x"); len(c.Warnings) != 0 {
+        t.Errorf("second view warnings = %+v, want none", c.Warnings)
+    }
+
+    curry.SoldOut, curry.LeftSize = true, 0
+    listings["curry"] = curry
+    c, _ = svc.View(ctx, "a@x")
+    if len(c.Warnings) != 1 || c.Warnings[0].Code != WarnSoldOut {
+        t.Errorf("sold out: warnings %+v", c.Warnings)
+    }
+    for _, line := range c.Items {
+        if line.ListingID == "curry" && line.Available {
+            t.Error("sold out item still available")
+        }
+    }
+}
+
+func TestAddChecksStock(t *testing.T) {
+    ctx := context.Background()
+    svc, _, _, _ := newTestCart(t)
+    if _, err := svc.Add(ctx, "a@x", "curry", 2); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := svc.Add(ctx, "a@x", "curry", 1); !errors.Is(err, ErrUnavailable) {
+        t.Errorf("adding past stock: err = %v, want ErrUnavailable", err)
+    }
+    if _, err := svc.Add(ctx, "a@x", "nope", 1); !errors.Is(err, ErrUnavailable) {
+        t.Errorf("adding unknown listing: err = %v, want ErrUnavailable", err)
+    }
+    if _, err := svc.SetQuantity(ctx, "a@x", "soup", 1); !errors.Is(err, ErrNotInCart) {
+        t.Errorf("setting quantity of missing item: err = %v, want ErrNotInCart", err)
+    }
+}
+
+func TestCheckoutSplitsBySeller(t *testing.T) {
+    ctx := context.Background()
+    svc, _, _, store := newTestCart(t)
+    svc.Add(ctx, "a@x", "soup", 2)
+    svc.Add(ctx, "a@x", "curry", 1)
+    svc.Add(ctx, "a@x", "bread", 1)
+
+    in := CheckoutInput{PaymentMethod: "tok_visa", Pickups: []Pickup{{SellerID: "s1", PickupWindowID: "w1", PickupDate: "2026-01-02"}}}
+    if _, _, err := svc.Checkout(ctx, "a@x", in); !errors.Is(err, ErrMissingPickup) {
+        t.Fatalf("err = %v, want ErrMissingPickup", err)
+    }
+
+    in.Pickups = append(in.Pickups, Pickup{SellerID: "s2", PickupWindowID: "w2", PickupDate: "2026-01-02"})
+    g, _, err := svc.Checkout(ctx, "a@x", in)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if g.UserEmail != "a@x" || g.PaymentMethod != "tok_visa" || len(g.Orders) != 2 {
+        t.Fatalf("group = %+v", g)
+    }
+    first, second := g.Orders[0], g.Orders[1]
+    if first.SellerID != "s1" || first.Subtotal != usd(1250) || string(first.ListingIDs) != `["soup","soup","bread"]` {
+        t.Errorf("first order = %+v", first)
+    }
+    if second.SellerID != "s2" || second.Subtotal != usd(1200) || second.PickupWindowID != "w2" {
+        t.Errorf("second order = %+v", second)
+    }
+    if items, _ := store.Items(ctx, "a@x"); len(items) != 0 {
+        t.Errorf("cart not emptied: %+v", items)
+    }
+}
+
+func TestCheckoutKeepsCartOnFailure(t *testing.T) {
+    ctx := context.Background()
+    svc, listings, orders, store := newTestCart(t)
+    svc.Add(ctx, "a@x", "soup", 1)
+    svc.Add(ctx, "a@x", "curry", 1)
+    in := CheckoutInput{Pickups: []Pickup{
+        {SellerID: "s1", PickupWindowID: "w1", PickupDate: "2026-01-02"},
+        {SellerID: "s2", PickupWindowID: "w2", PickupDate: "2026-01-02"},
+    }}
+
+    orders.fail = errors.New("declined")
+    if _, _, err := svc.Checkout(ctx, "a@x", in); err != orders.fail {
+        t.Fatalf("err = %v, want %v", err, orders.fail)
+    }
+    if items, _ := store.Items(ctx, "a@x"); len(items) != 2 {
+        t.Errorf("cart changed after a failed checkout: %+v", items)
+    }
+
+    // a cart with warnings must be reviewed first
+    orders.fail = nil
+    soup := listings["soup"]
+    soup.Price = usd(550)
+    listings["soup"] = soup
+    _, c, err := svc.Checkout(ctx, "a@x", in)
+    if !errors.Is(err, ErrNeedsReview) || c == nil || len(c.Warnings) != 1 {
+        t.Fatalf("err = %v, cart = %+v; want ErrNeedsReview with a warning", err, c)
+    }
+    if _, _, err := svc.Checkout(ctx, "a@x", in); err != nil {
+        t.Errorf("checkout after review: %v", err)
+    }
+}
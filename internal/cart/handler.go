@@ -0,0 +1,132 @@
+package cart
+
+import (
+    "errors"
+    "log"
+    "net/http"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/idempotency"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, idem idempotency.Store) {
+    grp := r.Group("/cart")
+    grp.Use(auth.Middleware())
+
+    // GET /cart/items – the cart, checked against current listings
+    grp.GET("/items", func(c *gin.Context) {
+        cart, err := svc.View(c.Request.Context(), c.GetString(string(auth.CtxEmailKey)))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, cart)
+    })
+
+    // POST /cart/items – add portions of a listing
+    grp.POST("/items", func(c *gin.Context) {
+        var in struct {
+            ListingID string `json:"listingId" binding:"required"`
+            Quantity  int    `json:"quantity"`
+        }
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if in.Quantity == 0 {
+            in.Quantity = 1
+        }
+        cart, err := svc.Add(c.Request.Context(), c.GetString(string(auth.CtxEmailKey)), in.ListingID, in.Quantity)
+        if err != nil {
+            itemError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, cart)
+    })
+
+    // PUT /cart/items/:listingId – set an item's quantity
+    grp.PUT("/items/:listingId", func(c *gin.Context) {
+        var in struct {
+            Quantity int `json:"quantity" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        cart, err := svc.SetQuantity(c.Request.Context(), c.GetString(string(auth.CtxEmailKey)), c.Param("listingId"), in.Quantity)
+        if err != nil {
+            itemError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, cart)
+    })
+
+    // DELETE /cart/items/:listingId – remove one item
+    grp.DELETE("/items/:listingId", func(c *gin.Context) {
+        cart, err := svc.Remove(c.Request.Context(), c.GetString(string(auth.CtxEmailKey)), c.Param("listingId"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, cart)
+    })
+
+    // DELETE /cart/items – empty the cart
+    grp.DELETE("/items", func(c *gin.Context) {
+        if err := svc.Clear(c.Request.Context(), c.GetString(string(auth.CtxEmailKey))); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    // POST /cart/checkout – place the cart as an order group, one order per seller
+    grp.POST("/checkout", idempotency.Middleware(idem, idempotency.DefaultTTL), func(c *gin.Context) {
+        var in CheckoutInput
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        group, cart, err := svc.Checkout(c.Request.Context(), c.GetString(string(auth.CtxEmailKey)), in)
+        if err != nil {
+            switch {
+            case errors.Is(err, ErrNeedsReview):
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "cart": cart})
+            case errors.Is(err, pickup.ErrSlotFull), errors.Is(err, order.ErrTotalMismatch), errors.Is(err, order.ErrOrderAlreadyExists),
+                errors.Is(err, promo.ErrLimitReached):
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            case errors.Is(err, payment.ErrDeclined):
+                c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+            case errors.Is(err, promo.ErrUnknownCode), errors.Is(err, promo.ErrNotApplicable):
+                c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+            case errors.Is(err, ErrEmptyCart), errors.Is(err, ErrMissingPickup),
+                errors.Is(err, pickup.ErrWindowNotFound), errors.Is(err, pickup.ErrInvalidSlot),
+                errors.Is(err, order.ErrMixedCurrency):
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            default:
+                log.Printf("cart checkout failed: %v", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            }
+            return
+        }
+        c.JSON(http.StatusCreated, group)
+    })
+}
+
+func itemError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, ErrInvalidQuantity):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrNotInCart):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrUnavailable):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
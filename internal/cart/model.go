@@ -0,0 +1,77 @@
+package cart
+
+import (
+    "errors"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+var (
+    ErrEmptyCart       = errors.New("cart is empty")
+    ErrInvalidQuantity = errors.New("quantity must be at least 1")
+    ErrUnavailable     = errors.New("listing is not available")
+    ErrNotInCart       = errors.New("listing is not in the cart")
+    ErrNeedsReview     = errors.New("cart changed since it was last viewed; review it and check out again")
+    ErrMissingPickup   = errors.New("a pickup slot is required for every seller in the cart")
+)
+
+// Item is what the cart stores per listing: the quantity and the price the
+// buyer last saw, so a later read can tell them it changed.
+type Item struct {
+    ListingID string      `json:"listingId"`
+    Quantity  int         `json:"quantity"`
+    Price     money.Money `json:"price"`
+    AddedAt   time.Time   `json:"addedAt"`
+}
+
+// Warning codes
+const (
+    WarnRemoved      = "removed"       // listing was deleted; dropped from the cart
+    WarnSoldOut      = "sold_out"      // no portions left
+    WarnUnavailable  = "unavailable"   // outside its publication window or switched off
+    WarnLowStock     = "low_stock"     // fewer portions left than were in the cart; quantity lowered
+    WarnPriceChanged = "price_changed" // the cart now has the new price
+)
+
+// Warning tells the buyer what changed about an item since they added it.
+type Warning struct {
+    ListingID string `json:"listingId"`
+    Code      string `json:"code"`
+    Message   string `json:"message"`
+}
+
+// Line is a cart item checked against its listing's current state.
+type Line struct {
+    ListingID string      `json:"listingId"`
+    SellerID  string      `json:"sellerId"`
+    Title     string      `json:"title"`
+    Image     string      `json:"image,omitempty"`
+    Quantity  int         `json:"quantity"`
+    UnitPrice money.Money `json:"unitPrice"`
+    Total     money.Money `json:"total"`
+    Available bool        `json:"available"`
+    LeftSize  int         `json:"leftSize"`
+}
+
+// Cart is a buyer's revalidated cart. Subtotal is left out when items are
+// priced in different currencies, which can't be checked out together.
+type Cart struct {
+    Items    []Line       `json:"items"`
+    Subtotal *money.Money `json:"subtotal,omitempty"`
+    Warnings []Warning    `json:"warnings,omitempty"`
+}
+
+// Pickup is the slot chosen for one seller's part of the cart.
+type Pickup struct {
+    SellerID       string `json:"sellerId" binding:"required"`
+    PickupWindowID string `json:"pickupWindowId" binding:"required"`
+    PickupDate     string `json:"pickupDate" binding:"required"`
+}
+
+// CheckoutInput is the body of POST /cart/checkout.
+type CheckoutInput struct {
+    Pickups       []Pickup `json:"pickups" binding:"required,dive"`
+    PaymentMethod string   `json:"paymentMethod"`
+    PromoCode     string   `json:"promoCode"`
+}
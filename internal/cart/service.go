@@ -0,0 +1,35 @@
+package cart
+
+import (
+    "context"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+)
+
+// Service manages buyers' carts. Every read checks the items against their
+// listings' current price, stock and availability.
+type Service interface {
+    View(ctx context.Context, owner string) (*Cart, error)
+    // Add puts quantity more portions of a listing in the cart.
+    Add(ctx context.Context, owner, listingID string, quantity int) (*Cart, error)
+    // SetQuantity replaces the quantity of an item already in the cart.
+    SetQuantity(ctx context.Context, owner, listingID string, quantity int) (*Cart, error)
+    Remove(ctx context.Context, owner, listingID string) (*Cart, error)
+    Clear(ctx context.Context, owner string) error
+
+    // Checkout places the cart as one order group, with an order per
+    // seller, and empties it. A cart with warnings returns ErrNeedsReview
+    // along with the cart.
+    Checkout(ctx context.Context, owner string, in CheckoutInput) (*order.Group, *Cart, error)
+}
+
+// Listings is the part of listing.Service the cart reads.
+type Listings interface {
+    GetByIDs(ids []string) ([]listing.Listing, error)
+}
+
+// Orders is the part of order.Service checkout uses.
+type Orders interface {
+    CreateGroup(g *order.Group) error
+}
@@ -0,0 +1,137 @@
+package cart
+
+import (
+    "context"
+    "encoding/json"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/redis/go-redis/v9"
+)
+
+// DefaultTTL is how long an untouched cart is kept.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// TTLFromEnv reads CART_TTL (a Go duration), defaulting to DefaultTTL.
+func TTLFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("CART_TTL")); err == nil && d > 0 {
+        return d
+    }
+    return DefaultTTL
+}
+
+// Store keeps each buyer's cart items. Writes refresh the cart's TTL.
+type Store interface {
+    Items(ctx context.Context, owner string) (map[string]Item, error)
+    Put(ctx context.Context, owner string, items []Item, ttl time.Duration) error
+    Remove(ctx context.Context, owner string, listingIDs ...string) error
+    Clear(ctx context.Context, owner string) error
+}
+
+// RedisStore keeps a cart as a hash of listing ID to JSON item under
+// "cart:<owner>".
+type RedisStore struct {
+    rdb *redis.Client
+}
+
+func NewRedisStore(addr, pass string, db int) *RedisStore {
+    return &RedisStore{rdb: redis.NewClient(&redis.Options{Addr: addr, Password: pass, DB: db})}
+}
+
+func cartKey(owner string) string { return "cart:" + owner }
+
+func (s *RedisStore) Items(ctx context.Context, owner string) (map[string]Item, error) {
+    raw, err := s.rdb.HGetAll(ctx, cartKey(owner)).Result()
+    if err != nil {
+        return nil, err
+    }
+    items := make(map[string]Item, len(raw))
+    for id, v := range raw {
+        var it Item
+        if err := json.Unmarshal([]byte(v), &it); err != nil {
+            continue // unreadable entries are dropped on the next write
+        }
+        items[id] = it
+    }
+    return items, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, owner string, items []Item, ttl time.Duration) error {
+    if len(items) == 0 {
+        return nil
+    }
+    values := make(map[string]interface{}, len(items))
+    for _, it := range items {
+        raw, err := json.Marshal(it)
+        if err != nil {
+            return err
+        }
+        values[it.ListingID] = raw
+    }
+    _, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+        p.HSet(ctx, cartKey(owner), values)
+        p.Expire(ctx, cartKey(owner), ttl)
+        return nil
+    })
+    return err
+}
+
+func (s *RedisStore) Remove(ctx context.Context, owner string, listingIDs ...string) error {
+    if len(listingIDs) == 0 {
+        return nil
+    }
+    return s.rdb.HDel(ctx, cartKey(owner), listingIDs...).Err()
+}
+
+func (s *RedisStore) Clear(ctx context.Context, owner string) error {
+    return s.rdb.Del(ctx, cartKey(owner)).Err()
+}
+
+// MemoryStore keeps carts in process, for tests and development.
+type MemoryStore struct {
+    mu    sync.Mutex
+    carts map[string]map[string]Item
+}
+
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{carts: make(map[string]map[string]Item)}
+}
+
+func (s *MemoryStore) Items(_ context.Context, owner string) (map[string]Item, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make(map[string]Item, len(s.carts[owner]))
+    for k, v := range s.carts[owner] {
+        out[k] = v
+    }
+    return out, nil
+}
+
+func (s *MemoryStore) Put(_ context.Context, owner string, items []Item, _ time.Duration) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.carts[owner] == nil {
+        s.carts[owner] = make(map[string]Item)
+    }
+    for _, it := range items {
+        s.carts[owner][it.ListingID] = it
+    }
+    return nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, owner string, listingIDs ...string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, id := range listingIDs {
+        delete(s.carts[owner], id)
+    }
+    return nil
+}
+
+func (s *MemoryStore) Clear(_ context.Context, owner string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.carts, owner)
+    return nil
+}
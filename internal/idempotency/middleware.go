@@ -0,0 +1,144 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+)
+
+const (
+	// Header is the request header carrying the client's key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Middleware makes the routes it guards idempotent for requests carrying
+// an Idempotency-Key. Keys are scoped to the caller (auth.Middleware must
+// run first) and the route. A retry with the same key and body gets the
+// first response back; the same key with a different body, or while the
+// first request is still running, gets 409. Server errors and panics
+// aren't stored, so those can be retried. Requests without the header run as usual.
+func Middleware(store Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": Header + " is too long"})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := c.Request.Method + " " + c.FullPath()
+		storeKey := "idempotency:" + hash([]byte(c.GetString(string(auth.CtxEmailKey))), []byte(route), []byte(key))
+		fingerprint := hash([]byte(c.Request.Method), []byte(c.Request.URL.Path), body)
+
+		ctx := c.Request.Context()
+		prev, claimed, err := store.Begin(ctx, storeKey, fingerprint, ttl)
+		if err != nil {
+			log.Printf("idempotency store: %v", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "idempotency store unavailable"})
+			return
+		}
+		if !claimed {
+			switch {
+			case prev.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": Header + " was already used for a different request"})
+			case !prev.Done():
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this " + Header + " is still in progress"})
+			default:
+				replay(c, prev)
+			}
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		// a panicking handler mustn't leave the key claimed until it
+		// expires: release it, then let the recovery middleware have it
+		defer func() {
+			if p := recover(); p != nil {
+				if err := store.Release(ctx, storeKey); err != nil {
+					log.Printf("idempotency store: %v", err)
+				}
+				panic(p)
+			}
+		}()
+		c.Next()
+
+		status := rec.Status()
+		if status >= 500 {
+			err = store.Release(ctx, storeKey)
+		} else {
+			err = store.Complete(ctx, storeKey, Record{
+				Fingerprint: fingerprint,
+				Status:      status,
+				Header:      http.Header{"Content-Type": rec.Header().Values("Content-Type")},
+				Body:        rec.body.Bytes(),
+			}, ttl)
+		}
+		if err != nil {
+			log.Printf("idempotency store: %v", err)
+		}
+	}
+}
+
+func replay(c *gin.Context, rec *Record) {
+	for k, vs := range rec.Header {
+		for _, v := range vs {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Writer.Header().Set(ReplayedHeader, "true")
+	c.Writer.WriteHeader(rec.Status)
+	c.Writer.Write(rec.Body)
+	c.Abort()
+}
+
+// hash is the hex SHA-256 of parts, length-prefixed so they can't run
+// into each other.
+func hash(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
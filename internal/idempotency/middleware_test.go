@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryStore()
+	calls := 0
+	fail := false
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(string(auth.CtxEmailKey), c.GetHeader("X-User"))
+	})
+	r.POST("/orders", Middleware(store, DefaultTTL), func(c *gin.Context) {
+		calls++
+		if fail {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	do := func(user, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("a@example.com", "k1", `{"x":1}`)
+	if first.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: %d, %d calls", first.Code, calls)
+	}
+	replayed := do("a@example.com", "k1", `{"x":1}`)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() || calls != 1 {
+		t.Errorf("replay: %d %s, %d calls; want the first response without a call", replayed.Code, replayed.Body, calls)
+	}
+	if replayed.Header().Get(ReplayedHeader) != "true" || !strings.HasPrefix(replayed.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("replay headers = %v", replayed.Header())
+	}
+
+	if w := do("a@example.com", "k1", `{"x":2}`); w.Code != http.StatusConflict || calls != 1 {
+		t.Errorf("same key, different body: %d, %d calls; want 409", w.Code, calls)
+	}
+	if w := do("b@example.com", "k1", `{"x":1}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("another user's key: %d, %d calls; want a new 201", w.Code, calls)
+	}
+	if w := do("a@example.com", "", `{"x":1}`); w.Code != http.StatusCreated || calls != 3 {
+		t.Errorf("no key: %d, %d calls; want a new 201", w.Code, calls)
+	}
+
+	// server errors aren't kept, so the retry runs again
+	fail = true
+	do("a@example.com", "k2", `{}`)
+	fail = false
+	if w := do("a@example.com", "k2", `{}`); w.Code != http.StatusCreated || calls != 5 {
+		t.Errorf("retry after 500: %d, %d calls; want a new 201", w.Code, calls)
+	}
+
+	// a key claimed by a request that's still running
+	storeKey := "idempotency:" + hash([]byte("a@example.com"), []byte("POST /orders"), []byte("k3"))
+	store.Begin(context.Background(), storeKey, hash([]byte("POST"), []byte("/orders"), []byte(`{}`)), DefaultTTL)
+	if w := do("a@example.com", "k3", `{}`); w.Code != http.StatusConflict || calls != 5 {
+		t.Errorf("in-progress key: %d, %d calls; want 409", w.Code, calls)
+	}
+}
+
+func TestMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryStore()
+	calls := 0
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.POST("/orders", Middleware(store, DefaultTTL), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set(Header, "k1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do(); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking request: %d; want 500", w.Code)
+	}
+	if w := do(); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry after panic: %d, %d calls; want a new 201", w.Code, calls)
+	}
+}
@@ -0,0 +1,127 @@
+// Package idempotency lets clients retry unsafe requests safely: a request
+// carrying an Idempotency-Key header runs once, and retries with the same
+// key get the stored response back instead of running it again.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultTTL is how long keys and their responses are kept.
+const DefaultTTL = 24 * time.Hour
+
+// Record is what's stored under a key. Status is zero while the first
+// request is still running.
+type Record struct {
+	Fingerprint string      `json:"fingerprint"` // hash of method, path and body
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Done reports whether the request finished and its response is stored.
+func (r *Record) Done() bool { return r.Status != 0 }
+
+// Store keeps idempotency records.
+type Store interface {
+	// Begin claims key for a new request. If the key is already taken it
+	// returns the existing record and claimed=false.
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (existing *Record, claimed bool, err error)
+	// Complete stores the response for a claimed key.
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Release drops a claim so the request can be retried, e.g. after a
+	// server error.
+	Release(ctx context.Context, key string) error
+}
+
+// RedisStore keeps records in Redis, where they expire on their own.
+type RedisStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisStore(addr, pass string, db int) *RedisStore {
+	return &RedisStore{rdb: redis.NewClient(&redis.Options{Addr: addr, Password: pass, DB: db})}
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	claim, _ := json.Marshal(Record{Fingerprint: fingerprint})
+	ok, err := s.rdb.SetNX(ctx, key, claim, ttl).Result()
+	if err != nil || ok {
+		return nil, ok, err
+	}
+	raw, err := s.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// expired or released in between; try once more
+		ok, err = s.rdb.SetNX(ctx, key, claim, ttl).Result()
+		return nil, ok, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var rec Record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, false, err
+	}
+	return &rec, false, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, key, raw, ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, key).Err()
+}
+
+// MemoryStore keeps records in process, for tests and single-instance
+// development.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]memoryRecord
+	now     func() time.Time
+}
+
+type memoryRecord struct {
+	Record
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord), now: time.Now}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.records[key]; ok && s.now().Before(r.expires) {
+		rec := r.Record
+		return &rec, false, nil
+	}
+	s.records[key] = memoryRecord{Record{Fingerprint: fingerprint}, s.now().Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = memoryRecord{rec, s.now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
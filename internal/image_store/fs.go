@@ -0,0 +1,251 @@
+package image_store
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "io/fs"
+    "mime"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+var errBadKey = errors.New("invalid object key")
+
+// FSStore keeps objects as files under a root directory. Downloads and
+// direct uploads go through RegisterRoutes, which only accepts URLs signed
+// by SignedURL and SignedPutURL.
+type FSStore struct {
+    root   string
+    prefix string // URL path the files are served under
+    secret []byte
+}
+
+// NewFSStore creates root if needed and returns a Store serving it under prefix.
+func NewFSStore(root, prefix string, secret []byte) (*FSStore, error) {
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        return nil, err
+    }
+    return &FSStore{root: root, prefix: strings.TrimRight(prefix, "/"), secret: secret}, nil
+}
+
+// path maps a key to its file, rejecting keys that would escape root.
+func (s *FSStore) path(key string) (string, error) {
+    if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "/") || strings.HasPrefix(key, "..") {
+        return "", errBadKey
+    }
+    return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *FSStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+    p, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+        return err
+    }
+    // write to a temp file and rename so readers never see partial objects
+    tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+    if _, err := io.Copy(tmp, r); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), p)
+}
+
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+    p, err := s.path(key)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func (s *FSStore) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+    if _, err := s.path(key); err != nil {
+        return "", err
+    }
+    expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+    q := url.Values{"expires": {expires}, "sig": {s.sign(key, expires)}}
+    return s.prefix + "/" + key + "?" + q.Encode(), nil
+}
+
+func (s *FSStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    p, err := s.path(key)
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    fi, err := os.Stat(p)
+    if err != nil {
+        if errors.Is(err, fs.ErrNotExist) {
+            return ObjectInfo{}, ErrNotFound
+        }
+        return ObjectInfo{}, err
+    }
+    return ObjectInfo{
+        Key:          key,
+        Size:         fi.Size(),
+        ContentType:  mime.TypeByExtension(path.Ext(key)),
+        LastModified: fi.ModTime(),
+    }, nil
+}
+
+func (s *FSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    p, err := s.path(key)
+    if err != nil {
+        return nil, err
+    }
+    f, err := os.Open(p)
+    if errors.Is(err, fs.ErrNotExist) {
+        return nil, ErrNotFound
+    }
+    return f, err
+}
+
+func (s *FSStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+    var out []ObjectInfo
+    err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+            return nil // directories and Put's in-flight temp files
+        }
+        rel, err := filepath.Rel(s.root, p)
+        if err != nil {
+            return err
+        }
+        key := filepath.ToSlash(rel)
+        if !strings.HasPrefix(key, prefix) {
+            return nil
+        }
+        fi, err := d.Info()
+        if err != nil {
+            return err
+        }
+        out = append(out, ObjectInfo{
+            Key:          key,
+            Size:         fi.Size(),
+            ContentType:  mime.TypeByExtension(path.Ext(key)),
+            LastModified: fi.ModTime(),
+        })
+        return nil
+    })
+    return out, err
+}
+
+func (s *FSStore) SignedPutURL(ctx context.Context, key string, ttl time.Duration, contentType string, size int64) (string, error) {
+    if _, err := s.path(key); err != nil {
+        return "", err
+    }
+    expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+    q := url.Values{
+        "expires": {expires},
+        "type":    {contentType},
+        "size":    {strconv.FormatInt(size, 10)},
+    }
+    q.Set("sig", s.sign(http.MethodPut, key, expires, q.Get("type"), q.Get("size")))
+    return s.prefix + "/" + key + "?" + q.Encode(), nil
+}
+
+// sign is the hex HMAC-SHA256 of the signed request fields.
+func (s *FSStore) sign(fields ...string) string {
+    mac := hmac.New(sha256.New, s.secret)
+    mac.Write([]byte(strings.Join(fields, "\n")))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the request's signature over the fields built for its
+// expiry, writing a 403 if it's invalid or expired.
+func (s *FSStore) verify(c *gin.Context, fields func(expires string) []string) bool {
+    expires := c.Query("expires")
+    exp, err := strconv.ParseInt(expires, 10, 64)
+    if err != nil || !hmac.Equal([]byte(c.Query("sig")), []byte(s.sign(fields(expires)...))) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "invalid signature"})
+        return false
+    }
+    if time.Now().Unix() > exp {
+        c.JSON(http.StatusForbidden, gin.H{"error": "link expired"})
+        return false
+    }
+    return true
+}
+
+// serve streams a file if the request carries a valid, unexpired signature.
+func (s *FSStore) serve(c *gin.Context) {
+    key := strings.TrimPrefix(c.Param("key"), "/")
+    if !s.verify(c, func(expires string) []string { return []string{key, expires} }) {
+        return
+    }
+    p, err := s.path(key)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    f, err := os.Open(p)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+        return
+    }
+    defer f.Close()
+    fi, err := f.Stat()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "could not read file"})
+        return
+    }
+    c.Header("Cache-Control", "private, max-age=3600")
+    http.ServeContent(c.Writer, c.Request, path.Base(key), fi.ModTime(), f)
+}
+
+// upload accepts a PUT signed by SignedPutURL. The Content-Type and
+// Content-Length must match what was signed, like S3's presigned PUTs.
+func (s *FSStore) upload(c *gin.Context) {
+    key := strings.TrimPrefix(c.Param("key"), "/")
+    contentType, size := c.Query("type"), c.Query("size")
+    if !s.verify(c, func(expires string) []string {
+        return []string{http.MethodPut, key, expires, contentType, size}
+    }) {
+        return
+    }
+    if c.ContentType() != contentType || strconv.FormatInt(c.Request.ContentLength, 10) != size {
+        c.JSON(http.StatusForbidden, gin.H{"error": "content type or length does not match the signed upload"})
+        return
+    }
+    body := http.MaxBytesReader(c.Writer, c.Request.Body, c.Request.ContentLength)
+    if err := s.Put(c.Request.Context(), key, body, c.Request.ContentLength, contentType); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "could not store upload"})
+        return
+    }
+    c.Status(http.StatusOK)
+}
+
+// RegisterRoutes mounts the signed download and upload routes for stores
+// that serve their own files; other backends hand out their own signed URLs.
+func RegisterRoutes(r *gin.Engine, store Store) {
+    if fsStore, ok := store.(*FSStore); ok {
+        r.GET(fsStore.prefix+"/*key", fsStore.serve)
+        r.PUT(fsStore.prefix+"/*key", fsStore.upload)
+    }
+}
@@ -0,0 +1,110 @@
+package image_store
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+type memoryObject struct {
+    data        []byte
+    contentType string
+    modified    time.Time
+}
+
+// MemoryStore keeps objects in process memory. It's meant for tests.
+type MemoryStore struct {
+    mu      sync.RWMutex
+    objects map[string]memoryObject
+}
+
+// NewMemoryStore returns an empty in-memory Store.
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{objects: make(map[string]memoryObject)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    s.objects[key] = memoryObject{data: data, contentType: contentType, modified: time.Now()}
+    s.mu.Unlock()
+    return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+    s.mu.Lock()
+    delete(s.objects, key)
+    s.mu.Unlock()
+    return nil
+}
+
+// SignedURL returns a memory:// URL; it only identifies the object.
+func (s *MemoryStore) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+    expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+    return "memory://" + key + "?" + url.Values{"expires": {expires}}.Encode(), nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    s.mu.RLock()
+    obj, ok := s.objects[key]
+    s.mu.RUnlock()
+    if !ok {
+        return ObjectInfo{}, ErrNotFound
+    }
+    return ObjectInfo{
+        Key:          key,
+        Size:         int64(len(obj.data)),
+        ContentType:  obj.contentType,
+        LastModified: obj.modified,
+    }, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    s.mu.RLock()
+    obj, ok := s.objects[key]
+    s.mu.RUnlock()
+    if !ok {
+        return nil, ErrNotFound
+    }
+    return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// SignedPutURL returns a memory:// URL; tests upload with Put directly.
+func (s *MemoryStore) SignedPutURL(ctx context.Context, key string, ttl time.Duration, contentType string, size int64) (string, error) {
+    return s.SignedURL(ctx, key, ttl)
+}
+
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    var out []ObjectInfo
+    for key, obj := range s.objects {
+        if strings.HasPrefix(key, prefix) {
+            out = append(out, ObjectInfo{
+                Key:          key,
+                Size:         int64(len(obj.data)),
+                ContentType:  obj.contentType,
+                LastModified: obj.modified,
+            })
+        }
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+    return out, nil
+}
+
+// Bytes returns a copy of an object's content, for assertions in tests.
+func (s *MemoryStore) Bytes(key string) ([]byte, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    obj, ok := s.objects[key]
+    return bytes.Clone(obj.data), ok
+}
@@ -3,13 +3,30 @@ package image_store
 import (
     "context"
     "fmt"
+    "io"
+    "net/http"
     "os"
+    "strconv"
+    "sync"
+    "time"
+
     "github.com/minio/minio-go/v7"
     "github.com/minio/minio-go/v7/pkg/credentials"
 )
 
-// NewMinioClientFromEnv creates a MinIO client and ensures the bucket exists
-func NewMinioClientFromEnv() (*minio.Client, error) {
+// minioStore keeps objects in a MinIO (or any S3-compatible) bucket.
+type minioStore struct {
+    client *minio.Client
+    bucket string
+
+    mu     sync.Mutex
+    exists bool // bucket has been checked/created
+}
+
+// NewMinioStoreFromEnv creates a MinIO-backed Store. The bucket is checked
+// (and created if missing) on first use, so MinIO being down at startup
+// only fails image requests rather than the whole server.
+func NewMinioStoreFromEnv() (Store, error) {
     endpoint := os.Getenv("MINIO_ENDPOINT")
     accessKeyID := os.Getenv("MINIO_ACCESS_KEY")
     secretAccessKey := os.Getenv("MINIO_SECRET_KEY")
@@ -23,23 +40,115 @@ func NewMinioClientFromEnv() (*minio.Client, error) {
         return nil, err
     }
 
-    // Ensure the bucket exists (auto-create if missing)
     bucket := os.Getenv("MINIO_BUCKET")
     if bucket == "" {
         bucket = "listing-images"
     }
-    ctx := context.Background()
-    exists, err := client.BucketExists(ctx, bucket)
+    return &minioStore{client: client, bucket: bucket}, nil
+}
+
+// ensureBucket makes sure the bucket exists (auto-create if missing),
+// retrying on every call until it succeeds once.
+func (s *minioStore) ensureBucket(ctx context.Context) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.exists {
+        return nil
+    }
+    exists, err := s.client.BucketExists(ctx, s.bucket)
     if err != nil {
-        return nil, fmt.Errorf("could not check bucket: %w", err)
+        return fmt.Errorf("could not check bucket: %w", err)
     }
     if !exists {
-        err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
-        if err != nil {
-            return nil, fmt.Errorf("could not create bucket: %w", err)
+        if err := s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{}); err != nil {
+            return fmt.Errorf("could not create bucket: %w", err)
+        }
+        fmt.Println("Created bucket:", s.bucket)
+    }
+    s.exists = true
+    return nil
+}
+
+func (s *minioStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+    if err := s.ensureBucket(ctx); err != nil {
+        return err
+    }
+    _, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+    return err
+}
+
+func (s *minioStore) Delete(ctx context.Context, key string) error {
+    if err := s.ensureBucket(ctx); err != nil {
+        return err
+    }
+    return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *minioStore) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+    u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, nil)
+    if err != nil {
+        return "", err
+    }
+    return u.String(), nil
+}
+
+func (s *minioStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    if err := s.ensureBucket(ctx); err != nil {
+        return ObjectInfo{}, err
+    }
+    info, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+    if err != nil {
+        if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+            return ObjectInfo{}, ErrNotFound
         }
-        fmt.Println("Created bucket:", bucket)
+        return ObjectInfo{}, err
+    }
+    return ObjectInfo{
+        Key:          info.Key,
+        Size:         info.Size,
+        ContentType:  info.ContentType,
+        LastModified: info.LastModified,
+    }, nil
+}
+
+func (s *minioStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    // stat first: GetObject is lazy and would only fail on the first read
+    if _, err := s.Stat(ctx, key); err != nil {
+        return nil, err
     }
+    return s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+}
 
-    return client, nil
+func (s *minioStore) SignedPutURL(ctx context.Context, key string, ttl time.Duration, contentType string, size int64) (string, error) {
+    if err := s.ensureBucket(ctx); err != nil {
+        return "", err
+    }
+    headers := http.Header{
+        "Content-Type":   {contentType},
+        "Content-Length": {strconv.FormatInt(size, 10)},
+    }
+    u, err := s.client.PresignHeader(ctx, http.MethodPut, s.bucket, key, ttl, nil, headers)
+    if err != nil {
+        return "", err
+    }
+    return u.String(), nil
+}
+
+func (s *minioStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+    if err := s.ensureBucket(ctx); err != nil {
+        return nil, err
+    }
+    var out []ObjectInfo
+    for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+        if obj.Err != nil {
+            return nil, obj.Err
+        }
+        out = append(out, ObjectInfo{
+            Key:          obj.Key,
+            Size:         obj.Size,
+            ContentType:  obj.ContentType,
+            LastModified: obj.LastModified,
+        })
+    }
+    return out, nil
 }
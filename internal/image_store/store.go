@@ -0,0 +1,78 @@
+package image_store
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+)
+
+// ErrNotFound is returned when an object doesn't exist.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+    Key          string
+    Size         int64
+    ContentType  string
+    LastModified time.Time
+}
+
+// Store is where listing images are kept. Keys are slash-separated object
+// names such as "listings/<id>/<hash>.jpg".
+type Store interface {
+    Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+    Delete(ctx context.Context, key string) error
+    // SignedURL returns a URL that serves the object until ttl elapses.
+    SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+    Stat(ctx context.Context, key string) (ObjectInfo, error)
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    // List returns every object whose key starts with prefix, sorted by key.
+    List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+    // SignedPutURL returns a URL a client can PUT exactly size bytes of
+    // contentType to until ttl elapses. Both values are part of the
+    // signature, so the upload must send matching Content-Type and
+    // Content-Length headers.
+    SignedPutURL(ctx context.Context, key string, ttl time.Duration, contentType string, size int64) (string, error)
+}
+
+// NewFromEnv builds the Store selected by IMAGE_STORE:
+//
+//   - "minio" (default): MINIO_ENDPOINT, MINIO_ACCESS_KEY, MINIO_SECRET_KEY,
+//     MINIO_BUCKET, MINIO_USE_SSL
+//   - "fs": files under IMAGE_STORE_DIR (default ./data/images), served by
+//     RegisterRoutes under IMAGE_STORE_URL_PREFIX (default /files) with URLs
+//     signed by IMAGE_STORE_SIGNING_KEY (default: the JWT secret)
+//   - "memory": in-process, for tests and local experiments
+//
+// Only configuration errors are returned; an unreachable MinIO is retried on
+// first use instead of failing startup.
+func NewFromEnv() (Store, error) {
+    switch backend := os.Getenv("IMAGE_STORE"); backend {
+    case "", "minio":
+        return NewMinioStoreFromEnv()
+    case "fs":
+        dir := os.Getenv("IMAGE_STORE_DIR")
+        if dir == "" {
+            dir = "./data/images"
+        }
+        prefix := os.Getenv("IMAGE_STORE_URL_PREFIX")
+        if prefix == "" {
+            prefix = "/files"
+        }
+        key := []byte(os.Getenv("IMAGE_STORE_SIGNING_KEY"))
+        if len(key) == 0 {
+            key = auth.Secret()
+        }
+        return NewFSStore(dir, prefix, key)
+    case "memory":
+        return NewMemoryStore(), nil
+    default:
+        return nil, fmt.Errorf("unknown IMAGE_STORE %q (want minio, fs or memory)", backend)
+    }
+}
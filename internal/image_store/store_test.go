@@ -0,0 +1,162 @@
+package image_store
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// exercise runs the behaviour every Store must share.
+func exercise(t *testing.T, s Store) {
+    t.Helper()
+    ctx := context.Background()
+    key := "listings/l1/abc.jpg"
+
+    if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("Stat before Put: err=%v; want ErrNotFound", err)
+    }
+    if err := s.Put(ctx, key, strings.NewReader("jpeg-bytes"), 10, "image/jpeg"); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    info, err := s.Stat(ctx, key)
+    if err != nil {
+        t.Fatalf("Stat: %v", err)
+    }
+    if info.Size != 10 || info.ContentType != "image/jpeg" {
+        t.Fatalf("Stat = %+v; want size 10, image/jpeg", info)
+    }
+    if _, err := s.SignedURL(ctx, key, time.Minute); err != nil {
+        t.Fatalf("SignedURL: %v", err)
+    }
+    if err := s.Put(ctx, "uploads/l1/u1", strings.NewReader("raw"), 3, "image/png"); err != nil {
+        t.Fatalf("Put: %v", err)
+    }
+    objs, err := s.List(ctx, "listings/")
+    if err != nil {
+        t.Fatalf("List: %v", err)
+    }
+    if len(objs) != 1 || objs[0].Key != key || objs[0].Size != 10 {
+        t.Fatalf("List(listings/) = %+v; want just %s", objs, key)
+    }
+    s.Delete(ctx, "uploads/l1/u1")
+    if err := s.Delete(ctx, key); err != nil {
+        t.Fatalf("Delete: %v", err)
+    }
+    if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotFound) {
+        t.Fatalf("Stat after Delete: err=%v; want ErrNotFound", err)
+    }
+    if err := s.Delete(ctx, key); err != nil {
+        t.Fatalf("Delete of missing object: %v", err)
+    }
+}
+
+func TestMemoryStore(t *testing.T) {
+    exercise(t, NewMemoryStore())
+}
+
+func TestFSStore(t *testing.T) {
+    s, err := NewFSStore(t.TempDir(), "/files", []byte("secret"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    exercise(t, s)
+
+    for _, key := range []string{"../escape.jpg", "/abs.jpg", "a/../../b.jpg", ""} {
+        if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+            t.Errorf("Put(%q) succeeded; want error", key)
+        }
+    }
+}
+
+func TestFSStoreServesSignedURLs(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    s, err := NewFSStore(t.TempDir(), "/files", []byte("secret"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    r := gin.New()
+    RegisterRoutes(r, s)
+
+    ctx := context.Background()
+    key := "listings/l1/abc.jpg"
+    s.Put(ctx, key, strings.NewReader("jpeg-bytes"), 10, "image/jpeg")
+
+    get := func(url string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+        return w
+    }
+
+    signed, _ := s.SignedURL(ctx, key, time.Minute)
+    w := get(signed)
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET signed URL: %d", w.Code)
+    }
+    if body, _ := io.ReadAll(w.Body); string(body) != "jpeg-bytes" {
+        t.Fatalf("body = %q", body)
+    }
+
+    if w := get(strings.Replace(signed, "abc.jpg", "other.jpg", 1)); w.Code != http.StatusForbidden {
+        t.Errorf("GET with signature for another key: %d; want 403", w.Code)
+    }
+    if w := get("/files/" + key); w.Code != http.StatusForbidden {
+        t.Errorf("GET without signature: %d; want 403", w.Code)
+    }
+    expired, _ := s.SignedURL(ctx, key, -time.Minute)
+    if w := get(expired); w.Code != http.StatusForbidden {
+        t.Errorf("GET expired URL: %d; want 403", w.Code)
+    }
+}
+
+func TestFSStoreAcceptsSignedPuts(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    s, err := NewFSStore(t.TempDir(), "/files", []byte("secret"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    r := gin.New()
+    RegisterRoutes(r, s)
+
+    ctx := context.Background()
+    key := "uploads/l1/u1"
+    put := func(url, contentType, body string) int {
+        w := httptest.NewRecorder()
+        req := httptest.NewRequest(http.MethodPut, url, strings.NewReader(body))
+        req.Header.Set("Content-Type", contentType)
+        r.ServeHTTP(w, req)
+        return w.Code
+    }
+
+    signed, _ := s.SignedPutURL(ctx, key, time.Minute, "image/png", 9)
+    if code := put(signed, "image/jpeg", "png-bytes"); code != http.StatusForbidden {
+        t.Errorf("PUT with other content type: %d; want 403", code)
+    }
+    if code := put(signed, "image/png", "png-bytes-longer"); code != http.StatusForbidden {
+        t.Errorf("PUT with other length: %d; want 403", code)
+    }
+    if code := put(strings.Replace(signed, "size=9", "size=16", 1), "image/png", "png-bytes-longer"); code != http.StatusForbidden {
+        t.Errorf("PUT with tampered size: %d; want 403", code)
+    }
+    if code := put(signed, "image/png", "png-bytes"); code != http.StatusOK {
+        t.Fatalf("PUT signed URL: %d", code)
+    }
+
+    rc, err := s.Get(ctx, key)
+    if err != nil {
+        t.Fatalf("Get: %v", err)
+    }
+    defer rc.Close()
+    if body, _ := io.ReadAll(rc); string(body) != "png-bytes" {
+        t.Fatalf("stored body = %q", body)
+    }
+    if _, err := s.Get(ctx, "uploads/l1/missing"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Get missing: err=%v; want ErrNotFound", err)
+    }
+}
@@ -0,0 +1,105 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 when
+// the data isn't a JPEG or carries no orientation tag. Re-encoding drops the
+// tag, so it has to be applied to the pixels first or phone photos would
+// come out sideways.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image: no more metadata
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		if marker == 0xE1 { // APP1
+			if o := exifOrientation(data[i+4 : i+2+size]); o != 0 {
+				return o
+			}
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from IFD0 of an APP1 Exif
+// segment, returning 0 if it's absent or malformed.
+func exifOrientation(seg []byte) int {
+	if len(seg) < 14 || string(seg[:6]) != "Exif\x00\x00" {
+		return 0
+	}
+	tiff := seg[6:]
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(bo.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(bo.Uint16(tiff[ifd:]))
+	for k := 0; k < entries; k++ {
+		e := ifd + 2 + k*12
+		if e+12 > len(tiff) {
+			return 0
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 {
+			if o := int(bo.Uint16(tiff[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orient transforms img so it displays upright for the given EXIF orientation.
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // the transposing orientations swap width and height
+		dw, dh = h, w
+	}
+	// src maps a destination pixel to the source pixel it comes from
+	src := map[int]func(x, y int) (int, int){
+		2: func(x, y int) (int, int) { return w - 1 - x, y },         // mirror horizontally
+		3: func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }, // rotate 180
+		4: func(x, y int) (int, int) { return x, h - 1 - y },         // mirror vertically
+		5: func(x, y int) (int, int) { return y, x },                 // transpose
+		6: func(x, y int) (int, int) { return y, h - 1 - x },         // rotate 90 clockwise
+		7: func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }, // transverse
+		8: func(x, y int) (int, int) { return w - 1 - y, x },         // rotate 90 counter-clockwise
+	}[orientation]
+
+	out := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := src(x, y)
+			si := img.PixOffset(sx+img.Rect.Min.X, sy+img.Rect.Min.Y)
+			di := out.PixOffset(x, y)
+			copy(out.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return out
+}
@@ -0,0 +1,189 @@
+// Package imaging validates uploaded images and produces the sanitized
+// renditions we store: metadata is dropped by re-encoding, and object names
+// are derived from the content hash rather than client filenames.
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+var (
+	ErrTooLarge        = errors.New("image exceeds maximum file size")
+	ErrUnsupportedType = errors.New("unsupported image type (allowed: JPEG, PNG, WebP)")
+	ErrDimensions      = errors.New("image dimensions exceed limit")
+	ErrCorrupt         = errors.New("image could not be decoded")
+)
+
+// allowedTypes are the sniffed content types we accept.
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// Allowed reports whether contentType is one we accept, for checking a
+// client's declared type before any bytes are sent. Process still sniffs
+// the content itself.
+func Allowed(contentType string) bool {
+	return allowedTypes[contentType]
+}
+
+// Limits bound what uploads are accepted.
+type Limits struct {
+	MaxBytes     int64 // encoded file size
+	MaxDimension int   // width and height of the source image, in pixels
+}
+
+// DefaultLimits are used when no environment overrides are set.
+var DefaultLimits = Limits{MaxBytes: 10 << 20, MaxDimension: 8000}
+
+// LimitsFromEnv reads IMAGE_MAX_BYTES and IMAGE_MAX_DIMENSION, falling back
+// to DefaultLimits for unset or invalid values.
+func LimitsFromEnv() Limits {
+	lim := DefaultLimits
+	if n, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		lim.MaxBytes = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("IMAGE_MAX_DIMENSION")); err == nil && n > 0 {
+		lim.MaxDimension = n
+	}
+	return lim
+}
+
+// rendition sizes, as the maximum length of the longer side
+const (
+	largeSize  = 2560
+	mediumSize = 1024
+	thumbSize  = 320
+	jpegQual   = 85
+)
+
+// Rendition is one encoded size of a processed image.
+type Rendition struct {
+	Suffix      string // appended to the hash in the filename: "", "_medium" or "_thumb"
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Filename is the content-addressed file name for this rendition.
+func (r Rendition) Filename(hash string) string {
+	ext := ".jpg"
+	if r.ContentType == "image/png" {
+		ext = ".png"
+	}
+	return hash + r.Suffix + ext
+}
+
+// Result holds the renditions of a processed upload.
+type Result struct {
+	Hash   string // hex SHA-256 of the full-size rendition
+	Full   Rendition
+	Medium Rendition
+	Thumb  Rendition
+}
+
+// Renditions lists all renditions, full size first.
+func (r *Result) Renditions() []Rendition {
+	return []Rendition{r.Full, r.Medium, r.Thumb}
+}
+
+// Process reads an upload, checks its real content type, size and
+// dimensions, applies its EXIF orientation and re-encodes it into the full,
+// medium and thumbnail renditions. Re-encoding drops EXIF (including GPS)
+// and any other embedded metadata.
+func Process(r io.Reader, lim Limits) (*Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, lim.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > lim.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	if !allowedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedType
+	}
+
+	// check dimensions from the header before decoding the pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if cfg.Width > lim.MaxDimension || cfg.Height > lim.MaxDimension {
+		return nil, fmt.Errorf("%w: %dx%d, max %d", ErrDimensions, cfg.Width, cfg.Height, lim.MaxDimension)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	full := orient(fit(src, largeSize), jpegOrientation(data))
+	res := &Result{}
+	if res.Full, err = encode(full, ""); err != nil {
+		return nil, err
+	}
+	if res.Medium, err = encode(fit(full, mediumSize), "_medium"); err != nil {
+		return nil, err
+	}
+	if res.Thumb, err = encode(fit(full, thumbSize), "_thumb"); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(res.Full.Data)
+	res.Hash = hex.EncodeToString(sum[:])
+	return res, nil
+}
+
+// fit scales img down so its longer side is at most size, converting it to
+// NRGBA either way. Images are never scaled up.
+func fit(img image.Image, size int) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	}
+	return dst
+}
+
+// encode writes opaque images as JPEG and images with transparency as PNG.
+func encode(img *image.NRGBA, suffix string) (Rendition, error) {
+	var buf bytes.Buffer
+	r := Rendition{Suffix: suffix, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if img.Opaque() {
+		r.ContentType = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQual}); err != nil {
+			return r, err
+		}
+	} else {
+		r.ContentType = "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return r, err
+		}
+	}
+	r.Data = buf.Bytes()
+	return r, nil
+}
@@ -0,0 +1,176 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solid(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+// withOrientation splices an APP1 Exif segment carrying the given
+// orientation right after the JPEG SOI marker.
+func withOrientation(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))      // IFD0 offset
+	binary.Write(&tiff, binary.BigEndian, uint16(1))      // one entry
+	binary.Write(&tiff, binary.BigEndian, uint16(0x0112)) // Orientation
+	binary.Write(&tiff, binary.BigEndian, uint16(3))      // SHORT
+	binary.Write(&tiff, binary.BigEndian, uint32(1))      // count
+	binary.Write(&tiff, binary.BigEndian, orientation)    // value
+	binary.Write(&tiff, binary.BigEndian, uint16(0))      // padding
+	binary.Write(&tiff, binary.BigEndian, uint32(0))      // no next IFD
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(jpg[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(jpg[2:])
+	return out.Bytes()
+}
+
+func TestProcessRenditionSizes(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, solid(3000, 1500, color.NRGBA{200, 100, 50, 255}))
+
+	res, err := Process(&buf, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	want := map[string][2]int{"": {2560, 1280}, "_medium": {1024, 512}, "_thumb": {320, 160}}
+	for _, r := range res.Renditions() {
+		if got := [2]int{r.Width, r.Height}; got != want[r.Suffix] {
+			t.Errorf("rendition %q is %v; want %v", r.Suffix, got, want[r.Suffix])
+		}
+		// opaque PNGs are stored as JPEG
+		if r.ContentType != "image/jpeg" {
+			t.Errorf("rendition %q content type %s; want image/jpeg", r.Suffix, r.ContentType)
+		}
+	}
+	if got := res.Thumb.Filename(res.Hash); got != res.Hash+"_thumb.jpg" {
+		t.Errorf("thumb filename %q", got)
+	}
+}
+
+func TestProcessKeepsTransparencyAsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, solid(10, 10, color.NRGBA{0, 0, 0, 0}))
+
+	res, err := Process(&buf, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if res.Full.ContentType != "image/png" {
+		t.Fatalf("content type %s; want image/png", res.Full.ContentType)
+	}
+}
+
+func TestProcessIsContentAddressed(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, solid(50, 50, color.NRGBA{1, 2, 3, 255}))
+	data := buf.Bytes()
+
+	a, err := Process(bytes.NewReader(data), DefaultLimits)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	b, _ := Process(bytes.NewReader(data), DefaultLimits)
+	if a.Hash != b.Hash || len(a.Hash) != 64 {
+		t.Fatalf("hashes %q and %q; want equal sha256 hex", a.Hash, b.Hash)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	var gifBuf, pngBuf bytes.Buffer
+	gif.Encode(&gifBuf, solid(10, 10, color.NRGBA{255, 0, 0, 255}), nil)
+	png.Encode(&pngBuf, solid(100, 20, color.NRGBA{255, 0, 0, 255}))
+
+	tests := []struct {
+		name string
+		data []byte
+		lim  Limits
+		want error
+	}{
+		{"gif", gifBuf.Bytes(), DefaultLimits, ErrUnsupportedType},
+		{"text disguised as image", []byte("<html>not an image</html>"), DefaultLimits, ErrUnsupportedType},
+		{"too many bytes", pngBuf.Bytes(), Limits{MaxBytes: 10, MaxDimension: 8000}, ErrTooLarge},
+		{"too wide", pngBuf.Bytes(), Limits{MaxBytes: 1 << 20, MaxDimension: 50}, ErrDimensions},
+		{"truncated", pngBuf.Bytes()[:60], DefaultLimits, ErrCorrupt},
+	}
+	for _, tc := range tests {
+		if _, err := Process(bytes.NewReader(tc.data), tc.lim); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err=%v; want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestProcessStripsExifAndAppliesOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, solid(40, 20, color.NRGBA{10, 200, 10, 255}), nil)
+	data := withOrientation(t, buf.Bytes(), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("test fixture orientation not readable")
+	}
+
+	res, err := Process(bytes.NewReader(data), DefaultLimits)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if res.Full.Width != 20 || res.Full.Height != 40 {
+		t.Fatalf("full size %dx%d; want 20x40 after rotating", res.Full.Width, res.Full.Height)
+	}
+	for _, r := range res.Renditions() {
+		if bytes.Contains(r.Data, []byte("Exif")) {
+			t.Errorf("rendition %q still carries EXIF", r.Suffix)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1 image: red, blue
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	copy(img.Pix, []uint8{255, 0, 0, 255, 0, 0, 255, 255})
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+
+	tests := []struct {
+		orientation  int
+		w, h         int
+		first, other color.NRGBA // pixel at (0,0) and at the opposite end
+	}{
+		{1, 2, 1, red, blue},
+		{2, 2, 1, blue, red},
+		{3, 2, 1, blue, red},
+		{6, 1, 2, red, blue},
+		{8, 1, 2, blue, red},
+	}
+	for _, tc := range tests {
+		out := orient(img, tc.orientation)
+		b := out.Bounds()
+		if b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("orientation %d: size %dx%d; want %dx%d", tc.orientation, b.Dx(), b.Dy(), tc.w, tc.h)
+			continue
+		}
+		if got := out.NRGBAAt(0, 0); got != tc.first {
+			t.Errorf("orientation %d: (0,0)=%v; want %v", tc.orientation, got, tc.first)
+		}
+		if got := out.NRGBAAt(tc.w-1, tc.h-1); got != tc.other {
+			t.Errorf("orientation %d: far corner=%v; want %v", tc.orientation, got, tc.other)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package ledger
+
+import (
+    "encoding/csv"
+    "io"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// WriteEntriesCSV writes one row per posting, with its entry's details,
+// for the finance team's spreadsheets. Debits and credits get their own
+// columns, as positive decimals.
+func WriteEntriesCSV(w io.Writer, entries []Entry) error {
+    cw := csv.NewWriter(w)
+    cw.Write([]string{"entry_id", "created_at", "kind", "ref", "order_id", "seller_id", "account", "debit", "credit", "currency", "memo"})
+    for _, e := range entries {
+        orderID := ""
+        if e.OrderID != nil {
+            orderID = *e.OrderID
+        }
+        for _, p := range e.Postings {
+            debit, credit := "", ""
+            if p.Amount.Amount >= 0 {
+                debit = p.Amount.Decimal()
+            } else {
+                credit = money.New(-p.Amount.Amount, p.Amount.Currency).Decimal()
+            }
+            cw.Write([]string{e.ID, e.CreatedAt.UTC().Format(time.RFC3339), e.Kind, e.Ref, orderID, e.SellerID,
+                p.Account, debit, credit, p.Amount.Currency, e.Memo})
+        }
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// PayoutRecipient is who a payout goes to, as the bank needs it.
+type PayoutRecipient struct {
+    Name  string
+    Email string
+}
+
+// WritePayoutsCSV writes a batch's payouts, one row each, with the
+// sellers' names and emails from recipients.
+func WritePayoutsCSV(w io.Writer, b *Batch, recipients map[string]PayoutRecipient) error {
+    cw := csv.NewWriter(w)
+    cw.Write([]string{"payout_id", "batch_id", "seller_id", "seller_name", "seller_email", "amount", "currency", "created_at"})
+    for _, p := range b.Payouts {
+        r := recipients[p.SellerID]
+        cw.Write([]string{p.ID, b.ID, p.SellerID, r.Name, r.Email, p.Amount.Decimal(), p.Amount.Currency,
+            p.CreatedAt.UTC().Format(time.RFC3339)})
+    }
+    cw.Flush()
+    return cw.Error()
+}
@@ -0,0 +1,202 @@
+package ledger
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service) {
+    mine := r.Group("/sellers/me", auth.Middleware(), seller.RequireSeller(sellers))
+
+    // GET /sellers/me/balance – what the platform owes the seller
+    mine.GET("/balance", func(c *gin.Context) {
+        sl := seller.FromContext(c)
+        balances, err := svc.Balances(sl.ID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        cm, err := svc.Commission(sl.ID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"sellerId": sl.ID, "commissionBp": cm.RateBP, "balances": balances})
+    })
+
+    mine.GET("/payouts", func(c *gin.Context) {
+        list, err := svc.Payouts(seller.FromContext(c).ID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, list)
+    })
+
+    admin := r.Group("/admin", auth.Middleware(), auth.RequireAdmin())
+
+    // GET /admin/ledger/entries – this month unless from and to are given;
+    // ?format=csv for a spreadsheet
+    admin.GET("/ledger/entries", func(c *gin.Context) {
+        now := time.Now().UTC()
+        from, err := queryTime(c, "from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        to, err := queryTime(c, "to", now)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if !from.Before(to) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+            return
+        }
+        entries, err := svc.Entries(from, to, c.Query("sellerId"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        switch c.DefaultQuery("format", "json") {
+        case "json":
+            c.JSON(http.StatusOK, entries)
+        case "csv":
+            var buf bytes.Buffer
+            if err := WriteEntriesCSV(&buf, entries); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+            }
+            name := fmt.Sprintf("ledger-%s-%s.csv", from.Format(time.DateOnly), to.Format(time.DateOnly))
+            c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+            c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+        default:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+        }
+    })
+
+    // POST /admin/payouts/batches – pay out every available balance now
+    admin.POST("/payouts/batches", func(c *gin.Context) {
+        b, err := svc.RunPayouts(c.GetString(string(auth.CtxEmailKey)))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusCreated, b)
+    })
+
+    admin.GET("/payouts/batches", func(c *gin.Context) {
+        list, err := svc.Batches()
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, list)
+    })
+
+    // GET /admin/payouts/batches/:id – with its payouts; ?format=csv for
+    // the bank transfers
+    admin.GET("/payouts/batches/:id", func(c *gin.Context) {
+        b, err := svc.Batch(c.Param("id"))
+        if err != nil {
+            if errors.Is(err, ErrBatchNotFound) {
+                c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                return
+            }
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        switch c.DefaultQuery("format", "json") {
+        case "json":
+            c.JSON(http.StatusOK, b)
+        case "csv":
+            recipients := make(map[string]PayoutRecipient)
+            for _, p := range b.Payouts {
+                if sl, err := sellers.GetByID(p.SellerID); err == nil {
+                    recipients[p.SellerID] = PayoutRecipient{Name: sl.Name, Email: sl.Email}
+                }
+            }
+            var buf bytes.Buffer
+            if err := WritePayoutsCSV(&buf, b, recipients); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+            }
+            c.Header("Content-Disposition", `attachment; filename="payouts-`+b.ID+`.csv"`)
+            c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+        default:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+        }
+    })
+
+    // GET /admin/sellers/:id/commission – the seller's rate, or the default
+    admin.GET("/sellers/:id/commission", func(c *gin.Context) {
+        if _, err := sellers.GetByID(c.Param("id")); err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+            return
+        }
+        cm, err := svc.Commission(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, cm)
+    })
+
+    // PUT /admin/sellers/:id/commission – {"rateBp": 1250}; applies to
+    // orders accepted from now on
+    admin.PUT("/sellers/:id/commission", func(c *gin.Context) {
+        var body struct {
+            RateBP *int `json:"rateBp" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        setCommission(c, svc, sellers, body.RateBP)
+    })
+
+    // DELETE /admin/sellers/:id/commission – back to the default
+    admin.DELETE("/sellers/:id/commission", func(c *gin.Context) {
+        setCommission(c, svc, sellers, nil)
+    })
+}
+
+func setCommission(c *gin.Context, svc Service, sellers seller.Service, rateBP *int) {
+    if _, err := sellers.GetByID(c.Param("id")); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    cm, err := svc.SetCommission(c.Param("id"), rateBP)
+    if err != nil {
+        if errors.Is(err, ErrInvalidRate) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, cm)
+}
+
+// queryTime reads the name query parameter as RFC 3339 or YYYY-MM-DD.
+func queryTime(c *gin.Context, name string, def time.Time) (time.Time, error) {
+    raw := c.Query(name)
+    if raw == "" {
+        return def, nil
+    }
+    t, err := time.Parse(time.RFC3339, raw)
+    if err != nil {
+        if t, err = time.Parse(time.DateOnly, raw); err != nil {
+            return t, fmt.Errorf("%s must be RFC 3339 or YYYY-MM-DD", name)
+        }
+    }
+    return t, nil
+}
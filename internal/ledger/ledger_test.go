@@ -0,0 +1,86 @@
+package ledger
+
+import (
+    "bytes"
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func TestSplitSale(t *testing.T) {
+    r := Rates{FeeBP: 290, FeeFixed: 30}
+    for _, tc := range []struct {
+        name                        string
+        captured, tax, discount     int64
+        bp                          int
+        fee, commission, sellerPart int64
+    }{
+        // 20.00 of food plus 2.60 HST: 10% of the 20.00
+        {"taxed", 2260, 260, 0, 1000, 96, 200, 2060},
+        // the platform paid 5.00 of a 25.00 order: commission on 25.00,
+        // and the seller is made whole
+        {"platform discount", 2000, 0, 500, 1000, 88, 250, 2250},
+        {"no commission", 1000, 0, 0, 0, 59, 0, 1000},
+        {"fee capped", 20, 0, 0, 1000, 20, 2, 18},
+    } {
+        s := splitSale(tc.captured, tc.tax, tc.discount, tc.bp, r)
+        if s.Fee != tc.fee || s.Commission != tc.commission || s.Seller != tc.sellerPart {
+            t.Errorf("%s: fee %d, commission %d, seller %d; want %d, %d, %d",
+                tc.name, s.Fee, s.Commission, s.Seller, tc.fee, tc.commission, tc.sellerPart)
+        }
+        var sum int64
+        ps := s.postings("s1", "CAD")
+        for _, p := range ps {
+            sum += p.Amount.Amount
+        }
+        if sum != 0 {
+            t.Errorf("%s: postings are off by %d: %+v", tc.name, sum, ps)
+        }
+        if got := saleSplit(&Entry{Postings: ps}); got != s {
+            t.Errorf("%s: read back %+v, want %+v", tc.name, got, s)
+        }
+    }
+}
+
+func TestRefundShare(t *testing.T) {
+    s := splitSale(1000, 0, 333, 1000, Rates{}) // commission 133, promotion 333
+
+    // three partial refunds that together refund everything give back
+    // all the commission and discount, despite rounding each time
+    var before, commission, promotion int64
+    for _, amount := range []int64{333, 333, 334} {
+        c, p := refundShare(s, before, amount)
+        commission, promotion, before = commission+c, promotion+p, before+amount
+    }
+    if commission != s.Commission || promotion != s.Promotion {
+        t.Errorf("refunded commission %d, promotion %d; want %d, %d", commission, promotion, s.Commission, s.Promotion)
+    }
+
+    c, p := refundShare(s, 0, 500)
+    if c != 67 || p != 167 {
+        t.Errorf("half refund: commission %d, promotion %d; want 67, 167", c, p)
+    }
+}
+
+func TestWriteEntriesCSV(t *testing.T) {
+    order := "o1"
+    entries := []Entry{{
+        ID: "e1", Kind: KindRefund, Ref: "r1", OrderID: &order, SellerID: "s1",
+        Memo: "missing naan, sorry", CreatedAt: time.Date(2026, 3, 14, 18, 0, 0, 0, time.UTC),
+        Postings: []Posting{
+            {Account: AccountClearing, Amount: money.New(-550, "CAD")},
+            {Account: SellerAvailable("s1"), Amount: money.New(550, "CAD")},
+        },
+    }}
+    var buf bytes.Buffer
+    if err := WriteEntriesCSV(&buf, entries); err != nil {
+        t.Fatal(err)
+    }
+    want := "entry_id,created_at,kind,ref,order_id,seller_id,account,debit,credit,currency,memo\n" +
+        "e1,2026-03-14T18:00:00Z,refund,r1,o1,s1,platform:clearing,,5.50,CAD,\"missing naan, sorry\"\n" +
+        "e1,2026-03-14T18:00:00Z,refund,r1,o1,s1,seller:s1:available,5.50,,CAD,\"missing naan, sorry\"\n"
+    if got := buf.String(); got != want {
+        t.Errorf("csv:\n%s\nwant:\n%s", got, want)
+    }
+}
@@ -0,0 +1,7 @@
+package ledger
+
+import "gorm.io/gorm"
+
+func Migrate(db *gorm.DB) error {
+    return db.AutoMigrate(&Entry{}, &Posting{}, &Commission{}, &Batch{}, &Payout{})
+}
@@ -0,0 +1,151 @@
+// Package ledger keeps the platform's books: a double-entry record of
+// what buyers paid, what the platform keeps, and what it owes and pays
+// out to sellers.
+package ledger
+
+import (
+    "errors"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+var (
+    ErrInvalidRate   = errors.New("commission must be between 0 and 10000 basis points")
+    ErrBatchNotFound = errors.New("payout batch not found")
+)
+
+// Platform accounts. Seller accounts are per seller, see SellerPending
+// and SellerAvailable.
+const (
+    AccountClearing   = "platform:clearing"   // card payments held by the platform
+    AccountFees       = "platform:fees"       // payment processing costs
+    AccountCommission = "platform:commission" // the platform's cut of sales
+    AccountPromotions = "platform:promotions" // discounts the platform paid for
+)
+
+// SellerPending is what the platform owes a seller for orders they
+// haven't handed over yet.
+func SellerPending(sellerID string) string { return "seller:" + sellerID + ":pending" }
+
+// SellerAvailable is what the platform owes a seller for handed-over
+// orders; payouts pay it out.
+func SellerAvailable(sellerID string) string { return "seller:" + sellerID + ":available" }
+
+// Entry kinds
+const (
+    KindSale       = "sale"       // an order's payment was captured
+    KindCompletion = "completion" // the order was handed over
+    KindRefund     = "refund"     // some of the payment was given back
+    KindPayout     = "payout"     // a seller was paid
+)
+
+// Entry is one event in the books. Its postings add up to zero.
+type Entry struct {
+    ID        string    `json:"id" gorm:"type:uuid;primaryKey"`
+    Kind      string    `json:"kind" gorm:"type:varchar(20);not null;uniqueIndex:idx_ledger_entry_ref,priority:1"`
+    Ref       string    `json:"ref" gorm:"type:varchar(100);not null;uniqueIndex:idx_ledger_entry_ref,priority:2"` // order, refund or payout ID, so each is posted once
+    OrderID   *string   `json:"orderId,omitempty" gorm:"type:uuid;index"`
+    SellerID  string    `json:"sellerId" gorm:"type:uuid;not null;index"`
+    Memo      string    `json:"memo" gorm:"type:text;not null;default:''"`
+    CreatedAt time.Time `json:"createdAt" gorm:"index"`
+    Postings  []Posting `json:"postings" gorm:"foreignKey:EntryID"`
+}
+
+func (Entry) TableName() string { return "ledger_entries" }
+
+// Posting moves Amount into or out of an account: debits are positive and
+// credits negative. Seller accounts are owed money, so their balances are
+// credits.
+type Posting struct {
+    ID      string      `json:"-" gorm:"type:uuid;primaryKey"`
+    EntryID string      `json:"-" gorm:"type:uuid;not null;index"`
+    Account string      `json:"account" gorm:"type:varchar(100);not null;index"`
+    Amount  money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
+
+func (Posting) TableName() string { return "ledger_postings" }
+
+// Commission overrides the default commission for one seller.
+type Commission struct {
+    SellerID  string    `json:"sellerId" gorm:"type:uuid;primaryKey"`
+    RateBP    int       `json:"rateBp" gorm:"not null"` // basis points of the seller's sales
+    Default   bool      `json:"default" gorm:"-"`       // no override; the platform default applies
+    UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Commission) TableName() string { return "seller_commissions" }
+
+// Batch is one payout run: every seller with money available is paid it.
+type Batch struct {
+    ID        string    `json:"id" gorm:"type:uuid;primaryKey"`
+    CreatedBy string    `json:"createdBy" gorm:"type:varchar(100);not null"`
+    CreatedAt time.Time `json:"createdAt" gorm:"index"`
+    Payouts   []Payout  `json:"payouts,omitempty" gorm:"foreignKey:BatchID"`
+}
+
+func (Batch) TableName() string { return "payout_batches" }
+
+// Payout is what one seller was paid, in one currency, in a batch.
+type Payout struct {
+    ID        string      `json:"id" gorm:"type:uuid;primaryKey"`
+    BatchID   string      `json:"batchId" gorm:"type:uuid;not null;index"`
+    SellerID  string      `json:"sellerId" gorm:"type:uuid;not null;index"`
+    Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+    CreatedAt time.Time   `json:"createdAt"`
+}
+
+func (Payout) TableName() string { return "payouts" }
+
+// Balance is what the platform owes a seller in one currency. Available
+// can go negative when a handed-over order is refunded after a payout;
+// later sales make it up.
+type Balance struct {
+    Currency  string      `json:"currency"`
+    Pending   money.Money `json:"pending"`
+    Available money.Money `json:"available"`
+    PaidOut   money.Money `json:"paidOut"`
+}
+
+// Rates are the platform's default commission and the card processing
+// fee it pays per captured order.
+type Rates struct {
+    CommissionBP int   // basis points of the seller's net sales, before tax
+    FeeBP        int   // basis points of the captured amount
+    FeeFixed     int64 // minor units per captured order
+}
+
+// DefaultRates are used when no environment overrides are set.
+var DefaultRates = Rates{CommissionBP: 1000, FeeBP: 290, FeeFixed: 30}
+
+// RatesFromEnv reads PLATFORM_COMMISSION_BP, PAYMENT_FEE_BP and
+// PAYMENT_FEE_FIXED, falling back to DefaultRates for unset or invalid
+// values.
+func RatesFromEnv() Rates {
+    r := DefaultRates
+    if n, err := strconv.Atoi(os.Getenv("PLATFORM_COMMISSION_BP")); err == nil && validRate(n) {
+        r.CommissionBP = n
+    }
+    if n, err := strconv.Atoi(os.Getenv("PAYMENT_FEE_BP")); err == nil && validRate(n) {
+        r.FeeBP = n
+    }
+    if n, err := strconv.ParseInt(os.Getenv("PAYMENT_FEE_FIXED"), 10, 64); err == nil && n >= 0 {
+        r.FeeFixed = n
+    }
+    return r
+}
+
+func validRate(bp int) bool {
+    return bp >= 0 && bp <= 10000
+}
+
+// PayoutIntervalFromEnv reads PAYOUT_INTERVAL (a Go duration, default
+// 168h); "0" disables scheduled payouts.
+func PayoutIntervalFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("PAYOUT_INTERVAL")); err == nil && d >= 0 {
+        return d
+    }
+    return 7 * 24 * time.Hour
+}
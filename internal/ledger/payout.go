@@ -0,0 +1,45 @@
+package ledger
+
+import (
+    "context"
+    "log"
+    "time"
+)
+
+// payoutActor is recorded as the creator of scheduled payout batches.
+const payoutActor = "system:payouts"
+
+// StartPayoutWorker runs a payout batch once the last one is at least
+// every old, until ctx is cancelled. It goes by the last batch rather
+// than by process start, so restarts don't pay out early. A zero
+// interval disables it.
+func StartPayoutWorker(ctx context.Context, svc Service, every time.Duration) {
+    if every <= 0 {
+        return
+    }
+    go func() {
+        t := time.NewTicker(min(every, time.Hour))
+        defer t.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+            }
+            batches, err := svc.Batches()
+            if err != nil {
+                log.Printf("payouts: %v", err)
+                continue
+            }
+            if len(batches) > 0 && time.Since(batches[0].CreatedAt) < every {
+                continue
+            }
+            b, err := svc.RunPayouts(payoutActor)
+            if err != nil {
+                log.Printf("payouts: %v", err)
+                continue
+            }
+            log.Printf("payouts: batch %s paid %d sellers", b.ID, len(b.Payouts))
+        }
+    }()
+}
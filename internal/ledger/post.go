@@ -0,0 +1,242 @@
+package ledger
+
+import (
+    "fmt"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+// split is how a captured order's money is shared out, in minor units.
+// The platform pays the card fee and any discount it funded, out of its
+// commission; the seller gets the rest, taxes included, since they remit
+// them.
+type split struct {
+    Captured   int64
+    Fee        int64
+    Promotion  int64 // platform-funded discount, made up to the seller
+    Commission int64
+    Seller     int64
+}
+
+// splitSale shares out captured, of which tax is taxes and which the
+// platform topped up by platformDiscount. Commission is charged on what
+// the seller's items sold for before tax.
+func splitSale(captured, tax, platformDiscount int64, commissionBP int, r Rates) split {
+    s := split{Captured: captured, Promotion: platformDiscount}
+    s.Fee = min(basisPoints(captured, r.FeeBP)+r.FeeFixed, captured)
+    s.Commission = basisPoints(max(captured-tax+platformDiscount, 0), commissionBP)
+    s.Seller = captured + platformDiscount - s.Commission
+    return s
+}
+
+// postings books the sale: the payment comes in net of the fee, and the
+// seller is owed their part until the order is handed over.
+func (s split) postings(sellerID, currency string) []Posting {
+    return nonZero([]Posting{
+        {Account: AccountClearing, Amount: money.New(s.Captured-s.Fee, currency)},
+        {Account: AccountFees, Amount: money.New(s.Fee, currency)},
+        {Account: AccountPromotions, Amount: money.New(s.Promotion, currency)},
+        {Account: AccountCommission, Amount: money.New(-s.Commission, currency)},
+        {Account: SellerPending(sellerID), Amount: money.New(-s.Seller, currency)},
+    })
+}
+
+// saleSplit reads a split back from a sale entry's postings.
+func saleSplit(e *Entry) split {
+    var s split
+    for _, p := range e.Postings {
+        switch p.Account {
+        case AccountClearing:
+            s.Captured += p.Amount.Amount
+        case AccountFees:
+            s.Captured += p.Amount.Amount
+            s.Fee = p.Amount.Amount
+        case AccountPromotions:
+            s.Promotion = p.Amount.Amount
+        case AccountCommission:
+            s.Commission = -p.Amount.Amount
+        default:
+            s.Seller = -p.Amount.Amount
+        }
+    }
+    return s
+}
+
+// refundShare is the commission and platform discount a refund of amount
+// takes back, after before was refunded already. Each is the sale's
+// share in proportion to what's been refunded in all, so a full refund
+// takes back all of both whatever the rounding along the way. The card
+// fee isn't returned.
+func refundShare(s split, before, amount int64) (commission, promotion int64) {
+    share := func(x int64) int64 {
+        return divRound(x*(before+amount), s.Captured) - divRound(x*before, s.Captured)
+    }
+    return share(s.Commission), share(s.Promotion)
+}
+
+// basisPoints is bp ten-thousandths of amount, rounded half up.
+func basisPoints(amount int64, bp int) int64 {
+    return divRound(amount*int64(bp), 10000)
+}
+
+func divRound(a, b int64) int64 {
+    if b == 0 {
+        return 0
+    }
+    return (2*a + b) / (2 * b)
+}
+
+func nonZero(ps []Posting) []Posting {
+    var out []Posting
+    for _, p := range ps {
+        if p.Amount.Amount != 0 {
+            out = append(out, p)
+        }
+    }
+    return out
+}
+
+func (s *postgresService) PostSale(tx *gorm.DB, orderID, sellerID string, captured money.Money) error {
+    if captured.Amount <= 0 {
+        return nil
+    }
+    sale, err := find(tx, KindSale, orderID)
+    if err != nil || sale != nil {
+        return err
+    }
+    var taxes, discount int64
+    err = tx.Model(&tax.Line{}).Where("order_id = ?", orderID).
+        Select("coalesce(sum(amount_amount), 0)").Scan(&taxes).Error
+    if err != nil {
+        return err
+    }
+    err = tx.Model(&promo.Line{}).Where("order_id = ? AND funding = ?", orderID, promo.FundedByPlatform).
+        Select("coalesce(sum(amount_amount), 0)").Scan(&discount).Error
+    if err != nil {
+        return err
+    }
+    c, err := s.commission(tx, sellerID)
+    if err != nil {
+        return err
+    }
+    sp := splitSale(captured.Amount, taxes, discount, c.RateBP, s.rates)
+    return post(tx, &Entry{
+        Kind:     KindSale,
+        Ref:      orderID,
+        OrderID:  &orderID,
+        SellerID: sellerID,
+        Memo:     fmt.Sprintf("commission %d.%02d%%", c.RateBP/100, c.RateBP%100),
+        Postings: sp.postings(sellerID, captured.Currency),
+    })
+}
+
+func (s *postgresService) PostCompletion(tx *gorm.DB, orderID string) error {
+    sale, err := find(tx, KindSale, orderID)
+    if err != nil || sale == nil {
+        return err // orders from before the ledger aren't in it
+    }
+    done, err := find(tx, KindCompletion, orderID)
+    if err != nil || done != nil {
+        return err
+    }
+    pending, err := orderSum(tx, orderID, SellerPending(sale.SellerID))
+    if err != nil || pending == 0 {
+        return err
+    }
+    currency := sale.Postings[0].Amount.Currency
+    return post(tx, &Entry{
+        Kind:     KindCompletion,
+        Ref:      orderID,
+        OrderID:  &orderID,
+        SellerID: sale.SellerID,
+        Postings: []Posting{
+            {Account: SellerPending(sale.SellerID), Amount: money.New(-pending, currency)},
+            {Account: SellerAvailable(sale.SellerID), Amount: money.New(pending, currency)},
+        },
+    })
+}
+
+func (s *postgresService) PostRefund(tx *gorm.DB, r *payment.Refund) error {
+    sale, err := find(tx, KindSale, r.OrderID)
+    if err != nil || sale == nil {
+        return err
+    }
+    if dup, err := find(tx, KindRefund, r.ID); err != nil || dup != nil {
+        return err
+    }
+    done, err := find(tx, KindCompletion, r.OrderID)
+    if err != nil {
+        return err
+    }
+    refunded, err := orderSum(tx, r.OrderID, AccountClearing, KindRefund)
+    if err != nil {
+        return err
+    }
+
+    // the seller gives back their part, from what they're owed for the
+    // order, and the platform its commission
+    account := SellerPending(sale.SellerID)
+    if done != nil {
+        account = SellerAvailable(sale.SellerID)
+    }
+    amount, currency := r.Amount.Amount, r.Amount.Currency
+    commission, promotion := refundShare(saleSplit(sale), -refunded, amount)
+    return post(tx, &Entry{
+        Kind:     KindRefund,
+        Ref:      r.ID,
+        OrderID:  &r.OrderID,
+        SellerID: sale.SellerID,
+        Memo:     r.Reason,
+        Postings: nonZero([]Posting{
+            {Account: AccountClearing, Amount: money.New(-amount, currency)},
+            {Account: AccountCommission, Amount: money.New(commission, currency)},
+            {Account: AccountPromotions, Amount: money.New(-promotion, currency)},
+            {Account: account, Amount: money.New(amount+promotion-commission, currency)},
+        }),
+    })
+}
+
+// find loads the entry of kind for ref with its postings, or nil.
+func find(tx *gorm.DB, kind, ref string) (*Entry, error) {
+    var e Entry
+    err := tx.Preload("Postings").Where("kind = ? AND ref = ?", kind, ref).Limit(1).Find(&e).Error
+    if err != nil || e.ID == "" {
+        return nil, err
+    }
+    return &e, nil
+}
+
+// orderSum adds up an order's postings to account, from entries of the
+// given kinds or of any kind.
+func orderSum(tx *gorm.DB, orderID, account string, kinds ...string) (int64, error) {
+    q := tx.Table("ledger_postings AS p").Joins("JOIN ledger_entries AS e ON e.id = p.entry_id").
+        Where("e.order_id = ? AND p.account = ?", orderID, account)
+    if len(kinds) > 0 {
+        q = q.Where("e.kind IN ?", kinds)
+    }
+    var n int64
+    err := q.Select("coalesce(sum(p.amount_amount), 0)").Scan(&n).Error
+    return n, err
+}
+
+// post records e, checking that it balances.
+func post(tx *gorm.DB, e *Entry) error {
+    var sum int64
+    for _, p := range e.Postings {
+        sum += p.Amount.Amount
+    }
+    if sum != 0 {
+        return fmt.Errorf("ledger: %s entry for %s is off by %d", e.Kind, e.Ref, sum)
+    }
+    e.ID = uuid.NewString()
+    for i := range e.Postings {
+        e.Postings[i].ID, e.Postings[i].EntryID = uuid.NewString(), e.ID
+    }
+    return tx.Create(e).Error
+}
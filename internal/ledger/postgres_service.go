@@ -0,0 +1,205 @@
+package ledger
+
+import (
+    "errors"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+type postgresService struct {
+    db    *gorm.DB
+    rates Rates
+}
+
+func NewPostgresService(db *gorm.DB, rates Rates) Service {
+    return &postgresService{db: db, rates: rates}
+}
+
+// commission is the seller's override, or the default.
+func (s *postgresService) commission(db *gorm.DB, sellerID string) (*Commission, error) {
+    var c Commission
+    if err := db.Limit(1).Find(&c, "seller_id = ?", sellerID).Error; err != nil {
+        return nil, err
+    }
+    if c.SellerID == "" {
+        c = Commission{SellerID: sellerID, RateBP: s.rates.CommissionBP, Default: true}
+    }
+    return &c, nil
+}
+
+func (s *postgresService) Commission(sellerID string) (*Commission, error) {
+    return s.commission(s.db, sellerID)
+}
+
+func (s *postgresService) SetCommission(sellerID string, rateBP *int) (*Commission, error) {
+    if rateBP == nil {
+        if err := s.db.Delete(&Commission{}, "seller_id = ?", sellerID).Error; err != nil {
+            return nil, err
+        }
+        return s.commission(s.db, sellerID)
+    }
+    if !validRate(*rateBP) {
+        return nil, ErrInvalidRate
+    }
+    c := Commission{SellerID: sellerID, RateBP: *rateBP, UpdatedAt: time.Now()}
+    err := s.db.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "seller_id"}},
+        DoUpdates: clause.AssignmentColumns([]string{"rate_bp", "updated_at"}),
+    }).Create(&c).Error
+    if err != nil {
+        return nil, err
+    }
+    return &c, nil
+}
+
+func (s *postgresService) Balances(sellerID string) ([]Balance, error) {
+    var sums []struct {
+        Account  string
+        Currency string
+        Amount   int64
+    }
+    err := s.db.Model(&Posting{}).
+        Select("account, amount_currency AS currency, sum(amount_amount) AS amount").
+        Where("account IN ?", []string{SellerPending(sellerID), SellerAvailable(sellerID)}).
+        Group("account, amount_currency").Scan(&sums).Error
+    if err != nil {
+        return nil, err
+    }
+    var paid []struct {
+        Currency string
+        Amount   int64
+    }
+    err = s.db.Model(&Payout{}).Select("amount_currency AS currency, sum(amount_amount) AS amount").
+        Where("seller_id = ?", sellerID).Group("amount_currency").Scan(&paid).Error
+    if err != nil {
+        return nil, err
+    }
+
+    byCurrency := make(map[string]*Balance)
+    balance := func(currency string) *Balance {
+        if b, ok := byCurrency[currency]; ok {
+            return b
+        }
+        zero := money.New(0, currency)
+        b := &Balance{Currency: currency, Pending: zero, Available: zero, PaidOut: zero}
+        byCurrency[currency] = b
+        return b
+    }
+    for _, sum := range sums {
+        // the seller is owed credits
+        if sum.Account == SellerPending(sellerID) {
+            balance(sum.Currency).Pending.Amount = -sum.Amount
+        } else {
+            balance(sum.Currency).Available.Amount = -sum.Amount
+        }
+    }
+    for _, p := range paid {
+        balance(p.Currency).PaidOut.Amount = p.Amount
+    }
+    list := make([]Balance, 0, len(byCurrency))
+    for _, b := range byCurrency {
+        list = append(list, *b)
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+    return list, nil
+}
+
+func (s *postgresService) Payouts(sellerID string) ([]Payout, error) {
+    list := []Payout{}
+    err := s.db.Where("seller_id = ?", sellerID).Order("created_at DESC, id").Find(&list).Error
+    return list, err
+}
+
+func (s *postgresService) RunPayouts(actor string) (*Batch, error) {
+    b := &Batch{ID: uuid.NewString(), CreatedBy: actor, Payouts: []Payout{}}
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        // one run at a time, so no balance is paid twice
+        if err := tx.Exec("LOCK TABLE payout_batches IN EXCLUSIVE MODE").Error; err != nil {
+            return err
+        }
+        var owed []struct {
+            Account  string
+            Currency string
+            Amount   int64
+        }
+        err := tx.Model(&Posting{}).
+            Select("account, amount_currency AS currency, sum(amount_amount) AS amount").
+            Where("account LIKE ?", SellerAvailable("%")).
+            Group("account, amount_currency").Having("sum(amount_amount) < 0").
+            Order("account, currency").Scan(&owed).Error
+        if err != nil {
+            return err
+        }
+        if err := tx.Omit("Payouts").Create(b).Error; err != nil {
+            return err
+        }
+        for _, o := range owed {
+            sellerID := strings.TrimSuffix(strings.TrimPrefix(o.Account, "seller:"), ":available")
+            p := Payout{ID: uuid.NewString(), BatchID: b.ID, SellerID: sellerID, Amount: money.New(-o.Amount, o.Currency)}
+            if err := tx.Create(&p).Error; err != nil {
+                return err
+            }
+            err := post(tx, &Entry{
+                Kind:     KindPayout,
+                Ref:      p.ID,
+                SellerID: sellerID,
+                Memo:     "batch " + b.ID,
+                Postings: []Posting{
+                    {Account: SellerAvailable(sellerID), Amount: p.Amount},
+                    {Account: AccountClearing, Amount: money.New(o.Amount, o.Currency)},
+                },
+            })
+            if err != nil {
+                return err
+            }
+            b.Payouts = append(b.Payouts, p)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return b, nil
+}
+
+func (s *postgresService) Batches() ([]Batch, error) {
+    list := []Batch{}
+    err := s.db.Order("created_at DESC, id").Find(&list).Error
+    return list, err
+}
+
+func (s *postgresService) Batch(id string) (*Batch, error) {
+    var b Batch
+    err := s.db.Preload("Payouts", func(db *gorm.DB) *gorm.DB {
+        return db.Order("seller_id, amount_currency")
+    }).First(&b, "id = ?", id).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrBatchNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    if b.Payouts == nil {
+        b.Payouts = []Payout{}
+    }
+    return &b, nil
+}
+
+func (s *postgresService) Entries(from, to time.Time, sellerID string) ([]Entry, error) {
+    q := s.db.Preload("Postings", func(db *gorm.DB) *gorm.DB {
+        return db.Order("account")
+    }).Where("created_at >= ? AND created_at < ?", from, to)
+    if sellerID != "" {
+        q = q.Where("seller_id = ?", sellerID)
+    }
+    list := []Entry{}
+    err := q.Order("created_at, id").Find(&list).Error
+    return list, err
+}
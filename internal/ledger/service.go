@@ -0,0 +1,47 @@
+package ledger
+
+import (
+    "time"
+
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
+// Service keeps the books. The Post methods take the order service's
+// transaction, so an order change and its entry commit together; each
+// is a no-op if already posted, and for orders whose payment was taken
+// before the ledger existed.
+type Service interface {
+    // PostSale books an order's captured payment: the card fee, the
+    // platform's commission, and what the seller is owed once they hand
+    // the order over.
+    PostSale(tx *gorm.DB, orderID, sellerID string, captured money.Money) error
+    // PostCompletion makes what the seller is owed for a handed-over
+    // order available for payout.
+    PostCompletion(tx *gorm.DB, orderID string) error
+    // PostRefund books a refund, taken from the seller's share and the
+    // platform's commission in proportion.
+    PostRefund(tx *gorm.DB, r *payment.Refund) error
+
+    // Balances is what a seller is owed, per currency.
+    Balances(sellerID string) ([]Balance, error)
+    // Payouts lists a seller's payouts, newest first.
+    Payouts(sellerID string) ([]Payout, error)
+
+    Commission(sellerID string) (*Commission, error)
+    // SetCommission overrides a seller's commission for orders accepted
+    // from now on; a nil rate goes back to the default.
+    SetCommission(sellerID string, rateBP *int) (*Commission, error)
+
+    // RunPayouts pays every seller their available balance, in a new batch.
+    RunPayouts(actor string) (*Batch, error)
+    // Batches lists payout batches, newest first, without their payouts.
+    Batches() ([]Batch, error)
+    Batch(id string) (*Batch, error)
+
+    // Entries lists the entries made in [from, to), optionally for one
+    // seller, oldest first.
+    Entries(from, to time.Time, sellerID string) ([]Entry, error)
+}
@@ -0,0 +1,69 @@
+package listing
+
+import (
+    "fmt"
+    "os"
+    "testing"
+
+    "github.com/google/uuid"
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/stdlib"
+    "gorm.io/driver/postgres"
+    "gorm.io/gorm"
+    "gorm.io/gorm/logger"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+// Tests that need Postgres run against TEST_DATABASE_URL, in a schema of
+// their own that is dropped afterwards, and are skipped without it.
+var testDB *gorm.DB
+
+func TestMain(m *testing.M) {
+    dsn := os.Getenv("TEST_DATABASE_URL")
+    if dsn == "" {
+        os.Exit(m.Run())
+    }
+    db, drop, err := openTestDB(dsn)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "listing tests:", err)
+        os.Exit(1)
+    }
+    testDB = db
+    code := m.Run()
+    drop()
+    os.Exit(code)
+}
+
+func openTestDB(dsn string) (*gorm.DB, func(), error) {
+    schema := "listing_test_" + uuid.NewString()[:8]
+    cfg, err := pgx.ParseConfig(dsn)
+    if err != nil {
+        return nil, nil, err
+    }
+    // public too, where pg_trgm may already be installed
+    cfg.RuntimeParams["search_path"] = schema + ",public"
+    db, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*cfg)}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+        return nil, nil, err
+    }
+    drop := func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") }
+    for _, migrate := range []func(*gorm.DB) error{seller.Migrate, Migrate} {
+        if err := migrate(db); err != nil {
+            drop()
+            return nil, nil, err
+        }
+    }
+    return db, drop, nil
+}
+
+func needDB(t *testing.T) *gorm.DB {
+    t.Helper()
+    if testDB == nil {
+        t.Skip("TEST_DATABASE_URL not set")
+    }
+    return testDB
+}
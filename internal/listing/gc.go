@@ -0,0 +1,142 @@
+package listing
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/image_store"
+)
+
+// imagePrefix is where gallery renditions are stored; pending direct
+// uploads live under uploads/ and are cleaned up by the upload janitor.
+const imagePrefix = "listings/"
+
+// GCOptions configure an orphaned image collection.
+type GCOptions struct {
+    // Grace keeps orphans younger than this, so an image stored moments
+    // before its gallery row is committed isn't collected.
+    Grace  time.Duration
+    DryRun bool // report orphans without deleting them
+
+    now func() time.Time // for tests
+}
+
+// GCOptionsFromEnv reads IMAGE_GC_GRACE (a Go duration, default 24h) and
+// IMAGE_GC_DRY_RUN.
+func GCOptionsFromEnv() GCOptions {
+    opts := GCOptions{Grace: 24 * time.Hour}
+    if d, err := time.ParseDuration(os.Getenv("IMAGE_GC_GRACE")); err == nil && d >= 0 {
+        opts.Grace = d
+    }
+    opts.DryRun, _ = strconv.ParseBool(os.Getenv("IMAGE_GC_DRY_RUN"))
+    return opts
+}
+
+// GCReport summarizes a collection.
+type GCReport struct {
+    DryRun     bool     `json:"dryRun"`
+    Scanned    int      `json:"scanned"`    // objects under listings/
+    Referenced int      `json:"referenced"` // still used by a listing
+    TooNew     int      `json:"tooNew"`     // orphaned but inside the grace period
+    Orphans    []string `json:"orphans"`    // collected (or, in a dry run, collectable) keys
+    Bytes      int64    `json:"bytes"`      // total size of Orphans
+    Failed     []string `json:"failed,omitempty"`
+}
+
+func (r GCReport) String() string {
+    verb := "deleted"
+    if r.DryRun {
+        verb = "would delete"
+    }
+    return fmt.Sprintf("scanned %d objects: %d referenced, %d orphans inside grace period, %s %d (%d bytes), %d failed",
+        r.Scanned, r.Referenced, r.TooNew, verb, len(r.Orphans)-len(r.Failed), r.Bytes, len(r.Failed))
+}
+
+// referenceSource is the part of Service the collector needs.
+type referenceSource interface {
+    ReferencedObjects() (map[string]bool, error)
+}
+
+// CollectOrphanedImages deletes stored images that no live listing refers
+// to: galleries of soft-deleted listings, renditions left behind by failed
+// deletes, and so on. Failed deletes are reported rather than aborting.
+func CollectOrphanedImages(ctx context.Context, refs referenceSource, store image_store.Store, opts GCOptions) (GCReport, error) {
+    now := time.Now
+    if opts.now != nil {
+        now = opts.now
+    }
+    report := GCReport{DryRun: opts.DryRun, Orphans: []string{}}
+
+    // list before loading references: an image added in between is then
+    // either referenced or too new, never collected
+    objs, err := store.List(ctx, imagePrefix)
+    if err != nil {
+        return report, fmt.Errorf("list images: %w", err)
+    }
+    used, err := refs.ReferencedObjects()
+    if err != nil {
+        return report, fmt.Errorf("load referenced images: %w", err)
+    }
+
+    cutoff := now().Add(-opts.Grace)
+    for _, obj := range objs {
+        report.Scanned++
+        switch {
+        case used[obj.Key]:
+            report.Referenced++
+        case obj.LastModified.After(cutoff):
+            report.TooNew++
+        default:
+            report.Orphans = append(report.Orphans, obj.Key)
+            if opts.DryRun {
+                report.Bytes += obj.Size
+                continue
+            }
+            if err := store.Delete(ctx, obj.Key); err != nil {
+                log.Printf("image gc: delete %s: %v", obj.Key, err)
+                report.Failed = append(report.Failed, obj.Key)
+                continue
+            }
+            report.Bytes += obj.Size
+        }
+    }
+    return report, nil
+}
+
+// ImageGCIntervalFromEnv reads IMAGE_GC_INTERVAL (a Go duration, default
+// 24h); "0" disables the periodic job.
+func ImageGCIntervalFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("IMAGE_GC_INTERVAL")); err == nil && d >= 0 {
+        return d
+    }
+    return 24 * time.Hour
+}
+
+// StartImageGC collects orphaned images every interval until ctx is
+// cancelled. A zero interval disables it.
+func StartImageGC(ctx context.Context, svc Service, store image_store.Store, interval time.Duration, opts GCOptions) {
+    if interval <= 0 {
+        return
+    }
+    go func() {
+        t := time.NewTicker(interval)
+        defer t.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+                report, err := CollectOrphanedImages(ctx, svc, store, opts)
+                if err != nil {
+                    log.Printf("image gc: %v", err)
+                    continue
+                }
+                log.Printf("image gc: %s", report)
+            }
+        }
+    }()
+}
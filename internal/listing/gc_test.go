@@ -0,0 +1,65 @@
+package listing
+
+import (
+    "context"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/image_store"
+)
+
+type staticRefs map[string]bool
+
+func (r staticRefs) ReferencedObjects() (map[string]bool, error) { return r, nil }
+
+func TestCollectOrphanedImages(t *testing.T) {
+    ctx := context.Background()
+    store := image_store.NewMemoryStore()
+    for _, key := range []string{
+        "listings/l1/a.jpg",
+        "listings/l1/a_thumb.jpg",
+        "listings/l2/old.jpg",
+        "uploads/l1/u1", // not ours to collect
+    } {
+        store.Put(ctx, key, strings.NewReader("data"), 4, "image/jpeg")
+    }
+    refs := staticRefs{"listings/l1/a.jpg": true, "listings/l1/a_thumb.jpg": true}
+
+    // everything was just written, so it's all inside the grace period
+    report, err := CollectOrphanedImages(ctx, refs, store, GCOptions{Grace: time.Hour})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Scanned != 3 || report.Referenced != 2 || report.TooNew != 1 || len(report.Orphans) != 0 {
+        t.Fatalf("fresh report = %+v", report)
+    }
+
+    later := func() time.Time { return time.Now().Add(2 * time.Hour) }
+    report, err = CollectOrphanedImages(ctx, refs, store, GCOptions{Grace: time.Hour, DryRun: true, now: later})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(report.Orphans) != 1 || report.Orphans[0] != "listings/l2/old.jpg" || report.Bytes != 4 {
+        t.Fatalf("dry-run report = %+v", report)
+    }
+    if _, ok := store.Bytes("listings/l2/old.jpg"); !ok {
+        t.Fatal("dry run deleted the orphan")
+    }
+
+    report, err = CollectOrphanedImages(ctx, refs, store, GCOptions{Grace: time.Hour, now: later})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(report.Orphans) != 1 || len(report.Failed) != 0 {
+        t.Fatalf("report = %+v", report)
+    }
+    if _, ok := store.Bytes("listings/l2/old.jpg"); ok {
+        t.Error("orphan was not deleted")
+    }
+    for _, key := range []string{"listings/l1/a.jpg", "listings/l1/a_thumb.jpg", "uploads/l1/u1"} {
+        if _, ok := store.Bytes(key); !ok {
+            t.Errorf("%s was deleted", key)
+        }
+    }
+}
@@ -1,27 +1,134 @@
 package listing
 
 import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "log"
     "net/http"
-    "os"
     "fmt"
+    "strconv"
+    "strings"
     "time"
     "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+    "gorm.io/gorm"
    	"github.com/albus-droid/Capstone-Project-Backend/internal/auth"
-    "github.com/minio/minio-go/v7"
+   	"github.com/albus-droid/Capstone-Project-Backend/internal/image_store"
+   	"github.com/albus-droid/Capstone-Project-Backend/internal/imaging"
+   	"github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+   	"github.com/albus-droid/Capstone-Project-Backend/internal/tax"
 )
 
+const (
+    defaultSearchLimit = 20
+    maxSearchLimit     = 100
+
+    defaultStockHistory = 50
+    maxStockHistory     = 500
+
+    // multipartOverhead allows for form boundaries and headers around an
+    // image of the maximum size
+    multipartOverhead = 64 << 10
+)
+
+// filterFromQuery builds a Filter from the listing query params. List params
+// accept repeated keys and/or comma-separated values.
+func filterFromQuery(c *gin.Context) (Filter, error) {
+    f := Filter{
+        SellerID:         c.Query("sellerId"),
+        Category:         strings.ToLower(c.Query("category")),
+        Cuisine:          queryList(c, "cuisine"),
+        Dietary:          queryList(c, "dietary"),
+        ExcludeAllergens: queryList(c, "excludeAllergens"),
+    }
+    if raw := c.Query("available"); raw != "" {
+        v, err := strconv.ParseBool(raw)
+        if err != nil {
+            return f, errors.New("invalid available flag")
+        }
+        f.Available = &v
+    }
+    switch f.Sort = c.Query("sort"); f.Sort {
+    case "", SortRating:
+    default:
+        return f, fmt.Errorf("sort must be %s", SortRating)
+    }
+    if err := checkKnown("dietary label", f.Dietary, DietaryLabels); err != nil {
+        return f, err
+    }
+    if err := checkKnown("allergen", f.ExcludeAllergens, Allergens); err != nil {
+        return f, err
+    }
+    return f, nil
+}
+
+// queryList collects ?key=a,b&key=c into normalized tags [a b c].
+func queryList(c *gin.Context, key string) []string {
+    var out []string
+    for _, v := range c.QueryArray(key) {
+        out = append(out, strings.Split(v, ",")...)
+    }
+    return normalizeTags(out)
+}
 
 // RegisterRoutes mounts listing endpoints under /listings
-func RegisterRoutes(r *gin.Engine, svc Service, minioClient *minio.Client) {
+func RegisterRoutes(r *gin.Engine, svc Service, store image_store.Store, sellers seller.Service) {
+    limits := imaging.LimitsFromEnv()
+
     // Public GET routes (no auth)
     public := r.Group("/listings")
     public.GET("", func(c *gin.Context) {
-        if sellerID := c.Query("sellerId"); sellerID != "" {
-            list, _ := svc.ListBySeller(sellerID)
-            c.JSON(http.StatusOK, list)
+        f, err := filterFromQuery(c)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
             return
         }
-        c.JSON(http.StatusOK, svc.ListAll())
+        list, err := svc.List(f)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list listings"})
+            return
+        }
+        c.JSON(http.StatusOK, list)
+    })
+
+    // GET /listings/metadata — the fixed vocabularies listings are tagged with
+    public.GET("/metadata", func(c *gin.Context) {
+        c.JSON(http.StatusOK, gin.H{
+            "categories":    Categories,
+            "taxCategories": tax.Categories,
+            "dietaryLabels": DietaryLabels,
+            "allergens":     Allergens,
+        })
+    })
+
+    // GET /listings/search?q=  — full-text search, combinable with sellerId and available
+    public.GET("/search", func(c *gin.Context) {
+        q := strings.TrimSpace(c.Query("q"))
+        if q == "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "missing query parameter q"})
+            return
+        }
+        f, err := filterFromQuery(c)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        limit := defaultSearchLimit
+        if raw := c.Query("limit"); raw != "" {
+            n, err := strconv.Atoi(raw)
+            if err != nil || n < 1 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+                return
+            }
+            limit = min(n, maxSearchLimit)
+        }
+        results, err := svc.Search(q, f, limit)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+            return
+        }
+        c.JSON(http.StatusOK, results)
     })
 
     public.GET("/:id", func(c *gin.Context) {
@@ -34,23 +141,27 @@ func RegisterRoutes(r *gin.Engine, svc Service, minioClient *minio.Client) {
         c.JSON(http.StatusOK, l)
     })
 
+    // GET /listings/:id/images — the gallery in display order
+    public.GET("/:id/images", func(c *gin.Context) {
+        imgs, err := svc.ListImages(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not list images"})
+            return
+        }
+        c.JSON(http.StatusOK, imgs)
+    })
+
     public.GET("/:id/image/:filename", func(c *gin.Context) {
         listingID := c.Param("id")
         filename := c.Param("filename")
-        bucket := os.Getenv("MINIO_BUCKET")
-        if bucket == "" {
-            bucket = "listing-images"
-        }
         objectName := fmt.Sprintf("listings/%s/%s", listingID, filename)
 
-        signedURL, err := minioClient.PresignedGetObject(
-            c, bucket, objectName, time.Hour, nil,
-        )
+        signedURL, err := store.SignedURL(c, objectName, time.Hour)
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate signed URL"})
             return
         }
-        c.JSON(http.StatusOK, gin.H{"signed_url": signedURL.String()})
+        c.JSON(http.StatusOK, gin.H{"signed_url": signedURL})
     })
 
     protected := r.Group("/listings")
@@ -64,86 +175,475 @@ func RegisterRoutes(r *gin.Engine, svc Service, minioClient *minio.Client) {
             return
         }
         if err := svc.Create(&l); err != nil {
+            if errors.Is(err, ErrInvalidMetadata) || errors.Is(err, ErrInvalidSchedule) || errors.Is(err, ErrInvalidPrice) {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+            }
             c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
             return
         }
         c.JSON(http.StatusCreated, gin.H{"message": "listing created", "id": l.ID,})
     })
 
-    // PUT /listings/:id  — update an existing listing
-    protected.PUT("/:id", func(c *gin.Context) {
+    // POST /listings/:id/restock-subscriptions — notify me when a sold-out listing is back
+    protected.POST("/:id/restock-subscriptions", func(c *gin.Context) {
+        email := c.GetString(string(auth.CtxEmailKey))
+        if err := svc.Subscribe(c.Param("id"), email); err != nil {
+            switch {
+            case errors.Is(err, ErrNotSoldOut):
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            case errors.Is(err, gorm.ErrRecordNotFound):
+                c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+            default:
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            }
+            return
+        }
+        c.JSON(http.StatusCreated, gin.H{"message": "you will be notified when this listing is restocked"})
+    })
+
+    // DELETE /listings/:id/restock-subscriptions
+    protected.DELETE("/:id/restock-subscriptions", func(c *gin.Context) {
+        email := c.GetString(string(auth.CtxEmailKey))
+        if err := svc.Unsubscribe(c.Param("id"), email); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    // DELETE /listings/:id  — remove a listing
+    protected.DELETE("/:id", func(c *gin.Context) {
         id := c.Param("id")
+        if err := svc.Delete(id); err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "not found or unable to delete"})
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+
+    // image gallery management, restricted to the listing's seller
+    owner := protected.Group("", seller.RequireSeller(sellers))
+
+    // PUT /listings/:id  — update an existing listing
+    owner.PUT("/:id", func(c *gin.Context) {
+        cur, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
         var l Listing
         if err := c.ShouldBindJSON(&l); err != nil {
             c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
             return
         }
-        if err := svc.Update(id, l); err != nil {
+        if l.LeftSize < 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "leftSize cannot be negative"})
+            return
+        }
+        if err := svc.Update(cur.ID, l); err != nil {
+            if errors.Is(err, ErrInvalidMetadata) || errors.Is(err, ErrInvalidSchedule) || errors.Is(err, ErrInvalidPrice) {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+            }
             // you can customize error handling based on your svc.Update error
             c.JSON(http.StatusNotFound, gin.H{"error": "not found or unable to update"})
             return
         }
+        // a leftSize in the body sets the stock, through the ledger, so a
+        // sold-out listing coming back notifies restock subscribers
+        if l.LeftSize != 0 {
+            change := StockChange{Op: OpSet, Quantity: l.LeftSize, Reason: "listing update"}
+            if _, err := svc.AdjustStock(cur.ID, c.GetString(string(auth.CtxEmailKey)), change); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+            }
+        }
         c.JSON(http.StatusOK, gin.H{"message": "listing updated"})
     })
 
-    // DELETE /listings/:id  — remove a listing
-    protected.DELETE("/:id", func(c *gin.Context) {
-        id := c.Param("id")
-        if err := svc.Delete(id); err != nil {
-            c.JSON(http.StatusNotFound, gin.H{"error": "not found or unable to delete"})
+    // PATCH /listings/:id — JSON merge patch (RFC 7386); null clears a field
+    owner.PATCH("/:id", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
             return
         }
-        c.Status(http.StatusNoContent)
+        if ct := c.ContentType(); ct != "application/merge-patch+json" && ct != "application/json" {
+            c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "use Content-Type application/merge-patch+json"})
+            return
+        }
+        var patch map[string]json.RawMessage
+        if err := c.ShouldBindJSON(&patch); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "patch must be a JSON object"})
+            return
+        }
+        updated, err := svc.Patch(l.ID, patch)
+        if err != nil {
+            if errors.Is(err, ErrInvalidPatch) || errors.Is(err, ErrInvalidMetadata) || errors.Is(err, ErrInvalidSchedule) || errors.Is(err, ErrInvalidPrice) {
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+                return
+            }
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, updated)
     })
 
+    // POST /listings/:id/stock — body {"op": "set"|"increment"|"decrement", "quantity", "reason"}
+    owner.POST("/:id/stock", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        var change StockChange
+        if err := c.ShouldBindJSON(&change); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        res, err := svc.AdjustStock(l.ID, c.GetString(string(auth.CtxEmailKey)), change)
+        if err != nil {
+            switch {
+            case errors.Is(err, ErrInvalidStockChange):
+                c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            case errors.Is(err, ErrInsufficientStock):
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+            default:
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            }
+            return
+        }
+        c.JSON(http.StatusOK, res)
+    })
 
-    // POST /listings/:id/image — Uploads image to MinIO and saves URL to Listing in DB
-    protected.POST("/:id/image", func(c *gin.Context) {
-        listingID := c.Param("id")
-        file, header, err := c.Request.FormFile("file")
+    // GET /listings/:id/stock — the stock ledger, newest first
+    owner.GET("/:id/stock", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        limit := defaultStockHistory
+        if raw := c.Query("limit"); raw != "" {
+            n, err := strconv.Atoi(raw)
+            if err != nil || n < 1 || n > maxStockHistory {
+                c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxStockHistory)})
+                return
+            }
+            limit = n
+        }
+        history, err := svc.StockHistory(l.ID, limit)
         if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"leftSize": l.LeftSize, "soldOut": l.SoldOut, "adjustments": history})
+    })
+
+    // POST /listings/:id/images — Uploads image to the store and appends it to the gallery
+    upload := func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes+multipartOverhead)
+        file, _, err := c.Request.FormFile("file")
+        if err != nil {
+            var tooBig *http.MaxBytesError
+            if errors.As(err, &tooBig) {
+                c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": imaging.ErrTooLarge.Error()})
+                return
+            }
             c.JSON(http.StatusBadRequest, gin.H{"error": "no file uploaded"})
             return
         }
         defer file.Close()
 
-        bucket := os.Getenv("MINIO_BUCKET")
-        if bucket == "" {
-            bucket = "listing-images"
+        // the client's filename and Content-Type are ignored: the content is
+        // sniffed, re-encoded without metadata and named by its hash
+        res, err := imaging.Process(file, limits)
+        if err != nil {
+            uploadError(c, err)
+            return
         }
-        objectName := fmt.Sprintf("listings/%s/%s", listingID, header.Filename)
-        contentType := header.Header.Get("Content-Type")
 
-        _, err = minioClient.PutObject(
-            c, bucket, objectName, file, header.Size,
-            minio.PutObjectOptions{ContentType: contentType},
-        )
-        if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "minio upload failed"})
+        img, ok := attachImage(c, svc, store, l.ID, res)
+        if !ok {
+            return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+            "image_url": img.URL,
+            "image":     img,
+        })
+    }
+    owner.POST("/:id/images", upload)
+    owner.POST("/:id/image", upload) // kept for older clients
+
+    // POST /listings/:id/images/upload-url — body {"contentType", "size"};
+    // returns a presigned PUT URL so the file goes straight to the store
+    owner.POST("/:id/images/upload-url", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        var body struct {
+            ContentType string `json:"contentType" binding:"required"`
+            Size        int64  `json:"size" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if !imaging.Allowed(body.ContentType) {
+            c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": imaging.ErrUnsupportedType.Error()})
+            return
+        }
+        if body.Size <= 0 || body.Size > limits.MaxBytes {
+            c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": imaging.ErrTooLarge.Error(), "maxSize": limits.MaxBytes})
             return
         }
 
-        imageAPIUrl := fmt.Sprintf("/listings/%s/image/%s", listingID, header.Filename)
+        up := &ImageUpload{
+            ID:          uuid.NewString(),
+            ListingID:   l.ID,
+            ContentType: body.ContentType,
+            Size:        body.Size,
+            ExpiresAt:   time.Now().Add(uploadConfirmWindow),
+        }
+        up.ObjectKey = fmt.Sprintf("uploads/%s/%s", l.ID, up.ID)
+        url, err := store.SignedPutURL(c, up.ObjectKey, uploadURLTTL, up.ContentType, up.Size)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create upload URL"})
+            return
+        }
+        if err := svc.CreateUpload(up); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create upload"})
+            return
+        }
+        c.JSON(http.StatusCreated, gin.H{
+            "uploadId": up.ID,
+            "url":      url,
+            "method":   http.MethodPut,
+            "headers": gin.H{
+                "Content-Type":   up.ContentType,
+                "Content-Length": strconv.FormatInt(up.Size, 10),
+            },
+            "urlExpiresAt": time.Now().Add(uploadURLTTL),
+            "expiresAt":    up.ExpiresAt,
+            "maxSize":      limits.MaxBytes,
+        })
+    })
 
-        // --- Update listing in DB ---
-        listing, err := svc.GetByID(listingID)
+    // POST /listings/:id/images/confirm — body {"uploadId"}; validates the
+    // uploaded object and adds it to the gallery
+    owner.POST("/:id/images/confirm", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        var body struct {
+            UploadID string `json:"uploadId" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        up, err := svc.GetUpload(l.ID, body.UploadID)
         if err != nil {
-            c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+            if errors.Is(err, ErrUploadNotFound) {
+                c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                return
+            }
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        if time.Now().After(up.ExpiresAt) {
+            discardUpload(c, svc, store, up)
+            c.JSON(http.StatusGone, gin.H{"error": "upload expired"})
             return
         }
 
-        // For single image per listing:
-        listing.Image = imageAPIUrl
+        info, err := store.Stat(c, up.ObjectKey)
+        if errors.Is(err, image_store.ErrNotFound) {
+            c.JSON(http.StatusConflict, gin.H{"error": "nothing has been uploaded yet"})
+            return
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not check upload"})
+            return
+        }
+        if info.Size != up.Size {
+            discardUpload(c, svc, store, up)
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "uploaded size does not match the requested size"})
+            return
+        }
 
-        // Save updated listing
-        err = svc.Update(listingID, *listing)
+        rc, err := store.Get(c, up.ObjectKey)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not read upload"})
+            return
+        }
+        res, err := imaging.Process(rc, limits)
+        rc.Close()
         if err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update listing with image"})
+            // the content won't get any better, so don't keep it around
+            discardUpload(c, svc, store, up)
+            uploadError(c, err)
             return
         }
+        img, ok := attachImage(c, svc, store, l.ID, res)
+        if !ok {
+            return
+        }
+        discardUpload(c, svc, store, up)
 
         c.JSON(http.StatusOK, gin.H{
-            "image_url": imageAPIUrl,
+            "image_url": img.URL,
+            "image":     img,
         })
     })
-}
\ No newline at end of file
+
+    // PUT /listings/:id/images/order — body {"imageIds": [...]} lists every image in display order
+    owner.PUT("/:id/images/order", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        var body struct {
+            ImageIDs []string `json:"imageIds" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := svc.ReorderImages(l.ID, body.ImageIDs); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        imgs, _ := svc.ListImages(l.ID)
+        c.JSON(http.StatusOK, imgs)
+    })
+
+    // PUT /listings/:id/images/:imageId/primary
+    owner.PUT("/:id/images/:imageId/primary", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        if err := svc.SetPrimaryImage(l.ID, c.Param("imageId")); err != nil {
+            imageError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "primary image updated"})
+    })
+
+    // DELETE /listings/:id/images/:imageId — removes the image and its stored objects
+    owner.DELETE("/:id/images/:imageId", func(c *gin.Context) {
+        l, ok := ownedListing(c, svc)
+        if !ok {
+            return
+        }
+        img, err := svc.DeleteImage(l.ID, c.Param("imageId"))
+        if err != nil {
+            imageError(c, err)
+            return
+        }
+        for _, o := range img.Objects() {
+            removeObject(c, store, o)
+        }
+        c.Status(http.StatusNoContent)
+    })
+}
+
+// attachImage stores each rendition of a processed upload under
+// listings/<id>/ and adds the image to the gallery, writing the error
+// response and removing anything already stored if that fails.
+func attachImage(c *gin.Context, svc Service, store image_store.Store, listingID string, res *imaging.Result) (*ListingImage, bool) {
+    img := &ListingImage{Width: res.Full.Width, Height: res.Full.Height}
+    var stored []string
+    renditions := []struct {
+        r           imaging.Rendition
+        object, url *string
+    }{
+        {res.Full, &img.ObjectName, &img.URL},
+        {res.Medium, &img.MediumObject, &img.MediumURL},
+        {res.Thumb, &img.ThumbObject, &img.ThumbURL},
+    }
+    for _, rd := range renditions {
+        filename := rd.r.Filename(res.Hash)
+        objectName := fmt.Sprintf("listings/%s/%s", listingID, filename)
+        err := store.Put(c, objectName, bytes.NewReader(rd.r.Data), int64(len(rd.r.Data)), rd.r.ContentType)
+        if err != nil {
+            for _, o := range stored {
+                removeObject(c, store, o)
+            }
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "image upload failed"})
+            return nil, false
+        }
+        stored = append(stored, objectName)
+        *rd.object = objectName
+        *rd.url = fmt.Sprintf("/listings/%s/image/%s", listingID, filename)
+    }
+
+    if err := svc.AddImage(listingID, img); err != nil {
+        for _, o := range stored {
+            removeObject(c, store, o)
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add image to listing"})
+        return nil, false
+    }
+    return img, true
+}
+
+// discardUpload removes a pending upload's raw object and its record.
+func discardUpload(c *gin.Context, svc Service, store image_store.Store, up *ImageUpload) {
+    removeObject(c, store, up.ObjectKey)
+    if err := svc.DeleteUpload(up.ID); err != nil {
+        log.Printf("delete upload %s: %v", up.ID, err)
+    }
+}
+
+// uploadError maps image validation errors to responses.
+func uploadError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, imaging.ErrTooLarge):
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+    case errors.Is(err, imaging.ErrUnsupportedType):
+        c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+    case errors.Is(err, imaging.ErrDimensions), errors.Is(err, imaging.ErrCorrupt):
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "could not read upload"})
+    }
+}
+
+// removeObject deletes an image object, logging rather than failing: a
+// leftover object is only wasted space.
+func removeObject(c *gin.Context, store image_store.Store, objectName string) {
+    if err := store.Delete(c, objectName); err != nil {
+        log.Printf("remove image object %s: %v", objectName, err)
+    }
+}
+
+// ownedListing loads the :id listing and checks it belongs to the seller set
+// by seller.RequireSeller, writing the error response if not.
+func ownedListing(c *gin.Context, svc Service) (*Listing, bool) {
+    l, err := svc.GetByID(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+        return nil, false
+    }
+    if sl := seller.FromContext(c); sl == nil || sl.ID != l.SellerID {
+        c.JSON(http.StatusForbidden, gin.H{"error": "not your listing"})
+        return nil, false
+    }
+    return l, true
+}
+
+// imageError maps gallery errors to responses.
+func imageError(c *gin.Context, err error) {
+    if errors.Is(err, ErrImageNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,84 @@
+package listing
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/golang-jwt/jwt/v4"
+    "github.com/google/uuid"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+func TestPutRestockNotifiesSubscribers(t *testing.T) {
+    db := needDB(t)
+    gin.SetMode(gin.TestMode)
+    svc := NewPostgresService(db)
+    r := gin.New()
+    RegisterRoutes(r, svc, nil, seller.NewPostgresService(db))
+
+    id := uuid.NewString()
+    sl := seller.Seller{ID: id, Name: "Test Kitchen", Email: id + "@example.com", Password: "x", Phone: "555-0100"}
+    if err := db.Create(&sl).Error; err != nil {
+        t.Fatal(err)
+    }
+    l := Listing{SellerID: id, Title: "Dumplings", Price: money.New(1200, "CAD"), Available: true, PortionSize: 1, LeftSize: 1}
+    if err := svc.Create(&l); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := svc.AdjustStock(l.ID, sl.Email, StockChange{Op: OpSet, Quantity: 0}); err != nil {
+        t.Fatal(err)
+    }
+    if err := svc.Subscribe(l.ID, "buyer@example.com"); err != nil {
+        t.Fatal(err)
+    }
+
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": sl.Email}).SignedString(auth.Secret())
+    if err != nil {
+        t.Fatal(err)
+    }
+    req := httptest.NewRequest(http.MethodPut, "/listings/"+l.ID, strings.NewReader(`{"title":"Pork dumplings","leftSize":6}`))
+    req.Header.Set("Authorization", "Bearer "+token)
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusOK {
+        t.Fatalf("PUT: %d %s", w.Code, w.Body)
+    }
+
+    got, err := svc.GetByID(l.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.LeftSize != 6 || got.SoldOut {
+        t.Errorf("after PUT: leftSize %d, soldOut %v; want 6, false", got.LeftSize, got.SoldOut)
+    }
+    history, err := svc.StockHistory(l.ID, 1)
+    if err != nil || len(history) == 0 || history[0].Op != OpSet || history[0].After != 6 {
+        t.Errorf("stock ledger: %+v, %v; want a set to 6", history, err)
+    }
+
+    timeout := time.After(5 * time.Second)
+    for {
+        select {
+        case e := <-event.Bus:
+            n, ok := e.Data.(RestockNotice)
+            if e.Type != "ListingRestocked" || !ok || n.Listing.ID != l.ID {
+                continue
+            }
+            if len(n.Subscribers) != 1 || n.Subscribers[0] != "buyer@example.com" {
+                t.Errorf("notified %v, want [buyer@example.com]", n.Subscribers)
+            }
+            return
+        case <-timeout:
+            t.Fatal("no ListingRestocked event")
+        }
+    }
+}
@@ -2,7 +2,56 @@ package listing
 
 import "gorm.io/gorm"
 
+// searchDDL adds the full-text search column and indexes. search_vector is a
+// generated column, so Postgres keeps it in sync on every insert and update.
+var searchDDL = []string{
+    `CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+    `ALTER TABLE listings ADD COLUMN IF NOT EXISTS search_vector tsvector
+        GENERATED ALWAYS AS (
+            setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) STORED`,
+    `CREATE INDEX IF NOT EXISTS idx_listings_search_vector ON listings USING GIN (search_vector)`,
+    `CREATE INDEX IF NOT EXISTS idx_listings_title_trgm ON listings USING GIN (title gin_trgm_ops)`,
+}
+
+// metadataDDL indexes dietary labels for the @> containment filter.
+var metadataDDL = []string{
+    `CREATE INDEX IF NOT EXISTS idx_listings_dietary ON listings USING GIN (dietary)`,
+}
+
+// galleryDDL moves images uploaded before galleries existed into listing_images.
+var galleryDDL = []string{
+    `INSERT INTO listing_images (id, listing_id, object_name, url, position, is_primary, created_at)
+        SELECT gen_random_uuid(), l.id, 'listings/' || l.id || '/' || regexp_replace(l.image, '^.*/', ''), l.image, 0, true, now()
+        FROM listings l
+        WHERE coalesce(l.image, '') <> ''
+          AND NOT EXISTS (SELECT 1 FROM listing_images i WHERE i.listing_id = l.id)`,
+}
+
+// priceDDL moves prices from the old float dollars column into integer
+// cents; prices were always USD before currencies existed.
+var priceDDL = []string{
+    `DO $$ BEGIN
+        IF EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'listings' AND column_name = 'price') THEN
+            UPDATE listings SET price_amount = round(price::numeric * 100)::bigint, price_currency = 'USD';
+            ALTER TABLE listings DROP COLUMN price;
+        END IF;
+    END $$`,
+}
+
 // Migrate creates/updates the listings table to match the model
 func Migrate(db *gorm.DB) error {
-    return db.AutoMigrate(&Listing{})
+    if err := db.AutoMigrate(&Listing{}, &ListingImage{}, &ImageUpload{}, &RestockSubscription{}, &StockAdjustment{}); err != nil {
+        return err
+    }
+    for _, ddl := range [][]string{searchDDL, metadataDDL, galleryDDL, priceDDL} {
+        for _, stmt := range ddl {
+            if err := db.Exec(stmt).Error; err != nil {
+                return err
+            }
+        }
+    }
+    return nil
 }
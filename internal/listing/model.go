@@ -1,9 +1,17 @@
 package listing
 
 import (
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
     "time"
 
+    "gorm.io/datatypes"
     "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
 )
 
 // Listing is the GORM model for an item listing
@@ -12,12 +20,223 @@ type Listing struct {
     SellerID    string         `json:"sellerId" gorm:"type:uuid;not null;index"`
     Title       string         `json:"title" gorm:"type:varchar(200);not null"`
     Description string         `json:"description" gorm:"type:text"`
-    Price       float64        `json:"price" gorm:"not null"`
+    Price       money.Money    `json:"price" gorm:"embedded;embeddedPrefix:price_"` // price_amount (minor units), price_currency
     Available   bool           `json:"available" gorm:"default:true;not null"`
     CreatedAt   time.Time      `json:"createdAt" gorm:"autoCreateTime"`
     PortionSize int            `json:"portionSize" gorm:"not null"`    // size of each portion
     LeftSize    int            `json:"leftSize" gorm:"not null;default:0"` // portions left
+    SoldOut     bool           `json:"soldOut" gorm:"not null;default:false"` // set when accepted orders use up LeftSize, cleared on restock
     UpdatedAt   time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
     DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"` // soft‑delete
-    Image       string         `json:"image,omitempty"` // for multiple images per listing
+    Image       string         `json:"image,omitempty"` // URL of the primary image, kept in sync with Images
+    Images      []ListingImage `json:"images,omitempty" gorm:"foreignKey:ListingID"` // gallery, ordered by Position
+
+    // structured metadata, see Categories, DietaryLabels and Allergens
+    Category          string                      `json:"category,omitempty" gorm:"type:varchar(30);index"`
+    CuisineTags       datatypes.JSONSlice[string] `json:"cuisineTags" gorm:"type:jsonb;not null;default:'[]'"`
+    Dietary           datatypes.JSONSlice[string] `json:"dietary" gorm:"type:jsonb;not null;default:'[]'"`
+    Allergens         datatypes.JSONSlice[string] `json:"allergens" gorm:"type:jsonb;not null;default:'[]'"`
+    AllergensDeclared bool                        `json:"allergensDeclared" gorm:"not null;default:false"` // seller explicitly declared what the dish contains
+    TaxCategory       string                      `json:"taxCategory,omitempty" gorm:"type:varchar(20);not null;default:''"` // see tax.Categories; prepared food if empty
+
+    // publication window, see schedule.go; PublishedAt/ExpiredAt record
+    // when the scheduler acted on it
+    PublishAt   *time.Time `json:"publishAt,omitempty" gorm:"index"`
+    ExpiresAt   *time.Time `json:"expiresAt,omitempty" gorm:"index"`
+    PublishedAt *time.Time `json:"publishedAt,omitempty"`
+    ExpiredAt   *time.Time `json:"expiredAt,omitempty"`
+
+    // over the listing's visible reviews, kept up to date by the review package
+    RatingAvg   float64 `json:"ratingAvg" gorm:"type:numeric(3,2);not null;default:0"`
+    RatingCount int     `json:"ratingCount" gorm:"not null;default:0"`
+}
+
+// ListingImage is one image in a listing's gallery.
+type ListingImage struct {
+    ID           string    `json:"id" gorm:"type:uuid;primaryKey"`
+    ListingID    string    `json:"listingId" gorm:"type:uuid;not null;index"`
+    ObjectName   string    `json:"-" gorm:"not null"` // object key in the image bucket
+    URL          string    `json:"url" gorm:"not null"`
+    MediumObject string    `json:"-"`
+    MediumURL    string    `json:"mediumUrl,omitempty"`
+    ThumbObject  string    `json:"-"`
+    ThumbURL     string    `json:"thumbUrl,omitempty"`
+    Width        int       `json:"width,omitempty"`
+    Height       int       `json:"height,omitempty"`
+    Position     int       `json:"position" gorm:"not null;default:0"`
+    IsPrimary    bool      `json:"primary" gorm:"not null;default:false"`
+    CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// Objects lists the stored objects of every rendition of the image.
+func (img ListingImage) Objects() []string {
+    var out []string
+    for _, o := range []string{img.ObjectName, img.MediumObject, img.ThumbObject} {
+        if o != "" {
+            out = append(out, o)
+        }
+    }
+    return out
+}
+
+// ErrImageNotFound is returned when an image doesn't belong to the listing.
+var ErrImageNotFound = errors.New("image not found")
+
+// ImageUpload is a presigned direct upload waiting to be confirmed. The raw
+// object lives at ObjectKey until confirm processes it into gallery
+// renditions; unconfirmed uploads are removed after ExpiresAt.
+type ImageUpload struct {
+    ID          string    `json:"uploadId" gorm:"type:uuid;primaryKey"`
+    ListingID   string    `json:"listingId" gorm:"type:uuid;not null;index"`
+    ObjectKey   string    `json:"-" gorm:"not null"`
+    ContentType string    `json:"contentType" gorm:"not null"`
+    Size        int64     `json:"size" gorm:"not null"`
+    ExpiresAt   time.Time `json:"expiresAt" gorm:"not null;index"`
+    CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// RestockSubscription asks for a notification when a sold-out listing is
+// restocked. Subscriptions are removed once notified.
+type RestockSubscription struct {
+    ListingID string    `json:"listingId" gorm:"type:uuid;primaryKey"`
+    UserEmail string    `json:"userEmail" gorm:"type:varchar(100);primaryKey"`
+    CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// RestockNotice is the data of a ListingRestocked event.
+type RestockNotice struct {
+    Listing     Listing
+    Subscribers []string // emails to notify
+}
+
+// ErrNotSoldOut is returned when subscribing to a listing that isn't sold out.
+var ErrNotSoldOut = errors.New("listing is not sold out")
+
+// ErrUploadNotFound is returned when an upload doesn't belong to the listing.
+var ErrUploadNotFound = errors.New("upload not found")
+
+// Categories are the dish categories a listing can be filed under.
+var Categories = []string{
+    "main", "side", "appetizer", "soup", "salad", "dessert",
+    "baked_goods", "breakfast", "snack", "beverage",
+}
+
+// DietaryLabels are the dietary claims a seller can make about a dish.
+var DietaryLabels = []string{
+    "vegan", "vegetarian", "pescatarian", "halal", "kosher",
+    "gluten_free", "dairy_free", "nut_free",
+}
+
+// Allergens is the fixed allergen list sellers declare against.
+var Allergens = []string{
+    "milk", "eggs", "fish", "shellfish", "tree_nuts",
+    "peanuts", "wheat", "soy", "sesame", "mustard",
+}
+
+// ErrInvalidPrice is returned for negative prices or unknown currencies.
+var ErrInvalidPrice = errors.New("invalid price")
+
+// normalizePrice defaults the currency and rejects negative amounts.
+func (l *Listing) normalizePrice() error {
+    if l.Price.Currency == "" {
+        l.Price.Currency = money.DefaultCurrency
+    }
+    switch {
+    case !money.ValidCurrency(l.Price.Currency):
+        return fmt.Errorf("%w: unknown currency %q", ErrInvalidPrice, l.Price.Currency)
+    case l.Price.IsNegative():
+        return fmt.Errorf("%w: price cannot be negative", ErrInvalidPrice)
+    }
+    return nil
+}
+
+// ErrInvalidMetadata wraps validation failures of listing metadata.
+var ErrInvalidMetadata = errors.New("invalid listing metadata")
+
+// maxCuisineTags bounds free-form cuisine tags per listing.
+const maxCuisineTags = 10
+
+// normalizeTags lowercases, trims and de-duplicates tags, keeping their order.
+func normalizeTags(tags []string) []string {
+    out := make([]string, 0, len(tags))
+    for _, t := range tags {
+        t = strings.ToLower(strings.TrimSpace(t))
+        if t != "" && !slices.Contains(out, t) {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
+// checkKnown returns an error naming the first value not in known.
+func checkKnown(kind string, values, known []string) error {
+    for _, v := range values {
+        if !slices.Contains(known, v) {
+            return fmt.Errorf("%w: unknown %s %q", ErrInvalidMetadata, kind, v)
+        }
+    }
+    return nil
+}
+
+// normalizeMetadata cleans up and validates category, tax category,
+// cuisine tags, dietary labels and allergens. Listing any allergen counts as declaring.
+func (l *Listing) normalizeMetadata() error {
+    l.Category = strings.ToLower(strings.TrimSpace(l.Category))
+    if l.Category != "" && !slices.Contains(Categories, l.Category) {
+        return fmt.Errorf("%w: unknown category %q", ErrInvalidMetadata, l.Category)
+    }
+    l.TaxCategory = strings.ToLower(strings.TrimSpace(l.TaxCategory))
+    if l.TaxCategory != "" && !slices.Contains(tax.Categories, l.TaxCategory) {
+        return fmt.Errorf("%w: unknown tax category %q", ErrInvalidMetadata, l.TaxCategory)
+    }
+    if l.CuisineTags != nil {
+        l.CuisineTags = normalizeTags(l.CuisineTags)
+        if len(l.CuisineTags) > maxCuisineTags {
+            return fmt.Errorf("%w: too many cuisine tags", ErrInvalidMetadata)
+        }
+    }
+    if l.Dietary != nil {
+        l.Dietary = normalizeTags(l.Dietary)
+        if err := checkKnown("dietary label", l.Dietary, DietaryLabels); err != nil {
+            return err
+        }
+    }
+    if l.Allergens != nil {
+        l.Allergens = normalizeTags(l.Allergens)
+        if err := checkKnown("allergen", l.Allergens, Allergens); err != nil {
+            return err
+        }
+        if len(l.Allergens) > 0 {
+            l.AllergensDeclared = true
+        }
+    }
+    return nil
+}
+
+// Filter narrows listing queries. Zero values mean "no constraint".
+type Filter struct {
+    SellerID  string
+    Available *bool
+    Category  string
+    Cuisine   []string // any of these cuisine tags
+    Dietary   []string // all of these dietary labels
+
+    // ExcludeAllergens drops listings containing any of these allergens, as
+    // well as listings whose seller never declared allergens at all.
+    ExcludeAllergens []string
+
+    Sort string // SortRating, or empty for the usual order
+}
+
+// SortRating lists the best rated listings first, with more reviews
+// breaking ties, ahead of the usual order.
+const SortRating = "rating"
+
+// SearchResult is a listing matched by a full-text query, with its rank
+// and highlighted fragments of the title and description.
+type SearchResult struct {
+    Listing
+    Rank      float64 `json:"rank"`
+    Highlight string  `json:"highlight"` // title with matches wrapped in <mark>
+    Snippet   string  `json:"snippet"`   // description fragments with matches wrapped in <mark>
 }
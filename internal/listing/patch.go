@@ -0,0 +1,168 @@
+package listing
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+
+    "gorm.io/datatypes"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// ErrInvalidPatch is returned for merge patches that touch unknown or
+// read-only fields, or leave the listing invalid.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// patchColumns maps the JSON fields a merge patch may change to their columns.
+var patchColumns = map[string]string{
+    "title":             "title",
+    "description":       "description",
+    "price":             "price_amount", // and price_currency, see Patch
+    "available":         "available",
+    "portionSize":       "portion_size",
+    "category":          "category",
+    "taxCategory":       "tax_category",
+    "cuisineTags":       "cuisine_tags",
+    "dietary":           "dietary",
+    "allergens":         "allergens",
+    "allergensDeclared": "allergens_declared",
+    "publishAt":         "publish_at",
+    "expiresAt":         "expires_at",
+}
+
+// patchRequired are the patchable fields that can't be removed with null.
+var patchRequired = map[string]bool{"title": true, "price": true, "available": true, "portionSize": true}
+
+// patchReadOnly explains why a known field can't be patched.
+var patchReadOnly = map[string]string{
+    "leftSize":    "use POST /listings/{id}/stock",
+    "soldOut":     "it follows leftSize",
+    "sellerId":    "listings can't change seller",
+    "id":          "it is read-only",
+    "createdAt":   "it is read-only",
+    "updatedAt":   "it is read-only",
+    "publishedAt": "it is set by the scheduler",
+    "expiredAt":   "it is set by the scheduler",
+    "image":       "use the gallery endpoints",
+    "images":      "use the gallery endpoints",
+    "ratingAvg":   "it follows reviews",
+    "ratingCount": "it follows reviews",
+}
+
+// checkPatch rejects fields a merge patch may not touch.
+func checkPatch(patch map[string]json.RawMessage) error {
+    if len(patch) == 0 {
+        return fmt.Errorf("%w: empty patch", ErrInvalidPatch)
+    }
+    for k, v := range patch {
+        if why, ok := patchReadOnly[k]; ok {
+            return fmt.Errorf("%w: %s cannot be patched; %s", ErrInvalidPatch, k, why)
+        }
+        if _, ok := patchColumns[k]; !ok {
+            return fmt.Errorf("%w: unknown field %q", ErrInvalidPatch, k)
+        }
+        if patchRequired[k] && isNull(v) {
+            return fmt.Errorf("%w: %s cannot be null", ErrInvalidPatch, k)
+        }
+    }
+    return nil
+}
+
+func isNull(v json.RawMessage) bool {
+    return bytes.Equal(bytes.TrimSpace(v), []byte("null"))
+}
+
+// mergePatch applies an RFC 7386 merge patch to l. Listing fields are all
+// scalars or arrays, so a top-level merge is the whole algorithm: present
+// keys replace, null removes (resets to the zero value).
+func mergePatch(l *Listing, patch map[string]json.RawMessage) (*Listing, error) {
+    raw, err := json.Marshal(l)
+    if err != nil {
+        return nil, err
+    }
+    var doc map[string]json.RawMessage
+    if err := json.Unmarshal(raw, &doc); err != nil {
+        return nil, err
+    }
+    for k, v := range patch {
+        if isNull(v) {
+            delete(doc, k)
+        } else {
+            doc[k] = v
+        }
+    }
+    if raw, err = json.Marshal(doc); err != nil {
+        return nil, err
+    }
+    var next Listing
+    if err := json.Unmarshal(raw, &next); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+    return &next, nil
+}
+
+func (s *postgresService) Patch(id string, patch map[string]json.RawMessage) (*Listing, error) {
+    if err := checkPatch(patch); err != nil {
+        return nil, err
+    }
+    var out Listing
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var cur Listing
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, "id = ?", id).Error; err != nil {
+            return err
+        }
+        next, err := mergePatch(&cur, patch)
+        if err != nil {
+            return err
+        }
+        if err := next.normalizeMetadata(); err != nil {
+            return err
+        }
+        if err := next.validateSchedule(); err != nil {
+            return err
+        }
+        if err := next.normalizePrice(); err != nil {
+            return err
+        }
+        switch {
+        case next.Title == "":
+            return fmt.Errorf("%w: title cannot be empty", ErrInvalidPatch)
+        case next.PortionSize < 1:
+            return fmt.Errorf("%w: portionSize must be at least 1", ErrInvalidPatch)
+        }
+        for _, tags := range []*datatypes.JSONSlice[string]{&next.CuisineTags, &next.Dietary, &next.Allergens} {
+            if *tags == nil {
+                *tags = datatypes.JSONSlice[string]{}
+            }
+        }
+
+        // Select writes the patched columns even when they're zero values,
+        // which Updates with a struct alone would skip
+        cols := []string{"updated_at"}
+        for k := range patch {
+            cols = append(cols, patchColumns[k])
+        }
+        if _, ok := patch["allergens"]; ok {
+            cols = append(cols, "allergens_declared") // listing allergens declares them
+        }
+        if _, ok := patch["price"]; ok {
+            cols = append(cols, "price_currency")
+        }
+        err = tx.Model(&Listing{}).Where("id = ?", id).Select(cols).Updates(next).Error
+        if err != nil {
+            return err
+        }
+        _, publish := patch["publishAt"]
+        _, expiry := patch["expiresAt"]
+        if err := rescheduled(tx, &cur, publish, expiry); err != nil {
+            return err
+        }
+        return withImages(tx).First(&out, "id = ?", id).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &out, nil
+}
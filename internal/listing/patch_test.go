@@ -0,0 +1,63 @@
+package listing
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+
+    "gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func TestCheckPatch(t *testing.T) {
+    for name, body := range map[string]string{
+        "stock":          `{"leftSize": 0}`,
+        "seller":         `{"sellerId": "other"}`,
+        "unknown":        `{"colour": "red"}`,
+        "null title":     `{"title": null}`,
+        "null available": `{"available": null}`,
+        "empty":          `{}`,
+    } {
+        var patch map[string]json.RawMessage
+        json.Unmarshal([]byte(body), &patch)
+        if err := checkPatch(patch); !errors.Is(err, ErrInvalidPatch) {
+            t.Errorf("%s: err=%v; want ErrInvalidPatch", name, err)
+        }
+    }
+}
+
+func TestMergePatch(t *testing.T) {
+    publish := time.Date(2025, 7, 4, 17, 0, 0, 0, time.UTC)
+    cur := &Listing{
+        ID:          "l1",
+        Title:       "Curry",
+        Description: "Spicy",
+        Price:       money.New(1250, "USD"),
+        Available:   true,
+        PortionSize: 1,
+        LeftSize:    4,
+        Category:    "main",
+        CuisineTags: datatypes.JSONSlice[string]{"thai"},
+        PublishAt:   &publish,
+    }
+    var patch map[string]json.RawMessage
+    json.Unmarshal([]byte(`{"available": false, "price": 0, "description": null, "publishAt": null, "cuisineTags": ["thai", "lao"]}`), &patch)
+    if err := checkPatch(patch); err != nil {
+        t.Fatal(err)
+    }
+    next, err := mergePatch(cur, patch)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if next.Available || next.Price != money.New(0, "USD") || next.Description != "" || next.PublishAt != nil {
+        t.Errorf("zero/null values not applied: %+v", next)
+    }
+    if len(next.CuisineTags) != 2 {
+        t.Errorf("cuisineTags = %v", next.CuisineTags)
+    }
+    if next.Title != "Curry" || next.LeftSize != 4 || next.Category != "main" || next.ID != "l1" {
+        t.Errorf("untouched fields changed: %+v", next)
+    }
+}
@@ -1,11 +1,19 @@
 package listing
 
 import (
+    "encoding/json"
     "errors"
+    "fmt"
+    "path"
     "sort"
+    "time"
 
     "github.com/google/uuid"
+    "gorm.io/datatypes"
     "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
 )
 
 // postgresService persists listings in Postgres via GORM.
@@ -19,13 +27,29 @@ func NewPostgresService(db *gorm.DB) Service {
 }
 
 func (s *postgresService) Create(l *Listing) error {
+    if err := l.normalizeMetadata(); err != nil {
+        return err
+    }
+    if err := l.normalizePrice(); err != nil {
+        return err
+    }
+    if err := l.validateSchedule(); err != nil {
+        return err
+    }
+    for _, tags := range []*datatypes.JSONSlice[string]{&l.CuisineTags, &l.Dietary, &l.Allergens} {
+        if *tags == nil {
+            *tags = datatypes.JSONSlice[string]{}
+        }
+    }
     l.ID = uuid.NewString()
+    l.SoldOut = false
+    l.RatingAvg, l.RatingCount = 0, 0
     return s.db.Create(l).Error
 }
 
 func (s *postgresService) GetByID(id string) (*Listing, error) {
     var l Listing
-    if err := s.db.First(&l, "id = ?", id).Error; err != nil {
+    if err := withImages(s.db).First(&l, "id = ?", id).Error; err != nil {
         if errors.Is(err, gorm.ErrRecordNotFound) {
             return nil, errors.New("listing not found")
         }
@@ -34,9 +58,20 @@ func (s *postgresService) GetByID(id string) (*Listing, error) {
     return &l, nil
 }
 
+func (s *postgresService) GetByIDs(ids []string) ([]Listing, error) {
+    out := []Listing{}
+    if len(ids) == 0 {
+        return out, nil
+    }
+    if err := withImages(s.db).Where("id IN ?", ids).Find(&out).Error; err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
 func (s *postgresService) ListBySeller(sellerID string) ([]Listing, error) {
     var out []Listing
-    if err := s.db.Where("seller_id = ?", sellerID).Find(&out).Error; err != nil {
+    if err := withImages(s.db).Where("seller_id = ?", sellerID).Find(&out).Error; err != nil {
         return nil, err
     }
     sort.Slice(out, func(i, j int) bool {
@@ -48,7 +83,7 @@ func (s *postgresService) ListBySeller(sellerID string) ([]Listing, error) {
 // If you need a ListAll (not in your in‑memory), you can add:
 func (s *postgresService) ListAll() []Listing {
     var all []Listing
-    s.db.Find(&all)
+    withImages(s.db).Find(&all)
     sort.Slice(all, func(i, j int) bool {
         return all[i].CreatedAt.Before(all[j].CreatedAt)
     })
@@ -56,24 +91,529 @@ func (s *postgresService) ListAll() []Listing {
 }
 
 func (s *postgresService) Update(id string, l Listing) error {
-    // l.ID = id             // no need if you never insert
-    // Update only the non‑zero fields in l for the row with this ID
-    result := s.db.
-        Model(&Listing{}).
-        Where("id = ?", id).
-        Updates(l)
+    if err := l.normalizeMetadata(); err != nil {
+        return err
+    }
+    if l.Price != (money.Money{}) { // omitted from the body
+        if err := l.normalizePrice(); err != nil {
+            return err
+        }
+    }
+    // the server owns these; stock goes through AdjustStock so it's audited
+    l.PublishedAt, l.ExpiredAt, l.SoldOut, l.LeftSize = nil, nil, false, 0
+    l.RatingAvg, l.RatingCount = 0, 0
+
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var cur Listing
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, "id = ?", id).Error; err != nil {
+            return err
+        }
+        merged := Listing{PublishAt: cur.PublishAt, ExpiresAt: cur.ExpiresAt}
+        if l.PublishAt != nil {
+            merged.PublishAt = l.PublishAt
+        }
+        if l.ExpiresAt != nil {
+            merged.ExpiresAt = l.ExpiresAt
+        }
+        if err := merged.validateSchedule(); err != nil {
+            return err
+        }
+
+        // Update only the non‑zero fields in l for the row with this ID
+        result := tx.
+            Model(&Listing{}).
+            Omit(clause.Associations).
+            Where("id = ?", id).
+            Updates(l)
+        if result.Error != nil {
+            return result.Error
+        }
+        return rescheduled(tx, &cur, l.PublishAt != nil, l.ExpiresAt != nil)
+    })
+}
 
-    if result.Error != nil {
-        return result.Error
+// rescheduled sends a listing whose publishAt/expiresAt changed through the
+// scheduler again; pushing back the expiry of an expired listing relists it.
+func rescheduled(tx *gorm.DB, cur *Listing, publish, expiry bool) error {
+    reset := map[string]interface{}{}
+    if publish {
+        reset["published_at"] = nil
     }
-    // Optionally: return ErrRecordNotFound if no rows were affected
-    if result.RowsAffected == 0 {
-        return gorm.ErrRecordNotFound
+    if expiry {
+        reset["expired_at"] = nil
+        if cur.ExpiredAt != nil {
+            reset["available"] = true
+        }
     }
-    return nil
+    if len(reset) == 0 {
+        return nil
+    }
+    return tx.Model(&Listing{}).Where("id = ?", cur.ID).UpdateColumns(reset).Error
+}
+
+func (s *postgresService) AdjustStock(listingID, actor string, c StockChange) (*StockResult, error) {
+    var res *StockResult
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var cur Listing
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, "id = ?", listingID).Error; err != nil {
+            return err
+        }
+        after, err := c.apply(cur.LeftSize)
+        if err != nil {
+            return err
+        }
+        res, err = setStock(tx, &cur, after, StockAdjustment{Op: c.Op, Reason: c.Reason, Actor: actor})
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    res.emitEvents()
+    return res, nil
+}
+
+// setStock moves a locked listing's stock to after, records adj in the
+// ledger, and sells it out or restocks it as needed.
+func setStock(tx *gorm.DB, cur *Listing, after int, adj StockAdjustment) (*StockResult, error) {
+    adj.ID = uuid.NewString()
+    adj.ListingID = cur.ID
+    adj.Before, adj.After, adj.Delta = cur.LeftSize, after, after-cur.LeftSize
+
+    res := &StockResult{}
+    cols := map[string]interface{}{"left_size": after}
+    switch {
+    case after == 0 && !cur.SoldOut:
+        cols["sold_out"] = true
+        res.SoldOut = true
+    case after > 0 && cur.SoldOut:
+        cols["sold_out"] = false
+        // subscriptions are one-shot: take them in the same transaction
+        // so a concurrent restock can't notify twice
+        var subs []RestockSubscription
+        if err := tx.Clauses(clause.Returning{}).Where("listing_id = ?", cur.ID).Delete(&subs).Error; err != nil {
+            return nil, err
+        }
+        res.Restocked = &RestockNotice{}
+        for _, sub := range subs {
+            res.Restocked.Subscribers = append(res.Restocked.Subscribers, sub.UserEmail)
+        }
+    }
+    if err := tx.Model(&Listing{}).Where("id = ?", cur.ID).UpdateColumns(cols).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Create(&adj).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.First(&res.Listing, "id = ?", cur.ID).Error; err != nil {
+        return nil, err
+    }
+    res.Adjustment = adj
+    if res.Restocked != nil {
+        res.Restocked.Listing = res.Listing
+    }
+    return res, nil
+}
+
+func (s *postgresService) StockHistory(listingID string, limit int) ([]StockAdjustment, error) {
+    var out []StockAdjustment
+    err := s.db.Where("listing_id = ?", listingID).Order("created_at DESC").Limit(limit).Find(&out).Error
+    if err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
+// ConsumePortion takes one portion of a listing for an accepted order,
+// recording it in the stock ledger. It must run in the order's transaction;
+// the caller emits a ListingSoldOut event for a returned listing once the
+// transaction commits.
+func ConsumePortion(tx *gorm.DB, listingID, orderID, buyer string) (soldOut *Listing, err error) {
+    var cur Listing
+    err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+        Where("id = ? AND left_size > 0", listingID).
+        Where(AvailableNowSQL).
+        First(&cur).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, fmt.Errorf("listing %s is not available or has no portions left", listingID)
+    }
+    if err != nil {
+        return nil, err
+    }
+    res, err := setStock(tx, &cur, cur.LeftSize-1, StockAdjustment{Op: OpOrder, Actor: buyer, OrderID: &orderID})
+    if err != nil || !res.SoldOut {
+        return nil, err
+    }
+    return &res.Listing, nil
+}
+
+// ReturnPortion puts back a portion ConsumePortion took for an order that
+// won't be fulfilled. Like ConsumePortion it runs in the order's
+// transaction; the caller emits ListingRestocked for a returned notice once
+// it commits. Deleted listings are left alone.
+func ReturnPortion(tx *gorm.DB, listingID, orderID, actor string) (restocked *RestockNotice, err error) {
+    var found []Listing
+    err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", listingID).Limit(1).Find(&found).Error
+    if err != nil || len(found) == 0 {
+        return nil, err
+    }
+    cur := found[0]
+    res, err := setStock(tx, &cur, cur.LeftSize+1, StockAdjustment{Op: OpOrderReturn, Actor: actor, OrderID: &orderID})
+    if err != nil {
+        return nil, err
+    }
+    return res.Restocked, nil
+}
+
+func (s *postgresService) Subscribe(listingID, email string) error {
+    var l Listing
+    if err := s.db.Select("id, sold_out").First(&l, "id = ?", listingID).Error; err != nil {
+        return err
+    }
+    if !l.SoldOut {
+        return ErrNotSoldOut
+    }
+    sub := RestockSubscription{ListingID: listingID, UserEmail: email}
+    // subscribing twice is fine
+    return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&sub).Error
+}
+
+func (s *postgresService) Unsubscribe(listingID, email string) error {
+    return s.db.Where("listing_id = ? AND user_email = ?", listingID, email).Delete(&RestockSubscription{}).Error
+}
+
+func (s *postgresService) PublishDue(now time.Time) ([]Listing, error) {
+    var out []Listing
+    err := s.db.Model(&out).
+        Clauses(clause.Returning{}).
+        Where("publish_at <= ? AND published_at IS NULL", now).
+        Where("expires_at IS NULL OR expires_at > ?", now).
+        UpdateColumns(map[string]interface{}{"available": true, "published_at": now}).Error
+    return out, err
+}
+
+func (s *postgresService) ExpireDue(now time.Time) ([]Listing, error) {
+    var out []Listing
+    err := s.db.Model(&out).
+        Clauses(clause.Returning{}).
+        Where("expires_at <= ? AND expired_at IS NULL", now).
+        UpdateColumns(map[string]interface{}{"available": false, "expired_at": now}).Error
+    return out, err
 }
 
 
 func (s *postgresService) Delete(id string) error {
     return s.db.Delete(&Listing{}, "id = ?", id).Error
 }
+
+// withImages preloads each listing's gallery in display order.
+func withImages(db *gorm.DB) *gorm.DB {
+    return db.Preload("Images", func(tx *gorm.DB) *gorm.DB {
+        return tx.Order("position, created_at")
+    })
+}
+
+// attachImages fills in the galleries of search results, which are scanned
+// rather than found and so can't use Preload. Scan skips AfterFind too, so
+// it also settles their availability.
+func (s *postgresService) attachImages(results []SearchResult) error {
+    now := time.Now()
+    ids := make([]string, len(results))
+    for i := range results {
+        ids[i] = results[i].ID
+        results[i].Available = results[i].AvailableAt(now)
+    }
+    var imgs []ListingImage
+    if err := s.db.Where("listing_id IN ?", ids).Order("position, created_at").Find(&imgs).Error; err != nil {
+        return err
+    }
+    byListing := make(map[string][]ListingImage)
+    for _, img := range imgs {
+        byListing[img.ListingID] = append(byListing[img.ListingID], img)
+    }
+    for i := range results {
+        results[i].Images = byListing[results[i].ID]
+    }
+    return nil
+}
+
+// headlineOpts controls ts_headline output for search snippets.
+const headlineOpts = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+// filtered scopes a listings query to the given filter.
+func (s *postgresService) filtered(f Filter) *gorm.DB {
+    q := s.db.Model(&Listing{})
+    if f.SellerID != "" {
+        q = q.Where("listings.seller_id = ?", f.SellerID)
+    }
+    if f.Available != nil {
+        if *f.Available {
+            q = q.Where(AvailableNowSQL)
+        } else {
+            q = q.Where("NOT (" + AvailableNowSQL + ")")
+        }
+    }
+    if f.Category != "" {
+        q = q.Where("listings.category = ?", f.Category)
+    }
+    if len(f.Cuisine) > 0 {
+        q = q.Where(jsonbOverlaps("listings.cuisine_tags"), f.Cuisine)
+    }
+    if len(f.Dietary) > 0 {
+        all, _ := json.Marshal(f.Dietary)
+        q = q.Where("listings.dietary @> ?::jsonb", string(all))
+    }
+    if len(f.ExcludeAllergens) > 0 {
+        q = q.Where("listings.allergens_declared").
+            Where("NOT "+jsonbOverlaps("listings.allergens"), f.ExcludeAllergens)
+    }
+    return q
+}
+
+// jsonbOverlaps is a condition matching rows whose jsonb string array column
+// shares at least one element with the []string bound to its placeholder.
+func jsonbOverlaps(column string) string {
+    return "EXISTS (SELECT 1 FROM jsonb_array_elements_text(" + column + ") AS t(v) WHERE t.v IN ?)"
+}
+
+func (s *postgresService) List(f Filter) ([]Listing, error) {
+    var out []Listing
+    q := s.filtered(f)
+    if f.Sort == SortRating {
+        q = q.Order("listings.rating_avg DESC, listings.rating_count DESC")
+    }
+    if err := withImages(q).Order("listings.created_at").Find(&out).Error; err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
+func (s *postgresService) Search(query string, f Filter, limit int) ([]SearchResult, error) {
+    const tsq = "websearch_to_tsquery('english', ?)"
+    highlight := "ts_headline('english', listings.title, " + tsq + ", 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')"
+    snippet := "ts_headline('english', coalesce(listings.description, ''), " + tsq + ", '" + headlineOpts + "')"
+    order := "rank DESC, listings.created_at DESC"
+    if f.Sort == SortRating {
+        order = "listings.rating_avg DESC, listings.rating_count DESC, " + order
+    }
+
+    var out []SearchResult
+    err := s.filtered(f).
+        Select("listings.*, ts_rank_cd(listings.search_vector, "+tsq+") AS rank, "+highlight+" AS highlight, "+snippet+" AS snippet",
+            query, query, query).
+        Where("listings.search_vector @@ "+tsq, query).
+        Order(order).
+        Limit(limit).
+        Scan(&out).Error
+    if err != nil {
+        return nil, err
+    }
+    if len(out) > 0 {
+        return out, s.attachImages(out)
+    }
+
+    // Nothing matched lexically: the query is probably misspelled, so rank
+    // by trigram word similarity against the title instead.
+    err = s.filtered(f).
+        Select("listings.*, word_similarity(?, listings.title) AS rank, listings.title AS highlight, "+snippet+" AS snippet",
+            query, query).
+        Where("? <% listings.title OR listings.title % ?", query, query).
+        Order(order).
+        Limit(limit).
+        Scan(&out).Error
+    if err != nil {
+        return nil, err
+    }
+    return out, s.attachImages(out)
+}
+
+func (s *postgresService) AddImage(listingID string, img *ListingImage) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        // lock the listing so concurrent uploads get distinct positions
+        var l Listing
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&l, "id = ?", listingID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return errors.New("listing not found")
+            }
+            return err
+        }
+        // uploads are content-addressed, so the same file twice is the same image
+        var existing ListingImage
+        err := tx.Where("listing_id = ? AND object_name = ?", listingID, img.ObjectName).First(&existing).Error
+        if err == nil {
+            *img = existing
+            return nil
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return err
+        }
+
+        var last struct{ Max *int }
+        if err := tx.Model(&ListingImage{}).Select("MAX(position) AS max").Where("listing_id = ?", listingID).Scan(&last).Error; err != nil {
+            return err
+        }
+        if img.ID == "" {
+            img.ID = uuid.NewString()
+        }
+        img.ListingID = listingID
+        img.Position = 0
+        img.IsPrimary = last.Max == nil // first image in the gallery
+        if last.Max != nil {
+            img.Position = *last.Max + 1
+        }
+        if err := tx.Create(img).Error; err != nil {
+            return err
+        }
+        if img.IsPrimary {
+            return tx.Model(&Listing{}).Where("id = ?", listingID).Update("image", img.URL).Error
+        }
+        return nil
+    })
+}
+
+func (s *postgresService) ListImages(listingID string) ([]ListingImage, error) {
+    var imgs []ListingImage
+    if err := s.db.Where("listing_id = ?", listingID).Order("position, created_at").Find(&imgs).Error; err != nil {
+        return nil, err
+    }
+    return imgs, nil
+}
+
+func (s *postgresService) ReorderImages(listingID string, imageIDs []string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var existing []ListingImage
+        if err := tx.Where("listing_id = ?", listingID).Find(&existing).Error; err != nil {
+            return err
+        }
+        // the new order must name every image exactly once
+        if len(imageIDs) != len(existing) {
+            return errors.New("image order must list every image of the listing")
+        }
+        known := make(map[string]bool, len(existing))
+        for _, img := range existing {
+            known[img.ID] = true
+        }
+        for pos, id := range imageIDs {
+            if !known[id] {
+                return ErrImageNotFound
+            }
+            delete(known, id) // catches duplicates
+            if err := tx.Model(&ListingImage{}).Where("id = ?", id).Update("position", pos).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (s *postgresService) SetPrimaryImage(listingID, imageID string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var img ListingImage
+        if err := tx.First(&img, "id = ? AND listing_id = ?", imageID, listingID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return ErrImageNotFound
+            }
+            return err
+        }
+        return setPrimary(tx, img)
+    })
+}
+
+// setPrimary flags img as its listing's only primary image and mirrors its
+// URL onto Listing.Image.
+func setPrimary(tx *gorm.DB, img ListingImage) error {
+    if err := tx.Model(&ListingImage{}).
+        Where("listing_id = ?", img.ListingID).
+        Update("is_primary", gorm.Expr("id = ?", img.ID)).Error; err != nil {
+        return err
+    }
+    return tx.Model(&Listing{}).Where("id = ?", img.ListingID).Update("image", img.URL).Error
+}
+
+func (s *postgresService) DeleteImage(listingID, imageID string) (*ListingImage, error) {
+    var img ListingImage
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.First(&img, "id = ? AND listing_id = ?", imageID, listingID).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return ErrImageNotFound
+            }
+            return err
+        }
+        if err := tx.Delete(&img).Error; err != nil {
+            return err
+        }
+        if !img.IsPrimary {
+            return nil
+        }
+        // promote the next image in the gallery, if any
+        var next ListingImage
+        err := tx.Where("listing_id = ?", listingID).Order("position, created_at").First(&next).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return tx.Model(&Listing{}).Where("id = ?", listingID).Update("image", "").Error
+        }
+        if err != nil {
+            return err
+        }
+        return setPrimary(tx, next)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &img, nil
+}
+
+func (s *postgresService) CreateUpload(u *ImageUpload) error {
+    if u.ID == "" {
+        u.ID = uuid.NewString()
+    }
+    return s.db.Create(u).Error
+}
+
+func (s *postgresService) GetUpload(listingID, uploadID string) (*ImageUpload, error) {
+    var u ImageUpload
+    if err := s.db.First(&u, "id = ? AND listing_id = ?", uploadID, listingID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, ErrUploadNotFound
+        }
+        return nil, err
+    }
+    return &u, nil
+}
+
+func (s *postgresService) DeleteUpload(uploadID string) error {
+    return s.db.Delete(&ImageUpload{}, "id = ?", uploadID).Error
+}
+
+func (s *postgresService) ExpiredUploads(before time.Time, limit int) ([]ImageUpload, error) {
+    var ups []ImageUpload
+    if err := s.db.Where("expires_at < ?", before).Order("expires_at").Limit(limit).Find(&ups).Error; err != nil {
+        return nil, err
+    }
+    return ups, nil
+}
+
+func (s *postgresService) ReferencedObjects() (map[string]bool, error) {
+    refs := make(map[string]bool)
+    var imgs []ListingImage
+    err := s.db.Select("listing_images.object_name, listing_images.medium_object, listing_images.thumb_object").
+        Joins("JOIN listings ON listings.id = listing_images.listing_id AND listings.deleted_at IS NULL").
+        Find(&imgs).Error
+    if err != nil {
+        return nil, err
+    }
+    for _, img := range imgs {
+        for _, o := range img.Objects() {
+            refs[o] = true
+        }
+    }
+
+    // listings.image predates galleries and may point at an object with no
+    // listing_images row
+    var legacy []Listing
+    if err := s.db.Select("id, image").Where("coalesce(image, '') <> ''").Find(&legacy).Error; err != nil {
+        return nil, err
+    }
+    for _, l := range legacy {
+        refs[fmt.Sprintf("listings/%s/%s", l.ID, path.Base(l.Image))] = true
+    }
+    return refs, nil
+}
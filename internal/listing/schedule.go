@@ -0,0 +1,102 @@
+package listing
+
+import (
+    "context"
+    "errors"
+    "log"
+    "os"
+    "time"
+
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+)
+
+// ErrInvalidSchedule is returned when a listing expires before it publishes.
+var ErrInvalidSchedule = errors.New("expiresAt must be after publishAt")
+
+// AvailableNowSQL matches listings that can be ordered right now. It checks
+// the publication window itself rather than trusting the available flag, so
+// a late scheduler never shows an expired listing or hides a published one.
+const AvailableNowSQL = `(listings.publish_at IS NULL OR listings.publish_at <= now())
+    AND (listings.expires_at IS NULL OR listings.expires_at > now())
+    AND (listings.available OR (listings.publish_at IS NOT NULL AND listings.published_at IS NULL))
+    AND NOT listings.sold_out`
+
+// AvailableAt is AvailableNowSQL for a loaded listing.
+func (l *Listing) AvailableAt(now time.Time) bool {
+    if l.PublishAt != nil && now.Before(*l.PublishAt) {
+        return false
+    }
+    if l.ExpiresAt != nil && !now.Before(*l.ExpiresAt) {
+        return false
+    }
+    if l.SoldOut {
+        return false
+    }
+    return l.Available || (l.PublishAt != nil && l.PublishedAt == nil)
+}
+
+// AfterFind reports availability as of now, whatever the scheduler has got
+// round to.
+func (l *Listing) AfterFind(tx *gorm.DB) error {
+    l.Available = l.AvailableAt(time.Now())
+    return nil
+}
+
+func (l *Listing) validateSchedule() error {
+    if l.PublishAt != nil && l.ExpiresAt != nil && !l.ExpiresAt.After(*l.PublishAt) {
+        return ErrInvalidSchedule
+    }
+    return nil
+}
+
+// SchedulerIntervalFromEnv reads LISTING_SCHEDULER_INTERVAL (a Go duration,
+// default 1m).
+func SchedulerIntervalFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("LISTING_SCHEDULER_INTERVAL")); err == nil && d > 0 {
+        return d
+    }
+    return time.Minute
+}
+
+// StartScheduler publishes and expires listings whose time has come every
+// interval until ctx is cancelled, emitting ListingPublished and
+// ListingExpired events.
+func StartScheduler(ctx context.Context, svc Service, interval time.Duration) {
+    go func() {
+        t := time.NewTicker(interval)
+        defer t.Stop()
+        for {
+            runSchedule(svc, time.Now())
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+            }
+        }
+    }()
+}
+
+func runSchedule(svc Service, now time.Time) {
+    published, err := svc.PublishDue(now)
+    if err != nil {
+        log.Printf("listing scheduler: publish: %v", err)
+    }
+    expired, err := svc.ExpireDue(now)
+    if err != nil {
+        log.Printf("listing scheduler: expire: %v", err)
+    }
+    for _, l := range published {
+        emit("ListingPublished", l)
+    }
+    for _, l := range expired {
+        emit("ListingExpired", l)
+    }
+}
+
+func emit(eventType string, data interface{}) {
+    go func(ev event.Event) {
+        event.Bus <- ev
+    }(event.Event{Type: eventType, Data: data})
+}
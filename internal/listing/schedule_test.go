@@ -0,0 +1,44 @@
+package listing
+
+import (
+    "testing"
+    "time"
+)
+
+func TestAvailableAt(t *testing.T) {
+    now := time.Date(2025, 7, 4, 18, 0, 0, 0, time.UTC)
+    at := func(h int) *time.Time {
+        t := time.Date(2025, 7, 4, h, 0, 0, 0, time.UTC)
+        return &t
+    }
+    for _, tc := range []struct {
+        name string
+        l    Listing
+        want bool
+    }{
+        {"plain available", Listing{Available: true}, true},
+        {"plain unavailable", Listing{}, false},
+        {"before publish", Listing{Available: true, PublishAt: at(19)}, false},
+        {"published", Listing{Available: true, PublishAt: at(17), PublishedAt: at(17)}, true},
+        {"publish due, scheduler late", Listing{PublishAt: at(17)}, true},
+        {"unlisted after publishing", Listing{PublishAt: at(17), PublishedAt: at(17)}, false},
+        {"expired, scheduler late", Listing{Available: true, PublishAt: at(17), ExpiresAt: at(18)}, false},
+        {"inside window", Listing{Available: true, PublishAt: at(17), ExpiresAt: at(20)}, true},
+        {"sold out", Listing{Available: true, SoldOut: true}, false},
+    } {
+        if got := tc.l.AvailableAt(now); got != tc.want {
+            t.Errorf("%s: AvailableAt = %v; want %v", tc.name, got, tc.want)
+        }
+    }
+}
+
+func TestValidateSchedule(t *testing.T) {
+    a := time.Now()
+    b := a.Add(time.Hour)
+    if err := (&Listing{PublishAt: &b, ExpiresAt: &a}).validateSchedule(); err != ErrInvalidSchedule {
+        t.Errorf("expiry before publish: err=%v; want ErrInvalidSchedule", err)
+    }
+    if err := (&Listing{PublishAt: &a, ExpiresAt: &b}).validateSchedule(); err != nil {
+        t.Errorf("valid window: %v", err)
+    }
+}
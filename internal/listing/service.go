@@ -1,13 +1,63 @@
 package listing
 
+import (
+    "encoding/json"
+    "time"
+)
+
 // Service defines what our handlers expect.
 type Service interface {
     Create(l *Listing) error
     GetByID(id string) (*Listing, error)
+    // GetByIDs returns the listings that exist among ids, in no particular
+    // order; missing and deleted ones are left out.
+    GetByIDs(ids []string) ([]Listing, error)
     ListBySeller(sellerID string) ([]Listing, error)
     ListAll() []Listing
+    List(f Filter) ([]Listing, error)
 
     // new
     Update(id string, l Listing) error
+    // Patch applies a JSON merge patch (RFC 7386); stock and other
+    // server-managed fields are rejected with ErrInvalidPatch.
+    Patch(id string, patch map[string]json.RawMessage) (*Listing, error)
     Delete(id string) error
+
+    // Search ranks listings against a free-text query, falling back to
+    // trigram similarity on the title when nothing matches exactly.
+    Search(query string, f Filter, limit int) ([]SearchResult, error)
+
+    // image gallery; the first image added becomes the primary one. Adding
+    // an image whose ObjectName is already in the gallery returns that image.
+    AddImage(listingID string, img *ListingImage) error
+    ListImages(listingID string) ([]ListingImage, error)
+    ReorderImages(listingID string, imageIDs []string) error
+    SetPrimaryImage(listingID, imageID string) error
+    DeleteImage(listingID, imageID string) (*ListingImage, error)
+
+    // pending presigned uploads
+    CreateUpload(u *ImageUpload) error
+    GetUpload(listingID, uploadID string) (*ImageUpload, error)
+    DeleteUpload(uploadID string) error
+    ExpiredUploads(before time.Time, limit int) ([]ImageUpload, error)
+
+    // AdjustStock changes LeftSize and records it in the stock ledger,
+    // selling out the listing at zero and notifying restock subscribers
+    // when it comes back. StockHistory is the ledger, newest first.
+    AdjustStock(listingID, actor string, c StockChange) (*StockResult, error)
+    StockHistory(listingID string, limit int) ([]StockAdjustment, error)
+
+    // restock notifications; Subscribe fails with ErrNotSoldOut unless the
+    // listing is sold out.
+    Subscribe(listingID, email string) error
+    Unsubscribe(listingID, email string) error
+
+    // PublishDue and ExpireDue mark listings whose publishAt/expiresAt has
+    // passed as available/unavailable, returning the listings they changed.
+    PublishDue(now time.Time) ([]Listing, error)
+    ExpireDue(now time.Time) ([]Listing, error)
+
+    // ReferencedObjects returns the image object keys still used by
+    // listings that aren't deleted; everything else under listings/ is garbage.
+    ReferencedObjects() (map[string]bool, error)
 }
@@ -0,0 +1,85 @@
+package listing
+
+import (
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Stock operations. OpOrder is recorded when an accepted order takes a
+// portion, OpOrderReturn when a rejected order gives it back.
+const (
+    OpSet         = "set"
+    OpIncrement   = "increment"
+    OpDecrement   = "decrement"
+    OpOrder       = "order"
+    OpOrderReturn = "order_return"
+)
+
+var (
+    ErrInvalidStockChange = errors.New("invalid stock change")
+    ErrInsufficientStock  = errors.New("not enough portions left")
+)
+
+// StockChange is a seller's adjustment of a listing's LeftSize.
+type StockChange struct {
+    Op       string `json:"op" binding:"required"` // set, increment or decrement
+    Quantity int    `json:"quantity"`
+    Reason   string `json:"reason"`
+}
+
+// apply returns the stock after the change.
+func (c StockChange) apply(before int) (int, error) {
+    if c.Quantity < 0 {
+        return 0, fmt.Errorf("%w: quantity must not be negative", ErrInvalidStockChange)
+    }
+    switch c.Op {
+    case OpSet:
+        return c.Quantity, nil
+    case OpIncrement, OpDecrement:
+        if c.Quantity == 0 {
+            return 0, fmt.Errorf("%w: quantity must be positive", ErrInvalidStockChange)
+        }
+        if c.Op == OpIncrement {
+            return before + c.Quantity, nil
+        }
+        if c.Quantity > before {
+            return 0, fmt.Errorf("%w: %d left, cannot take %d", ErrInsufficientStock, before, c.Quantity)
+        }
+        return before - c.Quantity, nil
+    default:
+        return 0, fmt.Errorf("%w: op must be set, increment or decrement", ErrInvalidStockChange)
+    }
+}
+
+// StockAdjustment is one entry in a listing's stock ledger.
+type StockAdjustment struct {
+    ID        string    `json:"id" gorm:"type:uuid;primaryKey"`
+    ListingID string    `json:"listingId" gorm:"type:uuid;not null;index"`
+    Op        string    `json:"op" gorm:"type:varchar(20);not null"`
+    Delta     int       `json:"delta" gorm:"not null"`
+    Before    int       `json:"before" gorm:"column:before_qty;not null"`
+    After     int       `json:"after" gorm:"column:after_qty;not null"`
+    Reason    string    `json:"reason,omitempty" gorm:"type:text"`
+    Actor     string    `json:"actor" gorm:"type:varchar(100);not null"` // seller email, or the buyer's for orders
+    OrderID   *string   `json:"orderId,omitempty" gorm:"type:uuid;index"`
+    CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// StockResult is what an adjustment did.
+type StockResult struct {
+    Adjustment StockAdjustment `json:"adjustment"`
+    Listing    Listing         `json:"listing"`
+    SoldOut    bool            `json:"-"` // this change sold the listing out
+    Restocked  *RestockNotice  `json:"-"` // this change restocked a sold-out listing
+}
+
+// emitEvents announces a sold-out or restocked listing.
+func (r *StockResult) emitEvents() {
+    if r.SoldOut {
+        emit("ListingSoldOut", r.Listing)
+    }
+    if r.Restocked != nil {
+        emit("ListingRestocked", *r.Restocked)
+    }
+}
@@ -0,0 +1,29 @@
+package listing
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestStockChangeApply(t *testing.T) {
+    for _, tc := range []struct {
+        change StockChange
+        before int
+        want   int
+        err    error
+    }{
+        {StockChange{Op: OpSet, Quantity: 0}, 5, 0, nil},
+        {StockChange{Op: OpSet, Quantity: 12}, 0, 12, nil},
+        {StockChange{Op: OpIncrement, Quantity: 3}, 2, 5, nil},
+        {StockChange{Op: OpDecrement, Quantity: 2}, 2, 0, nil},
+        {StockChange{Op: OpDecrement, Quantity: 3}, 2, 0, ErrInsufficientStock},
+        {StockChange{Op: OpIncrement, Quantity: 0}, 2, 0, ErrInvalidStockChange},
+        {StockChange{Op: OpSet, Quantity: -1}, 2, 0, ErrInvalidStockChange},
+        {StockChange{Op: OpOrder, Quantity: 1}, 2, 0, ErrInvalidStockChange}, // only orders record this
+    } {
+        got, err := tc.change.apply(tc.before)
+        if !errors.Is(err, tc.err) || (err == nil && got != tc.want) {
+            t.Errorf("%+v on %d = %d, %v; want %d, %v", tc.change, tc.before, got, err, tc.want, tc.err)
+        }
+    }
+}
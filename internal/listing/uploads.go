@@ -0,0 +1,76 @@
+package listing
+
+import (
+    "context"
+    "log"
+    "os"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/image_store"
+)
+
+const (
+    // uploadURLTTL is how long a presigned upload URL accepts the PUT
+    uploadURLTTL = 15 * time.Minute
+    // uploadConfirmWindow is how long an upload can wait for confirm before
+    // the janitor removes it
+    uploadConfirmWindow = time.Hour
+
+    defaultUploadJanitorInterval = 10 * time.Minute
+    uploadJanitorBatch           = 100
+)
+
+// UploadJanitorIntervalFromEnv reads IMAGE_UPLOAD_GC_INTERVAL (a Go
+// duration such as "5m"), defaulting to 10 minutes.
+func UploadJanitorIntervalFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("IMAGE_UPLOAD_GC_INTERVAL")); err == nil && d > 0 {
+        return d
+    }
+    return defaultUploadJanitorInterval
+}
+
+// StartUploadJanitor removes presigned uploads that were never confirmed,
+// every interval until ctx is cancelled.
+func StartUploadJanitor(ctx context.Context, svc Service, store image_store.Store, interval time.Duration) {
+    go func() {
+        t := time.NewTicker(interval)
+        defer t.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+                if n, err := SweepExpiredUploads(ctx, svc, store); err != nil {
+                    log.Printf("upload janitor: %v", err)
+                } else if n > 0 {
+                    log.Printf("upload janitor: removed %d expired uploads", n)
+                }
+            }
+        }
+    }()
+}
+
+// SweepExpiredUploads deletes the objects and records of every upload past
+// its confirm window and returns how many were removed. A record is only
+// dropped once its object is gone, so a store outage is retried next sweep.
+func SweepExpiredUploads(ctx context.Context, svc Service, store image_store.Store) (int, error) {
+    removed := 0
+    for {
+        ups, err := svc.ExpiredUploads(time.Now(), uploadJanitorBatch)
+        if err != nil {
+            return removed, err
+        }
+        for _, up := range ups {
+            if err := store.Delete(ctx, up.ObjectKey); err != nil {
+                return removed, err
+            }
+            if err := svc.DeleteUpload(up.ID); err != nil {
+                return removed, err
+            }
+            removed++
+        }
+        if len(ups) < uploadJanitorBatch {
+            return removed, nil
+        }
+    }
+}
@@ -0,0 +1,70 @@
+// Package mailer sends transactional email, such as order receipts,
+// through SMTP, or to the log when no SMTP server is configured.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a plain-text email with optional attachments.
+type Message struct {
+	To          []string
+	Subject     string
+	Text        string
+	Attachments []Attachment
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// NewFromEnv builds the Mailer configured by the environment:
+//
+//   - SMTP_HOST set: an SMTPMailer for SMTP_HOST:SMTP_PORT (default 587),
+//     logging in with SMTP_USERNAME and SMTP_PASSWORD if set, sending from
+//     MAIL_FROM
+//   - otherwise: a LogMailer, for development
+func NewFromEnv() (Mailer, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogMailer{}, nil
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		return nil, errors.New("MAIL_FROM is required with SMTP_HOST")
+	}
+	port := 587
+	if raw := os.Getenv("SMTP_PORT"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid SMTP_PORT %q", raw)
+		}
+		port = n
+	}
+	return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+}
+
+// LogMailer logs messages instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) Send(_ context.Context, m Message) error {
+	names := make([]string, len(m.Attachments))
+	for i, a := range m.Attachments {
+		names[i] = a.Filename
+	}
+	log.Printf("mailer: to %v: %q, attachments %v\n%s", m.To, m.Subject, names, m.Text)
+	return nil
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer returns a Mailer for the server at host:port. With an empty
+// username no login is attempted.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: net.JoinHostPort(host, strconv.Itoa(port)), from: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers m. net/smtp has no context support, so ctx is only checked
+// before connecting.
+func (s *SMTPMailer) Send(ctx context.Context, m Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := buildMessage(s.from, m, uuid.NewString(), time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, m.To, msg)
+}
+
+// buildMessage encodes m as a MIME message: the text alone, or
+// multipart/mixed with base64 attachments separated by boundary.
+func buildMessage(from string, m Message, boundary string, date time.Time) ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("%w: no recipients", ErrInvalidMessage)
+	}
+	// header values must not smuggle in more headers
+	for _, v := range append([]string{from, m.Subject}, m.To...) {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("%w: line break in a header", ErrInvalidMessage)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	text := "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n\r\n" + wrap(base64.StdEncoding.EncodeToString([]byte(m.Text)))
+	if len(m.Attachments) == 0 {
+		b.WriteString(text)
+		return b.Bytes(), nil
+	}
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\n%s", boundary, text)
+	for _, a := range m.Attachments {
+		if strings.ContainsAny(a.Filename+a.ContentType, "\r\n\"") {
+			return nil, fmt.Errorf("%w: bad attachment name or type", ErrInvalidMessage)
+		}
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s\r\n", a.ContentType)
+		fmt.Fprintf(&b, "Content-Disposition: attachment; filename=%q\r\n", a.Filename)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		b.WriteString(wrap(base64.StdEncoding.EncodeToString(a.Data)))
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// wrap breaks base64 into the 76-character lines MIME requires.
+func wrap(s string) string {
+	var b strings.Builder
+	for len(s) > 76 {
+		b.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	b.WriteString(s + "\r\n")
+	return b.String()
+}
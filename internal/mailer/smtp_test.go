@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	pdf := bytes.Repeat([]byte("%PDF-1.4 "), 50)
+	raw, err := buildMessage("shop@example.com", Message{
+		To:          []string{"buyer@example.com"},
+		Subject:     "Your receipt — order 1234",
+		Text:        "Thanks for your order!",
+		Attachments: []Attachment{{Filename: "receipt.pdf", ContentType: "application/pdf", Data: pdf}},
+	}, "b0undary", time.Date(2025, 7, 4, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Your receipt — order 1234" || msg.Header.Get("To") != "buyer@example.com" {
+		t.Errorf("headers = %v", msg.Header)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+
+	var parts []string
+	var attached []byte
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, p.Header.Get("Content-Type"))
+		if p.FileName() == "receipt.pdf" {
+			attached, _ = io.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		}
+	}
+	if len(parts) != 2 {
+		t.Fatalf("parts = %v, want text and attachment", parts)
+	}
+	if !bytes.Equal(attached, pdf) {
+		t.Errorf("attachment did not round-trip: %d bytes", len(attached))
+	}
+}
+
+func TestBuildMessageRejectsHeaderInjection(t *testing.T) {
+	for _, m := range []Message{
+		{To: []string{"a@example.com"}, Subject: "hi\r\nBcc: victim@example.com"},
+		{To: []string{"a@example.com\r\nBcc: victim@example.com"}},
+		{},
+	} {
+		if _, err := buildMessage("shop@example.com", m, "b", time.Now()); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("buildMessage(%+v): err = %v, want ErrInvalidMessage", m, err)
+		}
+	}
+}
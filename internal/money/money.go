@@ -0,0 +1,207 @@
+// Package money represents amounts as integer minor units (cents) with an
+// ISO 4217 currency, so sums and tax never pick up float rounding errors.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrInvalidCurrency  = errors.New("invalid currency")
+)
+
+// DefaultCurrency is assumed for amounts given without one, which is how
+// prices were sent before currencies existed.
+var DefaultCurrency = "USD"
+
+// exponents lists currencies whose minor unit isn't 1/100.
+var exponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+var (
+	currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+	decimalRe  = regexp.MustCompile(`^(-)?(\d+)(?:\.(\d+))?$`)
+)
+
+// Money is an amount in a currency's minor units. Embed it in GORM models
+// with `gorm:"embedded;embeddedPrefix:price_"` to get price_amount and
+// price_currency columns.
+type Money struct {
+	Amount   int64  `gorm:"not null;default:0"`
+	Currency string `gorm:"type:char(3);not null;default:'USD'"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Exponent is the number of decimal places in the currency's minor unit.
+func Exponent(currency string) int {
+	if e, ok := exponents[currency]; ok {
+		return e
+	}
+	return 2
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 code.
+func ValidCurrency(code string) bool {
+	return currencyRe.MatchString(code)
+}
+
+// Parse reads a decimal string such as "12.50" in currency. More decimal
+// places than the currency has are an error rather than being rounded.
+func Parse(s, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !ValidCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	m := decimalRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	exp := Exponent(currency)
+	frac := strings.TrimRight(m[3], "0")
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %s has at most %d decimal places", ErrInvalidAmount, currency, exp)
+	}
+	digits := m[2] + frac + strings.Repeat("0", exp-len(frac))
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if m[1] == "-" {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Decimal formats the amount without its currency, e.g. "12.50".
+func (m Money) Decimal() string {
+	exp := Exponent(m.Currency)
+	sign, n := "", m.Amount
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := strconv.FormatInt(n, 10)
+	if exp == 0 {
+		return sign + s
+	}
+	if len(s) <= exp {
+		s = strings.Repeat("0", exp-len(s)+1) + s
+	}
+	return sign + s[:len(s)-exp] + "." + s[len(s)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Add returns m+o; both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m-o; both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m times n, e.g. a unit price times a quantity.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Sum adds amounts that must share a currency. The sum of nothing is zero
+// in DefaultCurrency.
+func Sum(ms ...Money) (Money, error) {
+	if len(ms) == 0 {
+		return Money{Currency: DefaultCurrency}, nil
+	}
+	total := Money{Currency: ms[0].Currency}
+	for _, m := range ms {
+		var err error
+		if total, err = total.Add(m); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON writes {"amount": "12.50", "currency": "USD"}. The amount is a
+// string so clients never round-trip it through a float.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON reads the MarshalJSON object, whose amount may also be a
+// number. A bare number or decimal string, as clients sent before amounts
+// had currencies, is read in DefaultCurrency.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = []byte(strings.TrimSpace(string(b)))
+	if len(b) > 0 && b[0] == '{' {
+		var v struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		if v.Currency == "" {
+			v.Currency = DefaultCurrency
+		}
+		amount, err := decimalText(v.Amount)
+		if err != nil {
+			return err
+		}
+		*m, err = Parse(amount, v.Currency)
+		return err
+	}
+	amount, err := decimalText(b)
+	if err != nil {
+		return err
+	}
+	*m, err = Parse(amount, DefaultCurrency)
+	return err
+}
+
+// decimalText returns the digits of a JSON number or string.
+func decimalText(b json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAmount, b)
+	}
+	// JSON allows 1e3 and 1.50E2; the minor-unit parser doesn't
+	if f, err := n.Float64(); err == nil && strings.ContainsAny(n.String(), "eE") {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	return n.String(), nil
+}
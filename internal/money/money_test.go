@@ -0,0 +1,90 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in, currency string
+		want         int64
+		err          error
+	}{
+		{"12.50", "USD", 1250, nil},
+		{"12.5", "USD", 1250, nil},
+		{"12", "USD", 1200, nil},
+		{"0.07", "usd", 7, nil},
+		{"-3.10", "EUR", -310, nil},
+		{"1.230", "USD", 123, nil}, // trailing zeros are fine
+		{"1500", "JPY", 1500, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"1.234", "USD", 0, ErrInvalidAmount},
+		{"1.5", "JPY", 0, ErrInvalidAmount},
+		{"abc", "USD", 0, ErrInvalidAmount},
+		{"1.", "USD", 0, ErrInvalidAmount},
+		{"1.00", "US", 0, ErrInvalidCurrency},
+	} {
+		got, err := Parse(tc.in, tc.currency)
+		if !errors.Is(err, tc.err) || (err == nil && got.Amount != tc.want) {
+			t.Errorf("Parse(%q, %q) = %v, %v; want %d, %v", tc.in, tc.currency, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	for _, tc := range []struct {
+		m    Money
+		want string
+	}{
+		{New(1250, "USD"), "12.50"},
+		{New(5, "USD"), "0.05"},
+		{New(0, "USD"), "0.00"},
+		{New(-310, "EUR"), "-3.10"},
+		{New(1500, "JPY"), "1500"},
+		{New(1234, "KWD"), "1.234"},
+	} {
+		if got := tc.m.Decimal(); got != tc.want {
+			t.Errorf("%d %s Decimal() = %q; want %q", tc.m.Amount, tc.m.Currency, got, tc.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	total, err := Sum(New(1250, "USD"), New(199, "USD").Mul(3))
+	if err != nil || total != New(1847, "USD") {
+		t.Fatalf("Sum = %v, %v", total, err)
+	}
+	if _, err := Sum(New(1, "USD"), New(1, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("mixed Sum: err=%v; want ErrCurrencyMismatch", err)
+	}
+	if d, _ := New(500, "USD").Sub(New(120, "USD")); d != New(380, "USD") {
+		t.Errorf("Sub = %v", d)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b, _ := json.Marshal(New(1250, "USD"))
+	if string(b) != `{"amount":"12.50","currency":"USD"}` {
+		t.Errorf("Marshal = %s", b)
+	}
+	for in, want := range map[string]Money{
+		`{"amount":"12.50","currency":"EUR"}`: New(1250, "EUR"),
+		`{"amount":12.5,"currency":"EUR"}`:    New(1250, "EUR"),
+		`{"amount":"3"}`:                      New(300, DefaultCurrency),
+		`15.0`:                                New(1500, DefaultCurrency), // legacy float price
+		`2.99`:                                New(299, DefaultCurrency),
+		`"2.99"`:                              New(299, DefaultCurrency),
+		`1.5e1`:                               New(1500, DefaultCurrency),
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err != nil || m != want {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v", in, m, err, want)
+		}
+	}
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":"1.999","currency":"USD"}`), &m); err == nil {
+		t.Error("Unmarshal of sub-cent amount succeeded")
+	}
+}
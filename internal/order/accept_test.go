@@ -0,0 +1,76 @@
+package order
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestAcceptOnlyPending(t *testing.T) {
+    e := needDB(t)
+    sh := e.newShop(t, 5)
+    o := e.place(t, sh)
+
+    if err := e.svc.Accept(o.ID, o.UserEmail); !errors.Is(err, ErrForbidden) {
+        t.Fatalf("accept by the buyer: err = %v, want ErrForbidden", err)
+    }
+    if err := e.svc.Accept(o.ID, e.newShop(t, 1).seller.ID); !errors.Is(err, ErrForbidden) {
+        t.Fatalf("accept by another seller: err = %v, want ErrForbidden", err)
+    }
+    if got := e.stock(t, sh); got != 5 {
+        t.Fatalf("stock after forbidden accepts = %d, want 5", got)
+    }
+
+    if err := e.svc.Accept(o.ID, sh.seller.ID); err != nil {
+        t.Fatal(err)
+    }
+    code := e.order(t, o.ID).PickupCode
+    if got, want := e.stock(t, sh), 4; got != want {
+        t.Fatalf("stock after accept = %d, want %d", got, want)
+    }
+    if got, want := e.booked(t, sh), 1; got != want {
+        t.Fatalf("slot bookings after accept = %d, want %d", got, want)
+    }
+
+    // again while accepted, and once it's ready
+    for _, step := range []string{StatusAccepted, StatusReady} {
+        if step == StatusReady {
+            if _, err := e.svc.Ready(o.ID, sh.seller.ID); err != nil {
+                t.Fatal(err)
+            }
+        }
+        err := e.svc.Accept(o.ID, sh.seller.ID)
+        if !errors.Is(err, ErrInvalidStatus) {
+            t.Fatalf("accept of %s order: err = %v, want ErrInvalidStatus", step, err)
+        }
+        got := e.order(t, o.ID)
+        if got.Status != step || got.PickupCode != code {
+            t.Errorf("accept of %s order left status %s, code changed %v", step, got.Status, got.PickupCode != code)
+        }
+        if got := e.stock(t, sh); got != 4 {
+            t.Errorf("accept of %s order: stock = %d, want 4", step, got)
+        }
+        if got := e.booked(t, sh); got != 1 {
+            t.Errorf("accept of %s order: slot bookings = %d, want 1", step, got)
+        }
+    }
+}
+
+func TestAcceptClosedOrder(t *testing.T) {
+    e := needDB(t)
+    sh := e.newShop(t, 5)
+    for _, status := range []string{StatusCancelled, StatusRejected, StatusExpired} {
+        o := e.place(t, sh)
+        if err := e.db.Model(&Order{}).Where("id = ?", o.ID).Update("status", status).Error; err != nil {
+            t.Fatal(err)
+        }
+        if err := e.svc.Accept(o.ID, sh.seller.ID); !errors.Is(err, ErrInvalidStatus) {
+            t.Errorf("accept of %s order: err = %v, want ErrInvalidStatus", status, err)
+        }
+        if got := e.order(t, o.ID).Status; got != status {
+            t.Errorf("accept of %s order moved it to %s", status, got)
+        }
+    }
+    if got := e.stock(t, sh); got != 5 {
+        t.Errorf("stock = %d, want 5", got)
+    }
+}
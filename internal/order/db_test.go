@@ -0,0 +1,193 @@
+package order
+
+import (
+    "fmt"
+    "net/http"
+    "os"
+    "testing"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/stdlib"
+    "gorm.io/datatypes"
+    "gorm.io/driver/postgres"
+    "gorm.io/gorm"
+    "gorm.io/gorm/logger"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/ledger"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+// Tests that need Postgres run against TEST_DATABASE_URL, in a schema of
+// their own that is dropped afterwards, and are skipped without it.
+var testEnv *dbEnv
+
+type dbEnv struct {
+    db  *gorm.DB
+    svc *postgresService
+    gw  *payment.FakeGateway
+    // webhooks the gateway sent, oldest first
+    sent []webhook
+}
+
+type webhook struct {
+    payload []byte
+    header  http.Header
+}
+
+func TestMain(m *testing.M) {
+    // nothing listens to events in tests
+    go func() {
+        for range event.Bus {
+        }
+    }()
+
+    dsn := os.Getenv("TEST_DATABASE_URL")
+    if dsn == "" {
+        os.Exit(m.Run())
+    }
+    env, drop, err := openTestEnv(dsn)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "order tests:", err)
+        os.Exit(1)
+    }
+    testEnv = env
+    code := m.Run()
+    drop()
+    os.Exit(code)
+}
+
+func openTestEnv(dsn string) (*dbEnv, func(), error) {
+    schema := "order_test_" + uuid.NewString()[:8]
+    cfg, err := pgx.ParseConfig(dsn)
+    if err != nil {
+        return nil, nil, err
+    }
+    // public too, where pg_trgm may already be installed
+    cfg.RuntimeParams["search_path"] = schema + ",public"
+    db, err := gorm.Open(postgres.New(postgres.Config{Conn: stdlib.OpenDB(*cfg)}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+        return nil, nil, err
+    }
+    drop := func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") }
+
+    for _, migrate := range []func(*gorm.DB) error{
+        seller.Migrate, listing.Migrate, pickup.Migrate, payment.Migrate,
+        tax.Migrate, promo.Migrate, ledger.Migrate, Migrate,
+    } {
+        if err := migrate(db); err != nil {
+            drop()
+            return nil, nil, err
+        }
+    }
+    gw := payment.NewFakeGateway("test")
+    payments := payment.NewPostgresService(db, gw)
+    books := ledger.NewPostgresService(db, ledger.DefaultRates)
+    env := &dbEnv{db: db, svc: NewPostgresService(db, payments, books).(*postgresService), gw: gw}
+    gw.OnEvent = func(payload []byte, signature string) {
+        h := http.Header{}
+        h.Set(payment.SignatureHeader, signature)
+        env.sent = append(env.sent, webhook{payload, h})
+    }
+    return env, drop, nil
+}
+
+func needDB(t *testing.T) *dbEnv {
+    t.Helper()
+    if testEnv == nil {
+        t.Skip("TEST_DATABASE_URL not set")
+    }
+    return testEnv
+}
+
+// shop is a seller with one listing and a pickup slot a week from now.
+type shop struct {
+    seller  seller.Seller
+    listing listing.Listing
+    window  pickup.Window
+    date    string
+}
+
+func (e *dbEnv) newShop(t *testing.T, stock int) *shop {
+    t.Helper()
+    id := uuid.NewString()
+    sh := &shop{seller: seller.Seller{ID: id, Name: "Test Kitchen", Email: id + "@example.com", Password: "x", Phone: "555-0100"}}
+    if err := e.db.Create(&sh.seller).Error; err != nil {
+        t.Fatal(err)
+    }
+    sh.listing = listing.Listing{SellerID: id, Title: "Dumplings", Price: money.New(1200, "CAD"), Available: true, PortionSize: 1, LeftSize: stock}
+    if err := listing.NewPostgresService(e.db).Create(&sh.listing); err != nil {
+        t.Fatal(err)
+    }
+    day := time.Now().In(pickup.Location()).AddDate(0, 0, 7)
+    sh.window = pickup.Window{SellerID: id, Weekday: day.Weekday(), Start: 17 * 60, End: 19 * 60, Capacity: 5}
+    if err := pickup.NewPostgresService(e.db).CreateWindow(&sh.window); err != nil {
+        t.Fatal(err)
+    }
+    sh.date = day.Format(pickup.DateLayout)
+    return sh
+}
+
+// place places a pending order for one portion of the shop's listing.
+func (e *dbEnv) place(t *testing.T, sh *shop) *Order {
+    t.Helper()
+    o := &Order{
+        UserEmail:      "buyer@example.com",
+        SellerID:       sh.seller.ID,
+        ListingIDs:     datatypes.JSON(fmt.Sprintf("[%q]", sh.listing.ID)),
+        PickupWindowID: sh.window.ID,
+        PickupDate:     sh.date,
+        PaymentMethod:  "tok_visa",
+    }
+    if err := e.svc.Create(o); err != nil {
+        t.Fatal(err)
+    }
+    return o
+}
+
+func (e *dbEnv) order(t *testing.T, id string) *Order {
+    t.Helper()
+    var o Order
+    if err := e.db.Preload("Lines").First(&o, "id = ?", id).Error; err != nil {
+        t.Fatal(err)
+    }
+    return &o
+}
+
+func (e *dbEnv) stock(t *testing.T, sh *shop) int {
+    t.Helper()
+    var l listing.Listing
+    if err := e.db.First(&l, "id = ?", sh.listing.ID).Error; err != nil {
+        t.Fatal(err)
+    }
+    return l.LeftSize
+}
+
+func (e *dbEnv) booked(t *testing.T, sh *shop) int {
+    t.Helper()
+    var b pickup.SlotBooking
+    if err := e.db.Where("window_id = ? AND date = ?", sh.window.ID, sh.date).Limit(1).Find(&b).Error; err != nil {
+        t.Fatal(err)
+    }
+    return b.Booked
+}
+
+func (e *dbEnv) payment(t *testing.T, orderID string) *payment.Payment {
+    t.Helper()
+    var p payment.Payment
+    if err := e.db.First(&p, "order_id = ?", orderID).Error; err != nil {
+        t.Fatal(err)
+    }
+    return &p
+}
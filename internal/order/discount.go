@@ -0,0 +1,81 @@
+package order
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+)
+
+// applyPromo checks a promotion code against prepared orders placed
+// together and spreads its discount over their lines, giving each order
+// it touches a discount line. It returns nil if there's no code. Usage
+// limits are checked when the orders are inserted, see redeem.
+func (s *postgresService) applyPromo(code string, orders []*Order) (*promo.Promotion, error) {
+    if code == "" {
+        return nil, nil
+    }
+    p, err := promo.Find(s.db, code)
+    if err != nil {
+        return nil, err
+    }
+    var items []promo.Item
+    for _, o := range orders {
+        for _, l := range o.Lines {
+            items = append(items, promo.Item{SellerID: o.SellerID, ListingID: l.ListingID, Amount: l.Total})
+        }
+    }
+    off, err := p.Discount(items, time.Now())
+    if err != nil {
+        return nil, err
+    }
+
+    i := 0
+    for _, o := range orders {
+        var sum int64
+        for j := range o.Lines {
+            o.Lines[j].Discount = off[i]
+            sum += off[i].Amount
+            i++
+        }
+        if sum > 0 {
+            o.Discounts = []promo.Line{{
+                ID:          uuid.NewString(),
+                OrderID:     o.ID,
+                PromotionID: p.ID,
+                Code:        p.Code,
+                Description: p.Description,
+                Funding:     p.Funding,
+                Amount:      money.New(sum, o.Subtotal.Currency),
+            }}
+        }
+    }
+    return p, nil
+}
+
+// redeem records the promotion's use by the orders it discounted, within
+// the transaction inserting them.
+func redeem(tx *gorm.DB, p *promo.Promotion, userEmail string, orders []*Order) error {
+    if p == nil {
+        return nil
+    }
+    var rs []promo.Redemption
+    for _, o := range orders {
+        for _, d := range o.Discounts {
+            rs = append(rs, promo.Redemption{OrderID: o.ID, GroupID: o.GroupID, Amount: d.Amount})
+        }
+    }
+    return promo.Redeem(tx, p.ID, userEmail, rs)
+}
+
+// discountTotal sums discount lines.
+func discountTotal(lines []promo.Line, currency string) money.Money {
+    sum := money.New(0, currency)
+    for _, l := range lines {
+        sum.Amount += l.Amount.Amount
+    }
+    return sum
+}
@@ -0,0 +1,175 @@
+package order
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "time"
+
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+)
+
+// expiryActor is recorded as the actor of the expiry worker's refunds and
+// stock returns.
+const expiryActor = "system:order-expiry"
+
+// Timeouts are the order deadlines the expiry worker enforces.
+type Timeouts struct {
+    Accept time.Duration // from placement until the seller must accept
+    Pickup time.Duration // from ready until the buyer must pick up
+}
+
+// DefaultTimeouts are used when no environment overrides are set.
+var DefaultTimeouts = Timeouts{Accept: 30 * time.Minute, Pickup: time.Hour}
+
+// TimeoutsFromEnv reads ORDER_ACCEPT_TIMEOUT and ORDER_PICKUP_TIMEOUT (Go
+// durations), falling back to DefaultTimeouts for unset or invalid values.
+func TimeoutsFromEnv() Timeouts {
+    t := DefaultTimeouts
+    if d, err := time.ParseDuration(os.Getenv("ORDER_ACCEPT_TIMEOUT")); err == nil && d > 0 {
+        t.Accept = d
+    }
+    if d, err := time.ParseDuration(os.Getenv("ORDER_PICKUP_TIMEOUT")); err == nil && d > 0 {
+        t.Pickup = d
+    }
+    return t
+}
+
+// ExpiryIntervalFromEnv reads ORDER_EXPIRY_INTERVAL (a Go duration,
+// default 1m).
+func ExpiryIntervalFromEnv() time.Duration {
+    if d, err := time.ParseDuration(os.Getenv("ORDER_EXPIRY_INTERVAL")); err == nil && d > 0 {
+        return d
+    }
+    return time.Minute
+}
+
+// StartExpiryWorker expires overdue orders every interval until ctx is
+// cancelled, see Service.ExpireOverdue.
+func StartExpiryWorker(ctx context.Context, svc Service, timeouts Timeouts, interval time.Duration) {
+    go func() {
+        t := time.NewTicker(interval)
+        defer t.Stop()
+        for {
+            if _, err := svc.ExpireOverdue(time.Now(), timeouts); err != nil {
+                log.Printf("order expiry: %v", err)
+            }
+            select {
+            case <-ctx.Done():
+                return
+            case <-t.C:
+            }
+        }
+    }()
+}
+
+func (s *postgresService) ExpireOverdue(now time.Time, t Timeouts) ([]Order, error) {
+    acceptBy := now.Add(-t.Accept)
+    pickupBy := now.Add(-t.Pickup)
+    var ids []string
+    err := s.db.Model(&Order{}).
+        Where("(status = ? AND created_at <= ?) OR (status = ? AND ready_at <= ?)",
+            StatusPending, acceptBy.Unix(), StatusReady, pickupBy).
+        Order("created_at").Pluck("id", &ids).Error
+    if err != nil {
+        return nil, err
+    }
+
+    // one transaction per order, so one that fails doesn't hold up the rest
+    var done []Order
+    var errs []error
+    for _, id := range ids {
+        o, err := s.expire(id, acceptBy, pickupBy)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("order %s: %w", id, err))
+            continue
+        }
+        if o != nil {
+            done = append(done, *o)
+        }
+    }
+    return done, errors.Join(errs...)
+}
+
+// expire moves one overdue order to expired or no_show. It returns nil if
+// the order moved on in the meantime.
+func (s *postgresService) expire(id string, acceptBy, pickupBy time.Time) (*Order, error) {
+    var o *Order
+    var refund *payment.Refund
+    var restocked []listing.RestockNotice
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        switch {
+        case o.Status == StatusPending && o.CreatedAt <= acceptBy.Unix():
+            // the seller never accepted: release the hold
+            if _, err := s.payments.Void(tx, o.ID); err != nil && !errors.Is(err, payment.ErrPaymentNotFound) {
+                return err
+            }
+            if err := promo.Release(tx, o.ID); err != nil {
+                return err
+            }
+            o.Status = StatusExpired
+        case o.Status == StatusReady && o.ReadyAt != nil && !o.ReadyAt.After(pickupBy):
+            if refund, restocked, err = s.giveBack(tx, o, expiryActor, "not picked up in time"); err != nil {
+                return err
+            }
+            o.Status = StatusNoShow
+        default:
+            o = nil
+            return nil
+        }
+        return tx.Model(o).Update("status", o.Status).Error
+    })
+    if err != nil || o == nil {
+        return nil, err
+    }
+
+    o.Payment, _ = s.payments.ForOrder(o.ID)
+    events := []event.Event{{Type: "OrderExpired", Data: *o}}
+    if o.Status == StatusNoShow {
+        events[0].Type = "OrderNoShow"
+    }
+    if refund != nil {
+        events = append(events, event.Event{Type: "OrderRefunded", Data: RefundNotice{Order: *o, Refund: *refund}})
+    }
+    for _, n := range restocked {
+        events = append(events, event.Event{Type: "ListingRestocked", Data: n})
+    }
+    emitAll(events)
+    return o, nil
+}
+
+func (s *postgresService) Ready(id, sellerID string) (*Order, error) {
+    var o *Order
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        if o.SellerID != sellerID {
+            return ErrForbidden
+        }
+        if o.Status != StatusAccepted {
+            return fmt.Errorf("%w: only accepted orders can be marked ready, this one is %s", ErrInvalidStatus, o.Status)
+        }
+        now := time.Now()
+        o.Status, o.ReadyAt = StatusReady, &now
+        return tx.Model(o).Updates(map[string]interface{}{"status": o.Status, "ready_at": now}).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    o.Payment, _ = s.payments.ForOrder(o.ID)
+    emitAll([]event.Event{{Type: "OrderReady", Data: *o}})
+    return o, nil
+}
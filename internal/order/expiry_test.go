@@ -0,0 +1,104 @@
+package order
+
+import (
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
+func TestTimeoutsFromEnv(t *testing.T) {
+    t.Setenv("ORDER_ACCEPT_TIMEOUT", "")
+    t.Setenv("ORDER_PICKUP_TIMEOUT", "")
+    if got := TimeoutsFromEnv(); got != DefaultTimeouts {
+        t.Errorf("unset: %+v, want %+v", got, DefaultTimeouts)
+    }
+
+    t.Setenv("ORDER_ACCEPT_TIMEOUT", "10m")
+    t.Setenv("ORDER_PICKUP_TIMEOUT", "-5m")
+    want := Timeouts{Accept: 10 * time.Minute, Pickup: DefaultTimeouts.Pickup}
+    if got := TimeoutsFromEnv(); got != want {
+        t.Errorf("got %+v, want %+v", got, want)
+    }
+}
+
+// expireAt runs ExpireOverdue at now and returns the order with id if it
+// was expired.
+func expireAt(t *testing.T, e *dbEnv, now time.Time, id string) *Order {
+    t.Helper()
+    done, err := e.svc.ExpireOverdue(now, DefaultTimeouts)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i := range done {
+        if done[i].ID == id {
+            return &done[i]
+        }
+    }
+    return nil
+}
+
+func TestExpireUnaccepted(t *testing.T) {
+    e := needDB(t)
+    sh := e.newShop(t, 5)
+    o := e.place(t, sh)
+
+    if got := expireAt(t, e, time.Now(), o.ID); got != nil {
+        t.Fatalf("expired a fresh order: %+v", got)
+    }
+    got := expireAt(t, e, time.Now().Add(DefaultTimeouts.Accept+time.Minute), o.ID)
+    if got == nil || got.Status != StatusExpired {
+        t.Fatalf("overdue pending order: got %+v, want expired", got)
+    }
+    if s := e.order(t, o.ID).Status; s != StatusExpired {
+        t.Errorf("stored status = %s, want %s", s, StatusExpired)
+    }
+    if p := e.payment(t, o.ID); p.Status != payment.StatusVoided {
+        t.Errorf("payment = %s, want %s", p.Status, payment.StatusVoided)
+    }
+    if got := e.stock(t, sh); got != 5 {
+        t.Errorf("stock = %d, want 5", got)
+    }
+    if got := e.booked(t, sh); got != 0 {
+        t.Errorf("slot bookings = %d, want 0", got)
+    }
+}
+
+func TestExpireNoShow(t *testing.T) {
+    e := needDB(t)
+    sh := e.newShop(t, 5)
+    o := e.place(t, sh)
+    if err := e.svc.Accept(o.ID, sh.seller.ID); err != nil {
+        t.Fatal(err)
+    }
+
+    // accepted orders wait for the seller however long it takes
+    late := time.Now().Add(DefaultTimeouts.Accept + DefaultTimeouts.Pickup + time.Minute)
+    if got := expireAt(t, e, late, o.ID); got != nil {
+        t.Fatalf("expired an accepted order: %+v", got)
+    }
+
+    if _, err := e.svc.Ready(o.ID, sh.seller.ID); err != nil {
+        t.Fatal(err)
+    }
+    if got := expireAt(t, e, time.Now(), o.ID); got != nil {
+        t.Fatalf("expired an order just made ready: %+v", got)
+    }
+    got := expireAt(t, e, time.Now().Add(DefaultTimeouts.Pickup+time.Minute), o.ID)
+    if got == nil || got.Status != StatusNoShow {
+        t.Fatalf("overdue ready order: got %+v, want no_show", got)
+    }
+    if s := e.order(t, o.ID).Status; s != StatusNoShow {
+        t.Errorf("stored status = %s, want %s", s, StatusNoShow)
+    }
+    p := e.payment(t, o.ID)
+    if p.Status != payment.StatusRefunded || p.Refunded != p.Captured {
+        t.Errorf("payment = %s, refunded %s of %s; want all refunded", p.Status, p.Refunded, p.Captured)
+    }
+    if got := e.stock(t, sh); got != 5 {
+        t.Errorf("stock = %d, want 5", got)
+    }
+    if got := e.booked(t, sh); got != 0 {
+        t.Errorf("slot bookings = %d, want 0", got)
+    }
+}
@@ -0,0 +1,172 @@
+package order
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sync"
+    "testing"
+    "time"
+
+    "gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
+// placeGroup checks out one portion from each shop together.
+func (e *dbEnv) placeGroup(t *testing.T, shops ...*shop) *Group {
+    t.Helper()
+    g := &Group{UserEmail: "buyer@example.com", PaymentMethod: "tok_visa"}
+    for _, sh := range shops {
+        g.Orders = append(g.Orders, Order{
+            SellerID:       sh.seller.ID,
+            ListingIDs:     datatypes.JSON(fmt.Sprintf("[%q]", sh.listing.ID)),
+            PickupWindowID: sh.window.ID,
+            PickupDate:     sh.date,
+        })
+    }
+    if err := e.svc.CreateGroup(g); err != nil {
+        t.Fatal(err)
+    }
+    return g
+}
+
+// webhooks returns the events of type typ the gateway sent for ref.
+func (e *dbEnv) webhooks(t *testing.T, ref, typ string) []webhook {
+    t.Helper()
+    var out []webhook
+    for _, w := range e.sent {
+        var ev payment.WebhookEvent
+        if err := json.Unmarshal(w.payload, &ev); err != nil {
+            t.Fatal(err)
+        }
+        if ev.Reference == ref && ev.Type == typ {
+            out = append(out, w)
+        }
+    }
+    return out
+}
+
+func TestGroupSharesOneAuthorization(t *testing.T) {
+    e := needDB(t)
+    g := e.placeGroup(t, e.newShop(t, 5), e.newShop(t, 5), e.newShop(t, 5))
+
+    a := e.payment(t, g.Orders[0].ID)
+    shares := make([]money.Money, len(g.Orders))
+    for i, o := range g.Orders {
+        p := e.payment(t, o.ID)
+        if p.Reference != a.Reference || p.GroupID == nil || *p.GroupID != g.ID {
+            t.Errorf("order %d: reference %s, group %v; want %s, %s", i, p.Reference, p.GroupID, a.Reference, g.ID)
+        }
+        if p.Status != payment.StatusAuthorized || p.Amount != o.Total {
+            t.Errorf("order %d: payment %s of %s, want %s of %s", i, p.Status, p.Amount, payment.StatusAuthorized, o.Total)
+        }
+        shares[i] = p.Amount
+    }
+    if sum, err := money.Sum(shares...); err != nil || sum != g.Total {
+        t.Errorf("shares add up to %s, %v; want %s", sum, err, g.Total)
+    }
+}
+
+func TestGroupCaptureOneWhileVoidingAnother(t *testing.T) {
+    e := needDB(t)
+    sa, sb := e.newShop(t, 5), e.newShop(t, 5)
+    g := e.placeGroup(t, sa, sb)
+    a, b := g.Orders[0], g.Orders[1]
+
+    var wg sync.WaitGroup
+    var acceptErr, cancelErr error
+    wg.Add(2)
+    go func() {
+        defer wg.Done()
+        acceptErr = e.svc.Accept(a.ID, sa.seller.ID)
+    }()
+    go func() {
+        defer wg.Done()
+        _, cancelErr = e.svc.Cancel(b.ID, "buyer@example.com")
+    }()
+    wg.Wait()
+    if acceptErr != nil || cancelErr != nil {
+        t.Fatalf("accept: %v, cancel: %v", acceptErr, cancelErr)
+    }
+
+    pa, pb := e.payment(t, a.ID), e.payment(t, b.ID)
+    if pa.Status != payment.StatusCaptured || pa.Captured != a.Total {
+        t.Errorf("accepted order: payment %s, captured %s; want all of %s", pa.Status, pa.Captured, a.Total)
+    }
+    if pb.Status != payment.StatusVoided || pb.Captured.Amount != 0 {
+        t.Errorf("cancelled order: payment %s, captured %s; want voided, nothing", pb.Status, pb.Captured)
+    }
+    // the captured share keeps the hold: nothing was voided at the provider
+    if got := e.webhooks(t, pa.Reference, payment.EventVoided); len(got) != 0 {
+        t.Errorf("hold voided %d times; want it kept", len(got))
+    }
+    if got := e.webhooks(t, pa.Reference, payment.EventCaptured); len(got) != 1 {
+        t.Errorf("%d captures at the provider; want 1", len(got))
+    }
+}
+
+func TestGroupHoldReleasedWithLastOrder(t *testing.T) {
+    e := needDB(t)
+    g := e.placeGroup(t, e.newShop(t, 5), e.newShop(t, 5))
+    a, b := g.Orders[0], g.Orders[1]
+    ref := e.payment(t, a.ID).Reference
+
+    if _, err := e.svc.Cancel(a.ID, "buyer@example.com"); err != nil {
+        t.Fatal(err)
+    }
+    if p := e.payment(t, a.ID); p.Status != payment.StatusVoided {
+        t.Errorf("cancelled order: payment %s, want %s", p.Status, payment.StatusVoided)
+    }
+    if got := e.webhooks(t, ref, payment.EventVoided); len(got) != 0 {
+        t.Fatalf("hold voided while another order still holds it")
+    }
+
+    // the other one is never accepted
+    if got := expireAt(t, e, time.Now().Add(DefaultTimeouts.Accept+time.Minute), b.ID); got == nil || got.Status != StatusExpired {
+        t.Fatalf("overdue order: got %+v, want expired", got)
+    }
+    if p := e.payment(t, b.ID); p.Status != payment.StatusVoided {
+        t.Errorf("expired order: payment %s, want %s", p.Status, payment.StatusVoided)
+    }
+    if got := e.webhooks(t, ref, payment.EventVoided); len(got) != 1 {
+        t.Errorf("hold voided %d times after the last order; want once", len(got))
+    }
+}
+
+func TestGroupHoldExpiresByWebhook(t *testing.T) {
+    e := needDB(t)
+    sa := e.newShop(t, 5)
+    g := e.placeGroup(t, sa, e.newShop(t, 5))
+    ref := e.payment(t, g.Orders[0].ID).Reference
+
+    if err := e.gw.Expire(ref); err != nil {
+        t.Fatal(err)
+    }
+    sent := e.webhooks(t, ref, payment.EventExpired)
+    if len(sent) != 1 {
+        t.Fatalf("gateway sent %d expiries; want 1", len(sent))
+    }
+    changed, err := e.svc.payments.HandleWebhook(sent[0].payload, sent[0].header)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(changed) != len(g.Orders) {
+        t.Errorf("webhook changed %d payments; want %d", len(changed), len(g.Orders))
+    }
+    for i, o := range g.Orders {
+        if p := e.payment(t, o.ID); p.Status != payment.StatusExpired {
+            t.Errorf("order %d: payment %s, want %s", i, p.Status, payment.StatusExpired)
+        }
+    }
+
+    // redelivery changes nothing
+    if changed, err := e.svc.payments.HandleWebhook(sent[0].payload, sent[0].header); err != nil || len(changed) != 0 {
+        t.Errorf("redelivery: %d changed, %v; want none", len(changed), err)
+    }
+    // and the orders can't be accepted on a lapsed hold
+    if err := e.svc.Accept(g.Orders[0].ID, sa.seller.ID); !errors.Is(err, payment.ErrInvalidState) {
+        t.Errorf("accept after expiry: err = %v; want ErrInvalidState", err)
+    }
+}
@@ -1,29 +1,51 @@
 package order
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"log"
 	"encoding/json"
 	"errors"
 	"github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/idempotency"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/money"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+	"github.com/albus-droid/Capstone-Project-Backend/internal/seller"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/datatypes"
 )
 
 var ErrOrderAlreadyExists = errors.New("order already exists")
 
-func RegisterRoutes(r *gin.Engine, svc Service) {
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service, idem idempotency.Store) {
 	grp := r.Group("/orders")
 	grp.Use(auth.Middleware())
+	// creating orders and anything that moves money can be retried safely
+	// with an Idempotency-Key
+	keyed := idempotency.Middleware(idem, idempotency.DefaultTTL)
+	registerGroupRoutes(r, svc, keyed)
+	registerListRoutes(r, svc, sellers)
 
 	// ─────────────────────────────────────────────────────────────
 	// POST /orders – create an order
 	// ─────────────────────────────────────────────────────────────
-	grp.POST("", func(c *gin.Context) {
+	grp.POST("", keyed, func(c *gin.Context) {
 		var payload struct {
-			ListingIDs []string `json:"listingIds"`
-			SellerID   string   `json:"sellerId"`
-			Total      float64  `json:"total"`
+			ID             string      `json:"id"` // optional client-generated UUID
+			ListingIDs     []string    `json:"listingIds"`
+			SellerID       string      `json:"sellerId"`
+			Subtotal       money.Money `json:"subtotal"` // optional; checked against current prices
+			Total          money.Money `json:"total"`    // optional; checked against prices and taxes
+			PickupWindowID string      `json:"pickupWindowId" binding:"required"`
+			PickupDate     string      `json:"pickupDate" binding:"required"`
+			PaymentMethod  string      `json:"paymentMethod"` // provider card token
+			PromoCode      string      `json:"promoCode"`
 		}
 		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -31,23 +53,30 @@ func RegisterRoutes(r *gin.Engine, svc Service) {
 		}
 		raw, _ := json.Marshal(payload.ListingIDs)
 		email := c.GetString(string(auth.CtxEmailKey))
+		if payload.ID != "" {
+			if _, err := uuid.Parse(payload.ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a UUID"})
+				return
+			}
+		}
 		o := &Order{
+			ID:         payload.ID,
 			UserEmail:  email,
 			SellerID:   payload.SellerID,
 			ListingIDs: datatypes.JSON(raw),
+			Subtotal:   payload.Subtotal,
 			Total:      payload.Total,
+
+			PickupWindowID: payload.PickupWindowID,
+			PickupDate:     payload.PickupDate,
+			PaymentMethod:  payload.PaymentMethod,
+			PromoCode:      payload.PromoCode,
 		}
 		if err := svc.Create(o); err != nil {
-    		switch {
-    		case errors.Is(err, ErrOrderAlreadyExists):
-        		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-    		default:
-        		log.Printf("create order failed: %v", err)
-        		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-    		}
-    		return
-    	}
-    	c.JSON(http.StatusCreated, o) // return the order (or a message if you prefer)
+			createError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, o) // return the order (or a message if you prefer)
 	})
 
 	// ─────────────────────────────────────────────────────────────
@@ -77,28 +106,385 @@ func RegisterRoutes(r *gin.Engine, svc Service) {
 	})
 
 	// ─────────────────────────────────────────────────────────────
-	// PATCH /orders/:id/accept
+	// PATCH /orders/:id/accept – seller takes the order
 	// ─────────────────────────────────────────────────────────────
-	grp.PATCH("/:id/accept", func(c *gin.Context) {
-		id := c.Param("id")
-		email := c.GetString(string(auth.CtxEmailKey))
-		if err := svc.Accept(id, email); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	grp.PATCH("/:id/accept", seller.RequireSeller(sellers), keyed, func(c *gin.Context) {
+		if err := svc.Accept(c.Param("id"), seller.FromContext(c).ID); err != nil {
+			switch {
+			case errors.Is(err, ErrOrderNotFound), errors.Is(err, ErrForbidden), errors.Is(err, ErrInvalidStatus):
+				orderError(c, err)
+			default: // sold out, slot full, capture declined
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "order accepted"})
 	})
 
 	// ─────────────────────────────────────────────────────────────
-	// PATCH /orders/:id/complete
+	// PATCH /orders/:id/ready – seller has the order ready for pickup
 	// ─────────────────────────────────────────────────────────────
-	grp.PATCH("/:id/complete", func(c *gin.Context) {
-		id := c.Param("id")
+	grp.PATCH("/:id/ready", seller.RequireSeller(sellers), func(c *gin.Context) {
+		o, err := svc.Ready(c.Param("id"), seller.FromContext(c).ID)
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// GET /orders/:id/pickup-code – the buyer's code for collecting
+	// ─────────────────────────────────────────────────────────────
+	grp.GET("/:id/pickup-code", func(c *gin.Context) {
+		code, err := svc.PickupCode(c.Param("id"), c.GetString(string(auth.CtxEmailKey)))
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, code)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// POST /orders/:id/pickup-code – the buyer replaces their code
+	// ─────────────────────────────────────────────────────────────
+	grp.POST("/:id/pickup-code", func(c *gin.Context) {
+		code, err := svc.RotatePickupCode(c.Param("id"), c.GetString(string(auth.CtxEmailKey)))
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, code)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// POST /orders/:id/handoff – seller completes the order with the
+	// buyer's pickup code
+	// ─────────────────────────────────────────────────────────────
+	grp.POST("/:id/handoff", seller.RequireSeller(sellers), func(c *gin.Context) {
+		var body struct {
+			Code string `json:"code" binding:"required"` // typed code or scanned QR payload
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		o, err := svc.Handoff(c.Param("id"), seller.FromContext(c).ID, body.Code)
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// PATCH /orders/:id/cancel – buyer withdraws a pending order
+	// ─────────────────────────────────────────────────────────────
+	grp.PATCH("/:id/cancel", keyed, func(c *gin.Context) {
+		o, err := svc.Cancel(c.Param("id"), c.GetString(string(auth.CtxEmailKey)))
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// PATCH /orders/:id/reject – seller turns an order down
+	// ─────────────────────────────────────────────────────────────
+	grp.PATCH("/:id/reject", seller.RequireSeller(sellers), keyed, func(c *gin.Context) {
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if body.Reason == "" {
+			body.Reason = "rejected by seller"
+		}
+		sl := seller.FromContext(c)
+		o, err := svc.Reject(c.Param("id"), sl.ID, sl.Email, body.Reason)
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// POST /orders/:id/refunds – partial refund by the seller or an admin
+	// ─────────────────────────────────────────────────────────────
+	grp.POST("/:id/refunds", keyed, func(c *gin.Context) {
+		o, ok := managedOrder(c, svc, sellers)
+		if !ok {
+			return
+		}
+		var in RefundInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		refund, err := svc.Refund(o.ID, in, c.GetString(string(auth.CtxEmailKey)))
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, refund)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// GET /orders/:id/refunds – buyer, seller or admin
+	// ─────────────────────────────────────────────────────────────
+	grp.GET("/:id/refunds", func(c *gin.Context) {
+		o, err := svc.GetByID(c.Param("id"))
+		if err != nil {
+			orderError(c, err)
+			return
+		}
+		if o.UserEmail != c.GetString(string(auth.CtxEmailKey)) && !canManage(c, sellers, o) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "no access"})
+			return
+		}
+		list, err := svc.Refunds(o.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+}
+
+// registerGroupRoutes adds checkout groups: orders from several sellers
+// placed at once with one payment.
+func registerGroupRoutes(r *gin.Engine, svc Service, keyed gin.HandlerFunc) {
+	grp := r.Group("/order-groups")
+	grp.Use(auth.Middleware())
+
+	// ─────────────────────────────────────────────────────────────
+	// POST /order-groups – place one order per seller
+	// ─────────────────────────────────────────────────────────────
+	grp.POST("", keyed, func(c *gin.Context) {
+		var payload struct {
+			ID     string `json:"id"` // optional client-generated UUID
+			Orders []struct {
+				SellerID       string      `json:"sellerId" binding:"required"`
+				ListingIDs     []string    `json:"listingIds" binding:"required"`
+				Subtotal       money.Money `json:"subtotal"` // optional; checked against current prices
+				Total          money.Money `json:"total"`    // optional; checked against prices and taxes
+				PickupWindowID string      `json:"pickupWindowId" binding:"required"`
+				PickupDate     string      `json:"pickupDate" binding:"required"`
+			} `json:"orders" binding:"required,min=1,dive"`
+			Total         money.Money `json:"total"`         // optional, as for orders
+			PaymentMethod string      `json:"paymentMethod"` // provider card token
+			PromoCode     string      `json:"promoCode"`     // applies to the group as a whole
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if payload.ID != "" {
+			if _, err := uuid.Parse(payload.ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a UUID"})
+				return
+			}
+		}
+		g := &Group{
+			ID:            payload.ID,
+			UserEmail:     c.GetString(string(auth.CtxEmailKey)),
+			Total:         payload.Total,
+			PaymentMethod: payload.PaymentMethod,
+			PromoCode:     payload.PromoCode,
+		}
+		for _, in := range payload.Orders {
+			raw, _ := json.Marshal(in.ListingIDs)
+			g.Orders = append(g.Orders, Order{
+				SellerID:       in.SellerID,
+				ListingIDs:     datatypes.JSON(raw),
+				Subtotal:       in.Subtotal,
+				Total:          in.Total,
+				PickupWindowID: in.PickupWindowID,
+				PickupDate:     in.PickupDate,
+			})
+		}
+		if err := svc.CreateGroup(g); err != nil {
+			createError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, g)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// GET /order-groups/:id – the buyer's group with its orders
+	// ─────────────────────────────────────────────────────────────
+	grp.GET("/:id", func(c *gin.Context) {
+		g, err := svc.GetGroup(c.Param("id"))
+		if errors.Is(err, ErrGroupNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 		email := c.GetString(string(auth.CtxEmailKey))
-		if err := svc.Complete(id, email); err != nil {
+		if g.UserEmail != email && !auth.IsAdmin(email) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "no access"})
+			return
+		}
+		c.JSON(http.StatusOK, g)
+	})
+}
+
+// registerListRoutes adds the seller's and admins' paged order lists.
+func registerListRoutes(r *gin.Engine, svc Service, sellers seller.Service) {
+	// ─────────────────────────────────────────────────────────────
+	// GET /sellers/me/orders – the seller's orders, newest first
+	// ─────────────────────────────────────────────────────────────
+	r.GET("/sellers/me/orders", auth.Middleware(), seller.RequireSeller(sellers), func(c *gin.Context) {
+		q, err := queryFromRequest(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		page, err := svc.ListBySeller(seller.FromContext(c).ID, q)
+		writePage(c, page, err)
+	})
+
+	// ─────────────────────────────────────────────────────────────
+	// GET /admin/orders – all orders, also filterable by seller and buyer
+	// ─────────────────────────────────────────────────────────────
+	r.GET("/admin/orders", auth.Middleware(), auth.RequireAdmin(), func(c *gin.Context) {
+		q, err := queryFromRequest(c)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "order completed"})
+		q.SellerID, q.UserEmail = c.Query("sellerId"), c.Query("buyer")
+		page, err := svc.Search(q)
+		writePage(c, page, err)
 	})
 }
+
+// queryFromRequest reads the list filters: status (repeated or
+// comma-separated), from and to (RFC 3339 or YYYY-MM-DD), listingId,
+// limit and cursor. A to date includes that whole day.
+func queryFromRequest(c *gin.Context) (Query, error) {
+	q := Query{ListingID: c.Query("listingId"), Cursor: c.Query("cursor")}
+	for _, v := range c.QueryArray("status") {
+		for _, st := range strings.Split(v, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				q.Statuses = append(q.Statuses, st)
+			}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, raw); err != nil {
+				return q, fmt.Errorf("%s must be RFC 3339 or YYYY-MM-DD", p.name)
+			}
+			if p.name == "to" {
+				t = t.AddDate(0, 0, 1)
+			}
+		}
+		*p.dst = &t
+	}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return q, errors.New("limit must be a number")
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+func writePage(c *gin.Context, page *Page, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, page)
+	case errors.Is(err, ErrInvalidQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("list orders failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// createError writes the response for an error placing an order or group.
+func createError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrOrderAlreadyExists), errors.Is(err, pickup.ErrSlotFull), errors.Is(err, ErrTotalMismatch),
+		errors.Is(err, promo.ErrLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	case errors.Is(err, promo.ErrUnknownCode), errors.Is(err, promo.ErrNotApplicable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, pickup.ErrWindowNotFound), errors.Is(err, pickup.ErrInvalidSlot),
+		errors.Is(err, ErrNoListings), errors.Is(err, ErrUnknownListing), errors.Is(err, ErrMixedCurrency),
+		errors.Is(err, ErrDuplicateSeller):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("create order failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// canManage reports whether the caller is an admin or the order's seller.
+func canManage(c *gin.Context, sellers seller.Service, o *Order) bool {
+	email := c.GetString(string(auth.CtxEmailKey))
+	if auth.IsAdmin(email) {
+		return true
+	}
+	sl, err := sellers.GetByEmail(email)
+	return err == nil && sl.ID == o.SellerID
+}
+
+// managedOrder loads the :id order for its seller or an admin, writing the
+// error response otherwise.
+func managedOrder(c *gin.Context, svc Service, sellers seller.Service) (*Order, bool) {
+	o, err := svc.GetByID(c.Param("id"))
+	if err != nil {
+		orderError(c, err)
+		return nil, false
+	}
+	if !canManage(c, sellers, o) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the seller or an admin can do this"})
+		return nil, false
+	}
+	return o, true
+}
+
+// orderError writes the response for an error from the order lifecycle.
+func orderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "no access"})
+	case errors.Is(err, ErrInvalidStatus), errors.Is(err, payment.ErrInvalidState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRefund), errors.Is(err, payment.ErrInvalidAmount), errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWrongCode):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrHandoffLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+	default:
+		log.Printf("order update failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,184 @@
+package order
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "errors"
+    "fmt"
+    "math/big"
+    "net/url"
+    "strings"
+    "time"
+
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+)
+
+// MaxHandoffAttempts is how many wrong pickup codes a seller can enter for
+// an order before handoff is locked until the buyer issues a new code.
+const MaxHandoffAttempts = 5
+
+const pickupCodeDigits = 6
+
+var (
+    ErrWrongCode     = errors.New("wrong pickup code")
+    ErrHandoffLocked = errors.New("too many wrong pickup codes; the buyer must issue a new one")
+)
+
+// PickupCode is what the buyer shows when collecting an order: the code to
+// read out, and the same code as a QR payload for the seller to scan.
+type PickupCode struct {
+    OrderID      string `json:"orderId"`
+    Code         string `json:"code"`
+    QRPayload    string `json:"qrPayload"`
+    AttemptsLeft int    `json:"attemptsLeft"`
+}
+
+func newPickupCode() (string, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%0*d", pickupCodeDigits, n.Int64()), nil
+}
+
+// qrPayload is the text encoded in an order's pickup QR code.
+func qrPayload(orderID, code string) string {
+    return "pickup:" + orderID + "?code=" + code
+}
+
+// parseHandoffCode accepts either a typed code or a scanned QR payload,
+// returning the code. A payload for another order is rejected.
+func parseHandoffCode(orderID, input string) (string, error) {
+    input = strings.TrimSpace(input)
+    rest, ok := strings.CutPrefix(input, "pickup:")
+    if !ok {
+        return input, nil
+    }
+    id, query, _ := strings.Cut(rest, "?")
+    if id != orderID {
+        return "", fmt.Errorf("%w: QR code is for another order", ErrWrongCode)
+    }
+    q, err := url.ParseQuery(query)
+    if err != nil {
+        return "", fmt.Errorf("%w: unreadable QR code", ErrWrongCode)
+    }
+    return q.Get("code"), nil
+}
+
+func (o *Order) pickupCode() *PickupCode {
+    return &PickupCode{
+        OrderID:      o.ID,
+        Code:         o.PickupCode,
+        QRPayload:    qrPayload(o.ID, o.PickupCode),
+        AttemptsLeft: max(MaxHandoffAttempts-o.HandoffAttempts, 0),
+    }
+}
+
+func (s *postgresService) PickupCode(id, callerEmail string) (*PickupCode, error) {
+    var code *PickupCode
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        o, err := s.buyersAcceptedOrder(tx, id, callerEmail)
+        if err != nil {
+            return err
+        }
+        // orders accepted before codes existed get theirs now
+        if o.PickupCode == "" {
+            if err := setPickupCode(tx, o); err != nil {
+                return err
+            }
+        }
+        code = o.pickupCode()
+        return nil
+    })
+    return code, err
+}
+
+func (s *postgresService) RotatePickupCode(id, callerEmail string) (*PickupCode, error) {
+    var code *PickupCode
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        o, err := s.buyersAcceptedOrder(tx, id, callerEmail)
+        if err != nil {
+            return err
+        }
+        if err := setPickupCode(tx, o); err != nil {
+            return err
+        }
+        code = o.pickupCode()
+        return nil
+    })
+    return code, err
+}
+
+// buyersAcceptedOrder locks an order for its buyer, who can only see or
+// change its pickup code once it's accepted.
+func (s *postgresService) buyersAcceptedOrder(tx *gorm.DB, id, callerEmail string) (*Order, error) {
+    o, err := lockOrder(tx, id)
+    if err != nil {
+        return nil, err
+    }
+    if o.UserEmail != callerEmail {
+        return nil, ErrForbidden
+    }
+    if o.Status != StatusAccepted && o.Status != StatusReady {
+        return nil, fmt.Errorf("%w: pickup codes are for accepted orders, this one is %s", ErrInvalidStatus, o.Status)
+    }
+    return o, nil
+}
+
+// setPickupCode gives o a new code and clears its wrong attempts.
+func setPickupCode(tx *gorm.DB, o *Order) error {
+    code, err := newPickupCode()
+    if err != nil {
+        return err
+    }
+    o.PickupCode, o.HandoffAttempts = code, 0
+    return tx.Model(o).Updates(map[string]interface{}{"pickup_code": code, "handoff_attempts": 0}).Error
+}
+
+func (s *postgresService) Handoff(id, sellerID, code string) (*Order, error) {
+    var o *Order
+    wrong := false
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        if o.SellerID != sellerID {
+            return ErrForbidden
+        }
+        if o.Status != StatusAccepted && o.Status != StatusReady {
+            return fmt.Errorf("%w: only accepted or ready orders can be handed over, this one is %s", ErrInvalidStatus, o.Status)
+        }
+        if o.HandoffAttempts >= MaxHandoffAttempts {
+            return ErrHandoffLocked
+        }
+        given, err := parseHandoffCode(o.ID, code)
+        if err != nil || o.PickupCode == "" || subtle.ConstantTimeCompare([]byte(given), []byte(o.PickupCode)) != 1 {
+            // count the miss; the transaction commits so it sticks
+            wrong = true
+            o.HandoffAttempts++
+            return tx.Model(o).Update("handoff_attempts", o.HandoffAttempts).Error
+        }
+        // the seller has earned the order; it can be paid out
+        if err := s.books.PostCompletion(tx, o.ID); err != nil {
+            return err
+        }
+        now := time.Now()
+        o.Status, o.CompletedAt = StatusCompleted, &now
+        return tx.Model(o).Updates(map[string]interface{}{"status": o.Status, "completed_at": now}).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    if wrong {
+        if o.HandoffAttempts >= MaxHandoffAttempts {
+            return nil, ErrHandoffLocked
+        }
+        return nil, fmt.Errorf("%w: %d attempts left", ErrWrongCode, MaxHandoffAttempts-o.HandoffAttempts)
+    }
+    o.Payment, _ = s.payments.ForOrder(o.ID)
+    emitAll([]event.Event{{Type: "OrderCompleted", Data: *o}})
+    return o, nil
+}
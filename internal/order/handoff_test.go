@@ -0,0 +1,43 @@
+package order
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestNewPickupCode(t *testing.T) {
+    seen := make(map[string]bool)
+    for i := 0; i < 50; i++ {
+        code, err := newPickupCode()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(code) != pickupCodeDigits || strings.Trim(code, "0123456789") != "" {
+            t.Fatalf("code %q is not %d digits", code, pickupCodeDigits)
+        }
+        seen[code] = true
+    }
+    if len(seen) < 45 {
+        t.Errorf("only %d distinct codes in 50", len(seen))
+    }
+}
+
+func TestParseHandoffCode(t *testing.T) {
+    for _, tc := range []struct {
+        input string
+        want  string
+        err   error
+    }{
+        {"042137", "042137", nil},
+        {" 042137\n", "042137", nil},
+        {qrPayload("o1", "042137"), "042137", nil},
+        {qrPayload("o2", "042137"), "", ErrWrongCode},
+        {"pickup:o1?code=%zz", "", ErrWrongCode},
+    } {
+        got, err := parseHandoffCode("o1", tc.input)
+        if !errors.Is(err, tc.err) || got != tc.want {
+            t.Errorf("parseHandoffCode(%q) = %q, %v; want %q, %v", tc.input, got, err, tc.want, tc.err)
+        }
+    }
+}
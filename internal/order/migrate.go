@@ -3,6 +3,47 @@ package order
 
 import "gorm.io/gorm"
 
+// totalDDL moves totals from the old numeric dollars column into integer
+// cents; totals were always USD before currencies existed. Orders placed
+// before lines existed keep their total and have no lines.
+var totalDDL = []string{
+    `DO $$ BEGIN
+        IF EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'total') THEN
+            UPDATE orders SET total_amount = round(total * 100)::bigint, total_currency = 'USD';
+            ALTER TABLE orders DROP COLUMN total;
+        END IF;
+    END $$`,
+}
+
+// subtotalDDL gives orders from before taxes a subtotal equal to their
+// total, which it was.
+var subtotalDDL = []string{
+    `DO $$ BEGIN
+        IF EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'total_amount')
+           AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'subtotal_amount') THEN
+            ALTER TABLE orders ADD COLUMN subtotal_amount bigint NOT NULL DEFAULT 0,
+                               ADD COLUMN subtotal_currency char(3) NOT NULL DEFAULT 'USD';
+            UPDATE orders SET subtotal_amount = total_amount, subtotal_currency = total_currency;
+        END IF;
+    END $$`,
+}
+
 func Migrate(db *gorm.DB) error {
-    return db.AutoMigrate(&Order{})
+    for _, stmt := range subtotalDDL {
+        if err := db.Exec(stmt).Error; err != nil {
+            return err
+        }
+    }
+    if err := db.AutoMigrate(&Order{}, &Line{}, &Group{}); err != nil {
+        return err
+    }
+    for _, stmt := range totalDDL {
+        if err := db.Exec(stmt).Error; err != nil {
+            return err
+        }
+    }
+    return nil
 }
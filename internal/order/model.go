@@ -1,8 +1,16 @@
 package order
 
 import (
+    "errors"
+    "time"
+
     "gorm.io/gorm"
 	"gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
 )
 
 // Order is the GORM model for an order record
@@ -14,8 +22,150 @@ type Order struct {
     UserEmail  string         `json:"user_email" gorm:"type:varchar(100);not null;index"`
     SellerID   string         `json:"sellerId" gorm:"type:uuid;not null;index"`
     ListingIDs datatypes.JSON `json:"listingIds" gorm:"type:jsonb;not null;default:'[]'"`
-	Total      float64        `json:"total" gorm:"type:numeric;not null"`
-    CreatedAt  int64          `json:"createdAt" gorm:"autoCreateTime"`
-    Status     string         `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+    Total      money.Money    `json:"total" gorm:"embedded;embeddedPrefix:total_"` // Subtotal plus taxes not included in prices
+    Lines      []Line         `json:"lines,omitempty" gorm:"foreignKey:OrderID"`
+    CreatedAt  int64          `json:"createdAt" gorm:"autoCreateTime;index"`
+    Status     string         `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+    GroupID    *string        `json:"groupId,omitempty" gorm:"type:uuid;index"` // checkout group, for orders placed together
+
+    // taxes, worked out from the seller's tax profile when the order was
+    // placed; with TaxInclusive they are part of the line prices
+    Subtotal     money.Money `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"` // sum of Lines
+    Taxes        []tax.Line  `json:"taxes" gorm:"foreignKey:OrderID"`
+    TaxInclusive bool        `json:"taxInclusive" gorm:"not null;default:false"`
+
+    // promotion code the buyer entered, and what it took off; taxes are
+    // worked out on the discounted lines
+    PromoCode string       `json:"-" gorm:"-"`
+    Discounts []promo.Line `json:"discounts" gorm:"foreignKey:OrderID"`
+
+    // when the seller marked it ready, which starts the pickup timeout,
+    // and when it was handed over
+    ReadyAt     *time.Time `json:"readyAt,omitempty" gorm:"index"`
+    CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+    // pickup slot, see the pickup package
+    PickupWindowID string     `json:"pickupWindowId,omitempty" gorm:"type:uuid;index"`
+    PickupDate     string     `json:"pickupDate,omitempty" gorm:"type:varchar(10)"` // YYYY-MM-DD
+    PickupStart    *time.Time `json:"pickupStart,omitempty"`
+    PickupEnd      *time.Time `json:"pickupEnd,omitempty"`
+
+    // handoff, see handoff.go: set when the order is accepted, shown to
+    // the buyer only and entered by the seller to complete the order
+    PickupCode      string `json:"-" gorm:"type:varchar(12)"`
+    HandoffAttempts int    `json:"-" gorm:"not null;default:0"` // wrong codes entered
+
+    // card payment, authorized at placement and captured on accept;
+    // PaymentMethod is the provider token the buyer paid with
+    Payment       *payment.Payment `json:"payment,omitempty" gorm:"foreignKey:OrderID"`
+    PaymentMethod string           `json:"-" gorm:"-"`
     DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"` // optional soft-delete
 }
+
+// Line is one listing in an order, priced when the order was placed so
+// later price changes don't alter it. Repeating a listing ID in an order
+// raises its Quantity.
+type Line struct {
+    ID          string      `json:"id" gorm:"type:uuid;primaryKey"`
+    OrderID     string      `json:"-" gorm:"type:uuid;not null;index"`
+    ListingID   string      `json:"listingId" gorm:"type:uuid;not null"`
+    Title       string      `json:"title" gorm:"type:varchar(200);not null"`
+    Quantity    int         `json:"quantity" gorm:"not null"`
+    UnitPrice   money.Money `json:"unitPrice" gorm:"embedded;embeddedPrefix:unit_price_"`
+    Total       money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+    TaxCategory string      `json:"taxCategory,omitempty" gorm:"type:varchar(20);not null;default:''"` // the listing's, when ordered
+    Discount    money.Money `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`                 // the line's share of the order's discounts
+}
+
+func (Line) TableName() string { return "order_lines" }
+
+// Group is a checkout of orders from several sellers, placed together with
+// one payment authorization. Each seller gets their own order and accepts
+// or rejects it on its own; Status sums them up, see GroupStatus.
+type Group struct {
+    ID        string      `json:"id" gorm:"type:uuid;primaryKey"`
+    UserEmail string      `json:"user_email" gorm:"type:varchar(100);not null;index"`
+    Total     money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"` // sum of the orders
+    Orders    []Order     `json:"orders" gorm:"foreignKey:GroupID"`
+    Status    string      `json:"status" gorm:"-"`
+    CreatedAt int64       `json:"createdAt" gorm:"autoCreateTime"`
+
+    PaymentMethod string `json:"-" gorm:"-"`
+    PromoCode     string `json:"-" gorm:"-"`
+}
+
+func (Group) TableName() string { return "order_groups" }
+
+// Order statuses
+const (
+    StatusPending   = "pending"
+    StatusAccepted  = "accepted"
+    StatusReady     = "ready" // prepared and waiting for the buyer
+    StatusCompleted = "completed"
+    StatusCancelled = "cancelled" // by the buyer while pending
+    StatusRejected  = "rejected"  // by the seller
+    StatusExpired   = "expired"   // not accepted in time, see expiry.go
+    StatusNoShow    = "no_show"   // not picked up in time after it was ready
+
+    // groups only, see GroupStatus
+    StatusPartiallyAccepted  = "partially_accepted"
+    StatusPartiallyCompleted = "partially_completed"
+)
+
+// GroupStatus sums up the statuses of a group's orders. When they all
+// agree it's their status. Otherwise it's the furthest any order got,
+// marked partial: partially_completed if some were completed,
+// partially_accepted if some were accepted (ready counts as accepted). A
+// group none of whose orders were accepted is pending while any still
+// are, and rejected once none are left: turned down, cancelled or
+// expired.
+func GroupStatus(orders []Order) string {
+    if len(orders) == 0 {
+        return StatusPending
+    }
+    count := make(map[string]int)
+    for _, o := range orders {
+        count[o.Status]++
+    }
+    if count[orders[0].Status] == len(orders) {
+        return orders[0].Status
+    }
+    switch {
+    case count[StatusCompleted] > 0:
+        return StatusPartiallyCompleted
+    case count[StatusAccepted] > 0, count[StatusReady] > 0, count[StatusNoShow] > 0:
+        return StatusPartiallyAccepted
+    case count[StatusPending] > 0:
+        return StatusPending
+    default:
+        return StatusRejected
+    }
+}
+
+var (
+    ErrOrderNotFound   = errors.New("order not found")
+    ErrForbidden       = errors.New("forbidden")
+    ErrInvalidStatus   = errors.New("order status does not allow this")
+    ErrInvalidRefund   = errors.New("invalid refund")
+    ErrNoListings      = errors.New("no listings in order")
+    ErrUnknownListing  = errors.New("listing not found")
+    ErrMixedCurrency   = errors.New("all listings in an order must share a currency")
+    ErrTotalMismatch   = errors.New("total does not match current prices")
+    ErrGroupNotFound   = errors.New("order group not found")
+    ErrDuplicateSeller = errors.New("a group has one order per seller")
+)
+
+// RefundInput is a seller's or admin's partial refund: either an Amount,
+// or a LineID and Quantity of portions that were missing.
+type RefundInput struct {
+    Amount   *money.Money `json:"amount"`
+    LineID   string       `json:"lineId"`
+    Quantity int          `json:"quantity"` // defaults to 1 with lineId
+    Reason   string       `json:"reason" binding:"required"`
+}
+
+// RefundNotice is the data of an OrderRefunded event.
+type RefundNotice struct {
+    Order  Order
+    Refund payment.Refund
+}
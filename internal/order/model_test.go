@@ -0,0 +1,35 @@
+package order
+
+import "testing"
+
+func TestGroupStatus(t *testing.T) {
+    for _, tc := range []struct {
+        statuses []string
+        want     string
+    }{
+        {[]string{StatusPending, StatusPending}, StatusPending},
+        {[]string{StatusAccepted, StatusAccepted}, StatusAccepted},
+        {[]string{StatusAccepted, StatusPending}, StatusPartiallyAccepted},
+        {[]string{StatusAccepted, StatusRejected}, StatusPartiallyAccepted},
+        {[]string{StatusCompleted, StatusAccepted}, StatusPartiallyCompleted},
+        {[]string{StatusCompleted, StatusCancelled, StatusPending}, StatusPartiallyCompleted},
+        {[]string{StatusCompleted, StatusCompleted}, StatusCompleted},
+        {[]string{StatusPending, StatusRejected}, StatusPending},
+        {[]string{StatusCancelled, StatusCancelled}, StatusCancelled},
+        {[]string{StatusCancelled, StatusRejected}, StatusRejected},
+        {[]string{StatusReady, StatusReady}, StatusReady},
+        {[]string{StatusReady, StatusPending}, StatusPartiallyAccepted},
+        {[]string{StatusNoShow, StatusCompleted}, StatusPartiallyCompleted},
+        {[]string{StatusExpired, StatusPending}, StatusPending},
+        {[]string{StatusExpired, StatusCancelled}, StatusRejected},
+        {nil, StatusPending},
+    } {
+        orders := make([]Order, len(tc.statuses))
+        for i, st := range tc.statuses {
+            orders[i].Status = st
+        }
+        if got := GroupStatus(orders); got != tc.want {
+            t.Errorf("GroupStatus(%v) = %s, want %s", tc.statuses, got, tc.want)
+        }
+    }
+}
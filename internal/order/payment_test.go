@@ -0,0 +1,35 @@
+package order
+
+import (
+    "testing"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
+func TestPlaceAuthorizesAcceptCaptures(t *testing.T) {
+    e := needDB(t)
+    sh := e.newShop(t, 5)
+    o := e.place(t, sh)
+
+    p := e.payment(t, o.ID)
+    if p.Status != payment.StatusAuthorized || p.Amount != o.Total {
+        t.Fatalf("after placing: payment %s of %s, want %s of %s", p.Status, p.Amount, payment.StatusAuthorized, o.Total)
+    }
+    if got := e.stock(t, sh); got != 5 {
+        t.Errorf("stock after placing = %d, want 5", got)
+    }
+
+    if err := e.svc.Accept(o.ID, sh.seller.ID); err != nil {
+        t.Fatal(err)
+    }
+    p = e.payment(t, o.ID)
+    if p.Status != payment.StatusCaptured || p.Captured != o.Total {
+        t.Errorf("after accept: payment %s, captured %s, want all of %s", p.Status, p.Captured, o.Total)
+    }
+    if got := e.stock(t, sh); got != 4 {
+        t.Errorf("stock after accept = %d, want 4", got)
+    }
+    if got := e.booked(t, sh); got != 1 {
+        t.Errorf("slot bookings after accept = %d, want 1", got)
+    }
+}
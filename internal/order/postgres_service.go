@@ -2,19 +2,28 @@ package order
 
 import (
     "errors"
+    "fmt"
     "time"
-    "sort"
     "encoding/json"
     "github.com/google/uuid"
     "gorm.io/gorm"
     "gorm.io/datatypes"
+    "gorm.io/gorm/clause"
 
     "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/ledger"
     "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
 )
 
 type postgresService struct {
-    db *gorm.DB
+    db       *gorm.DB
+    payments payment.Service
+    books    ledger.Service
 }
 
 func parseListingIDs(data datatypes.JSON) ([]string, error) {
@@ -23,22 +32,50 @@ func parseListingIDs(data datatypes.JSON) ([]string, error) {
     return ids, err
 }
 
-func NewPostgresService(db *gorm.DB) Service {
-    return &postgresService{db: db}
+func NewPostgresService(db *gorm.DB, payments payment.Service, books ledger.Service) Service {
+    return &postgresService{db: db, payments: payments, books: books}
 }
 
 func (s *postgresService) Create(o *Order) error {
-    // 1) assign a fresh ID & timestamp
-    o.ID = uuid.NewString()
-    o.CreatedAt = time.Now().Unix()
-    o.Status = "pending"      // if not already set
+    if err := s.prepare(o); err != nil {
+        return err
+    }
+    promotion, err := s.applyPromo(o.PromoCode, []*Order{o})
+    if err != nil {
+        return err
+    }
+    if err := s.finishPricing(o); err != nil {
+        return err
+    }
 
-    // 2) insert—UUID collisions are practically impossible, so no pre‑check needed
-    if err := s.db.Create(o).Error; err != nil {
+    // insert the order and its lines—a client-chosen ID that's taken
+    // inserts nothing—redeem the promotion, and hold the total on the
+    // buyer's card; a decline or a used-up code rolls the order back
+    err = s.db.Transaction(func(tx *gorm.DB) error {
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Payment").Create(o)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return ErrOrderAlreadyExists
+        }
+        if err := redeem(tx, promotion, o.UserEmail, []*Order{o}); err != nil {
+            return err
+        }
+        p, err := s.payments.Authorize(tx, payment.AuthorizeRequest{
+            OrderID:       o.ID,
+            Amount:        o.Total,
+            Customer:      o.UserEmail,
+            PaymentMethod: o.PaymentMethod,
+        })
+        o.Payment = p
+        return err
+    })
+    if err != nil {
         return err
     }
 
-    // 3) emit the OrderPlaced event
+    // emit the OrderPlaced event
     go func(placed Order) {
         event.Bus <- event.Event{Type: "OrderPlaced", Data: placed}
     }(*o)
@@ -46,11 +83,207 @@ func (s *postgresService) Create(o *Order) error {
     return nil
 }
 
+// prepare readies a new order for insertion: it assigns an ID unless the
+// client chose one, checks the pickup slot, and prices the lines from the
+// listings rather than from what the client sent. Discounts, taxes and
+// the total follow in applyPromo and finishPricing.
+func (s *postgresService) prepare(o *Order) error {
+    if o.ID == "" {
+        o.ID = uuid.NewString()
+    }
+    o.CreatedAt = time.Now().Unix()
+    o.Status = StatusPending
+
+    listingIDs, err := parseListingIDs(o.ListingIDs)
+    if err != nil {
+        return errors.New("invalid listing IDs")
+    }
+    slot, err := pickup.CheckSlot(s.db, o.SellerID, listingIDs, o.PickupWindowID, o.PickupDate)
+    if err != nil {
+        return err
+    }
+    o.PickupStart, o.PickupEnd = &slot.Start, &slot.End
+
+    var found []listing.Listing
+    if err := s.db.Where("id IN ?", listingIDs).Find(&found).Error; err != nil {
+        return err
+    }
+    byID := make(map[string]listing.Listing, len(found))
+    for _, l := range found {
+        byID[l.ID] = l
+    }
+    lines, subtotal, err := priceLines(o.ID, listingIDs, byID)
+    if err != nil {
+        return err
+    }
+    // a client subtotal or total is only a check that the buyer saw
+    // current prices
+    if o.Subtotal != (money.Money{}) && o.Subtotal != subtotal {
+        return fmt.Errorf("%w: expected subtotal %s", ErrTotalMismatch, subtotal)
+    }
+    o.Lines, o.Subtotal = lines, subtotal
+    return nil
+}
+
+// finishPricing works out a prepared order's taxes, on the lines less any
+// discount, and its total.
+func (s *postgresService) finishPricing(o *Order) error {
+    taxes, inclusive, err := s.taxLines(o.ID, o.SellerID, o.Lines)
+    if err != nil {
+        return err
+    }
+    total, err := o.Subtotal.Sub(discountTotal(o.Discounts, o.Subtotal.Currency))
+    if err != nil {
+        return err
+    }
+    if total, err = total.Add(tax.Added(taxes, total.Currency)); err != nil {
+        return err
+    }
+    if o.Total != (money.Money{}) && o.Total != total {
+        return fmt.Errorf("%w: expected %s", ErrTotalMismatch, total)
+    }
+    o.Total, o.Taxes, o.TaxInclusive = total, taxes, inclusive
+    return nil
+}
+
+// taxLines works out the taxes on an order's lines from its seller's tax
+// profile, and whether the seller's prices include them.
+func (s *postgresService) taxLines(orderID, sellerID string, lines []Line) ([]tax.Line, bool, error) {
+    var sl seller.Seller
+    if err := s.db.Select("id", "country", "region", "tax_inclusive").First(&sl, "id = ?", sellerID).Error; err != nil {
+        return nil, false, err
+    }
+    items := make([]tax.Item, len(lines))
+    for i, l := range lines {
+        items[i] = tax.Item{Category: l.TaxCategory, Amount: money.New(l.Total.Amount-l.Discount.Amount, l.Total.Currency)}
+    }
+    taxes, err := tax.Calculate(s.db, sl.Country, sl.Region, sl.TaxInclusive, items)
+    if err != nil {
+        return nil, false, err
+    }
+    for i := range taxes {
+        taxes[i].ID, taxes[i].OrderID = uuid.NewString(), orderID
+    }
+    return taxes, sl.TaxInclusive, nil
+}
+
+func (s *postgresService) CreateGroup(g *Group) error {
+    if len(g.Orders) == 0 {
+        return ErrNoListings
+    }
+    if g.ID == "" {
+        g.ID = uuid.NewString()
+    }
+    g.CreatedAt = time.Now().Unix()
+
+    sellers := make(map[string]bool, len(g.Orders))
+    orders := make([]*Order, len(g.Orders))
+    subtotals := make([]money.Money, len(g.Orders))
+    for i := range g.Orders {
+        o := &g.Orders[i]
+        if sellers[o.SellerID] {
+            return fmt.Errorf("%w: seller %s appears twice", ErrDuplicateSeller, o.SellerID)
+        }
+        sellers[o.SellerID] = true
+        o.ID, o.UserEmail, o.GroupID = "", g.UserEmail, &g.ID
+        if err := s.prepare(o); err != nil {
+            return err
+        }
+        orders[i], subtotals[i] = o, o.Subtotal
+    }
+    // one authorization means one currency
+    if _, err := money.Sum(subtotals...); errors.Is(err, money.ErrCurrencyMismatch) {
+        return fmt.Errorf("%w: %v", ErrMixedCurrency, err)
+    }
+    // a code applies to the checkout as a whole
+    promotion, err := s.applyPromo(g.PromoCode, orders)
+    if err != nil {
+        return err
+    }
+    totals := make([]money.Money, len(g.Orders))
+    for i, o := range orders {
+        if err := s.finishPricing(o); err != nil {
+            return err
+        }
+        totals[i] = o.Total
+    }
+    total, err := money.Sum(totals...)
+    if err != nil {
+        return err
+    }
+    if g.Total != (money.Money{}) && g.Total != total {
+        return fmt.Errorf("%w: expected %s", ErrTotalMismatch, total)
+    }
+    g.Total = total
+
+    // the group, its orders and the one hold covering them all go in
+    // together or not at all
+    err = s.db.Transaction(func(tx *gorm.DB) error {
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Orders").Create(g)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return ErrOrderAlreadyExists
+        }
+        if err := tx.Omit("Payment").Create(&g.Orders).Error; err != nil {
+            return err
+        }
+        if err := redeem(tx, promotion, g.UserEmail, orders); err != nil {
+            return err
+        }
+        shares := make([]payment.Share, len(g.Orders))
+        for i, o := range g.Orders {
+            shares[i] = payment.Share{OrderID: o.ID, Amount: o.Total}
+        }
+        ps, err := s.payments.AuthorizeGroup(tx, payment.AuthorizeRequest{
+            OrderID:       g.ID,
+            Amount:        g.Total,
+            Customer:      g.UserEmail,
+            PaymentMethod: g.PaymentMethod,
+        }, shares)
+        if err != nil {
+            return err
+        }
+        for i := range g.Orders {
+            g.Orders[i].Payment = &ps[i]
+        }
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    g.Status = GroupStatus(g.Orders)
+
+    // each seller hears about their own order
+    events := make([]event.Event, len(g.Orders))
+    for i, o := range g.Orders {
+        events[i] = event.Event{Type: "OrderPlaced", Data: o}
+    }
+    emitAll(events)
+    return nil
+}
+
+func (s *postgresService) GetGroup(id string) (*Group, error) {
+    var g Group
+    err := s.db.Preload("Orders", func(db *gorm.DB) *gorm.DB {
+        return db.Order("created_at, id")
+    }).Preload("Orders.Lines").Preload("Orders.Discounts").Preload("Orders.Taxes").Preload("Orders.Payment").First(&g, "id = ?", id).Error
+    if err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, ErrGroupNotFound
+        }
+        return nil, err
+    }
+    g.Status = GroupStatus(g.Orders)
+    return &g, nil
+}
+
 func (s *postgresService) GetByID(id string) (*Order, error) {
     var o Order
-    if err := s.db.First(&o, "id = ?", id).Error; err != nil {
+    if err := s.db.Preload("Lines").Preload("Discounts").Preload("Taxes").Preload("Payment").First(&o, "id = ?", id).Error; err != nil {
         if errors.Is(err, gorm.ErrRecordNotFound) {
-            return nil, errors.New("order not found")
+            return nil, ErrOrderNotFound
         }
         return nil, err
     }
@@ -59,28 +292,27 @@ func (s *postgresService) GetByID(id string) (*Order, error) {
 
 func (s *postgresService) ListByUser(userEmail string) ([]Order, error) {
     var list []Order
-    if err := s.db.Where("user_email = ?", userEmail).Find(&list).Error; err != nil {
+    err := s.db.Preload("Lines").Preload("Discounts").Preload("Taxes").Preload("Payment").Where("user_email = ?", userEmail).
+        Order("created_at, id").Find(&list).Error
+    if err != nil {
         return nil, err
     }
-    // keep same ordering as in-memory
-    sort.Slice(list, func(i, j int) bool {
-        return list[i].CreatedAt < list[j].CreatedAt
-    })
     return list, nil
 }
 
-func (s *postgresService) Accept(id, callerEmail string) error {
-    return s.db.Transaction(func(tx *gorm.DB) error {
-        // Find the order and check user
-        var o Order
-        if err := tx.First(&o, "id = ?", id).Error; err != nil {
-            if errors.Is(err, gorm.ErrRecordNotFound) {
-                return errors.New("order not found")
-            }
+func (s *postgresService) Accept(id, sellerID string) error {
+    var soldOut []listing.Listing
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        // Lock the order so a repeated accept can't take stock or a slot twice
+        o, err := lockOrder(tx, id)
+        if err != nil {
             return err
         }
-        if o.UserEmail != callerEmail {
-            return errors.New("forbidden")
+        if o.SellerID != sellerID {
+            return ErrForbidden
+        }
+        if o.Status != StatusPending {
+            return fmt.Errorf("%w: only pending orders can be accepted, this one is %s", ErrInvalidStatus, o.Status)
         }
 
         // Parse listing IDs from JSON
@@ -89,61 +321,63 @@ func (s *postgresService) Accept(id, callerEmail string) error {
             return errors.New("invalid listing IDs")
         }
         if len(listingIDs) == 0 {
-            return errors.New("no listings in order")
+            return ErrNoListings
         }
 
-        // For each listing ID, decrement left_size if possible
+        // For each listing ID, take a portion if one is left
         for _, lid := range listingIDs {
-            result := tx.Model(&listing.Listing{}).
-                Where("id = ? AND left_size > 0", lid).
-                UpdateColumn("left_size", gorm.Expr("left_size - ?", 1))
-            if result.Error != nil {
-                return result.Error
+            l, err := listing.ConsumePortion(tx, lid, o.ID, o.UserEmail)
+            if err != nil {
+                return err
+            }
+            if l != nil {
+                soldOut = append(soldOut, *l)
             }
-            if result.RowsAffected == 0 {
-                return errors.New("no portions left for listing " + lid)
+        }
+
+        // Book the pickup slot; orders from before slots existed have none
+        if o.PickupWindowID != "" {
+            if err := pickup.Reserve(tx, o.PickupWindowID, o.PickupDate); err != nil {
+                return err
             }
         }
 
-        // Update order status
-        if err := tx.Model(&o).Update("status", "accepted").Error; err != nil {
+        // Take the payment; orders from before payments existed have none
+        p, err := s.payments.Capture(tx, o.ID)
+        if err != nil && !errors.Is(err, payment.ErrPaymentNotFound) {
             return err
         }
-        o.Status = "accepted"
+        o.Payment = p
+        if p != nil {
+            if err := s.books.PostSale(tx, o.ID, o.SellerID, p.Captured); err != nil {
+                return err
+            }
+        }
+
+        // Update order status; the buyer shows the pickup code at handoff
+        code, err := newPickupCode()
+        if err != nil {
+            return err
+        }
+        if err := tx.Model(o).Updates(map[string]interface{}{"status": StatusAccepted, "pickup_code": code}).Error; err != nil {
+            return err
+        }
+        o.Status, o.PickupCode = StatusAccepted, code
 
         // Emit event
         go func(ev event.Event) {
             event.Bus <- ev
-        }(event.Event{Type: "OrderAccepted", Data: o})
+        }(event.Event{Type: "OrderAccepted", Data: *o})
 
         return nil
     })
-}
-
-
-func (s *postgresService) Complete(id, callerEmail string) error {
-    return s.updateStatus(id, callerEmail, "completed", "OrderCompleted")
-}
-
-func (s *postgresService) updateStatus(id, callerEmail, newStatus, eventType string) error {
-    var o Order
-    if err := s.db.First(&o, "id = ?", id).Error; err != nil {
-        if errors.Is(err, gorm.ErrRecordNotFound) {
-            return errors.New("order not found")
-        }
+    if err != nil {
         return err
     }
-    if o.UserEmail != callerEmail {
-        return errors.New("forbidden")
-    }
-
-    if err := s.db.Model(&o).Update("status", newStatus).Error; err != nil {
-        return err
+    for _, l := range soldOut {
+        go func(ev event.Event) {
+            event.Bus <- ev
+        }(event.Event{Type: "ListingSoldOut", Data: l})
     }
-    o.Status = newStatus
-    // emit event
-    go func(ev event.Event) {
-       event.Bus <- ev
-    }(event.Event{Type: eventType, Data: o})
     return nil
 }
@@ -0,0 +1,54 @@
+package order
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/google/uuid"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// priceLines turns an order's listing IDs into priced lines and their total,
+// using the listings' current prices. Every listing must be in listings and
+// they must all be priced in one currency.
+func priceLines(orderID string, listingIDs []string, listings map[string]listing.Listing) ([]Line, money.Money, error) {
+    var lines []Line
+    index := make(map[string]int)
+    for _, id := range listingIDs {
+        if i, ok := index[id]; ok {
+            lines[i].Quantity++
+            continue
+        }
+        l, ok := listings[id]
+        if !ok {
+            return nil, money.Money{}, fmt.Errorf("%w: %s", ErrUnknownListing, id)
+        }
+        index[id] = len(lines)
+        lines = append(lines, Line{
+            ID:          uuid.NewString(),
+            OrderID:     orderID,
+            ListingID:   id,
+            Title:       l.Title,
+            Quantity:    1,
+            UnitPrice:   l.Price,
+            TaxCategory: l.TaxCategory,
+        })
+    }
+    if len(lines) == 0 {
+        return nil, money.Money{}, ErrNoListings
+    }
+
+    totals := make([]money.Money, len(lines))
+    for i := range lines {
+        lines[i].Total = lines[i].UnitPrice.Mul(int64(lines[i].Quantity))
+        lines[i].Discount = money.New(0, lines[i].Total.Currency)
+        totals[i] = lines[i].Total
+    }
+    total, err := money.Sum(totals...)
+    if errors.Is(err, money.ErrCurrencyMismatch) {
+        return nil, money.Money{}, fmt.Errorf("%w: %v", ErrMixedCurrency, err)
+    }
+    return lines, total, err
+}
@@ -0,0 +1,40 @@
+package order
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func TestPriceLines(t *testing.T) {
+    listings := map[string]listing.Listing{
+        "a": {ID: "a", Title: "Curry", Price: money.New(1250, "USD")},
+        "b": {ID: "b", Title: "Rice", Price: money.New(199, "USD")},
+        "e": {ID: "e", Title: "Crêpe", Price: money.New(500, "EUR")},
+    }
+
+    lines, total, err := priceLines("o1", []string{"a", "b", "a"}, listings)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if total != money.New(2699, "USD") {
+        t.Errorf("total = %v; want 26.99 USD", total)
+    }
+    if len(lines) != 2 || lines[0].Quantity != 2 || lines[0].Total != money.New(2500, "USD") || lines[1].Title != "Rice" {
+        t.Errorf("lines = %+v", lines)
+    }
+    for _, l := range lines {
+        if l.OrderID != "o1" || l.ID == "" {
+            t.Errorf("line not tied to order: %+v", l)
+        }
+    }
+
+    if _, _, err := priceLines("o2", []string{"a", "e"}, listings); !errors.Is(err, ErrMixedCurrency) {
+        t.Errorf("mixed currencies: err = %v; want ErrMixedCurrency", err)
+    }
+    if _, _, err := priceLines("o3", []string{"a", "gone"}, listings); !errors.Is(err, ErrUnknownListing) {
+        t.Errorf("missing listing: err = %v; want ErrUnknownListing", err)
+    }
+}
@@ -0,0 +1,136 @@
+package order
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    DefaultPageSize = 20
+    MaxPageSize     = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid order query")
+
+// statuses an order can be in, for checking filters
+var orderStatuses = map[string]bool{
+    StatusPending: true, StatusAccepted: true, StatusReady: true, StatusCompleted: true,
+    StatusCancelled: true, StatusRejected: true, StatusExpired: true, StatusNoShow: true,
+}
+
+// Query filters a list of orders, newest first. Empty fields don't filter.
+type Query struct {
+    SellerID  string
+    UserEmail string
+    Statuses  []string   // any of these
+    ListingID string     // orders containing this listing
+    From      *time.Time // placed at or after
+    To        *time.Time // placed before
+    Limit     int        // page size, DefaultPageSize if 0
+    Cursor    string     // Page.NextCursor of the previous page
+}
+
+// Page is one page of a Query's results. NextCursor is empty on the last
+// page.
+type Page struct {
+    Orders     []Order `json:"orders"`
+    NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// validate checks the query and applies the default page size.
+func (q *Query) validate() error {
+    for _, st := range q.Statuses {
+        if !orderStatuses[st] {
+            return fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, st)
+        }
+    }
+    if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+        return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+    }
+    switch {
+    case q.Limit == 0:
+        q.Limit = DefaultPageSize
+    case q.Limit < 0 || q.Limit > MaxPageSize:
+        return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+    }
+    return nil
+}
+
+// cursor marks the last order of a page by its place in the ordering.
+type cursor struct {
+    CreatedAt int64
+    ID        string
+}
+
+func (c cursor) encode() string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt, 10) + ":" + c.ID))
+}
+
+func decodeCursor(s string) (cursor, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return cursor{}, fmt.Errorf("%w: bad cursor", ErrInvalidQuery)
+    }
+    ts, id, ok := strings.Cut(string(raw), ":")
+    createdAt, err := strconv.ParseInt(ts, 10, 64)
+    if !ok || err != nil || id == "" {
+        return cursor{}, fmt.Errorf("%w: bad cursor", ErrInvalidQuery)
+    }
+    return cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+func (s *postgresService) ListBySeller(sellerID string, q Query) (*Page, error) {
+    q.SellerID = sellerID
+    return s.Search(q)
+}
+
+func (s *postgresService) Search(q Query) (*Page, error) {
+    if err := q.validate(); err != nil {
+        return nil, err
+    }
+    tx := s.db.Preload("Lines").Preload("Discounts").Preload("Taxes").Preload("Payment")
+    if q.SellerID != "" {
+        tx = tx.Where("seller_id = ?", q.SellerID)
+    }
+    if q.UserEmail != "" {
+        tx = tx.Where("user_email = ?", q.UserEmail)
+    }
+    if len(q.Statuses) > 0 {
+        tx = tx.Where("status IN ?", q.Statuses)
+    }
+    if q.ListingID != "" {
+        ids, _ := json.Marshal([]string{q.ListingID})
+        tx = tx.Where("listing_ids @> ?::jsonb", string(ids))
+    }
+    if q.From != nil {
+        tx = tx.Where("created_at >= ?", q.From.Unix())
+    }
+    if q.To != nil {
+        tx = tx.Where("created_at < ?", q.To.Unix())
+    }
+    if q.Cursor != "" {
+        c, err := decodeCursor(q.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        tx = tx.Where("(created_at, id) < (?, ?)", c.CreatedAt, c.ID)
+    }
+
+    // one extra row tells us whether there's another page
+    var list []Order
+    if err := tx.Order("created_at DESC, id DESC").Limit(q.Limit + 1).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    page := &Page{Orders: list}
+    if len(list) > q.Limit {
+        page.Orders = list[:q.Limit]
+        last := page.Orders[q.Limit-1]
+        page.NextCursor = cursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+    }
+    return page, nil
+}
@@ -0,0 +1,68 @@
+package order
+
+import (
+    "errors"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+    c := cursor{CreatedAt: 1720000000, ID: "8d1c2b1e-4f7a-4c3e-9a55-0c1f0e2d3b4a"}
+    got, err := decodeCursor(c.encode())
+    if err != nil || got != c {
+        t.Fatalf("decodeCursor(encode(%+v)) = %+v, %v", c, got, err)
+    }
+    for _, bad := range []string{"!!!", "bm9jb2xvbg", c.encode()[:4]} {
+        if _, err := decodeCursor(bad); !errors.Is(err, ErrInvalidQuery) {
+            t.Errorf("decodeCursor(%q): err = %v, want ErrInvalidQuery", bad, err)
+        }
+    }
+}
+
+func TestQueryValidate(t *testing.T) {
+    now := time.Now()
+    earlier := now.Add(-time.Hour)
+
+    q := Query{Statuses: []string{StatusPending, StatusNoShow}, From: &earlier, To: &now}
+    if err := q.validate(); err != nil || q.Limit != DefaultPageSize {
+        t.Errorf("valid query: err = %v, limit = %d", err, q.Limit)
+    }
+    for _, q := range []Query{
+        {Statuses: []string{"shipped"}},
+        {From: &now, To: &earlier},
+        {Limit: MaxPageSize + 1},
+        {Limit: -1},
+    } {
+        if err := q.validate(); !errors.Is(err, ErrInvalidQuery) {
+            t.Errorf("validate(%+v) = %v, want ErrInvalidQuery", q, err)
+        }
+    }
+}
+
+func TestQueryFromRequestDates(t *testing.T) {
+    for raw, want := range map[string]struct{ from, to string }{
+        // a to date covers that day
+        "from=2025-07-01&to=2025-07-01": {"2025-07-01T00:00:00Z", "2025-07-02T00:00:00Z"},
+        // a to time is where it stops
+        "from=2025-07-01T09:00:00Z&to=2025-07-01T17:00:00Z": {"2025-07-01T09:00:00Z", "2025-07-01T17:00:00Z"},
+    } {
+        c, _ := gin.CreateTestContext(httptest.NewRecorder())
+        c.Request = httptest.NewRequest("GET", "/admin/orders?"+raw, nil)
+        q, err := queryFromRequest(c)
+        if err != nil {
+            t.Fatalf("%s: %v", raw, err)
+        }
+        if got := q.From.Format(time.RFC3339); got != want.from {
+            t.Errorf("%s: from = %s, want %s", raw, got, want.from)
+        }
+        if got := q.To.Format(time.RFC3339); got != want.to {
+            t.Errorf("%s: to = %s, want %s", raw, got, want.to)
+        }
+        if err := q.validate(); err != nil {
+            t.Errorf("%s: %v", raw, err)
+        }
+    }
+}
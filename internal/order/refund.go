@@ -0,0 +1,273 @@
+package order
+
+import (
+    "errors"
+    "fmt"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/pickup"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+// lockOrder loads an order with its lines, locking it for the rest of tx.
+func lockOrder(tx *gorm.DB, id string) (*Order, error) {
+    var o Order
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&o, "id = ?", id).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrOrderNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    if err := tx.Where("order_id = ?", id).Find(&o.Lines).Error; err != nil {
+        return nil, err
+    }
+    return &o, nil
+}
+
+func emitAll(events []event.Event) {
+    for _, ev := range events {
+        go func(ev event.Event) {
+            event.Bus <- ev
+        }(ev)
+    }
+}
+
+func (s *postgresService) Cancel(id, callerEmail string) (*Order, error) {
+    var o *Order
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        if o.UserEmail != callerEmail {
+            return ErrForbidden
+        }
+        if o.Status != StatusPending {
+            return fmt.Errorf("%w: only pending orders can be cancelled, this one is %s", ErrInvalidStatus, o.Status)
+        }
+        // nothing was captured yet, so releasing the hold is the whole
+        // refund; the promotion code can be used again
+        if o.Payment, err = s.payments.Void(tx, o.ID); err != nil && !errors.Is(err, payment.ErrPaymentNotFound) {
+            return err
+        }
+        if err := promo.Release(tx, o.ID); err != nil {
+            return err
+        }
+        o.Status = StatusCancelled
+        return tx.Model(o).Update("status", o.Status).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    emitAll([]event.Event{{Type: "OrderCancelled", Data: *o}})
+    return o, nil
+}
+
+func (s *postgresService) Reject(id, sellerID, actor, reason string) (*Order, error) {
+    var o *Order
+    var refund *payment.Refund
+    var restocked []listing.RestockNotice
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        if o.SellerID != sellerID {
+            return ErrForbidden
+        }
+        switch o.Status {
+        case StatusPending:
+            if _, err := s.payments.Void(tx, o.ID); err != nil && !errors.Is(err, payment.ErrPaymentNotFound) {
+                return err
+            }
+            if err := promo.Release(tx, o.ID); err != nil {
+                return err
+            }
+        case StatusAccepted, StatusReady:
+            if refund, restocked, err = s.giveBack(tx, o, actor, reason); err != nil {
+                return err
+            }
+        default:
+            return fmt.Errorf("%w: only pending, accepted or ready orders can be rejected, this one is %s", ErrInvalidStatus, o.Status)
+        }
+        o.Status = StatusRejected
+        return tx.Model(o).Update("status", o.Status).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    o.Payment, _ = s.payments.ForOrder(o.ID)
+    events := []event.Event{{Type: "OrderRejected", Data: *o}}
+    if refund != nil {
+        events = append(events, event.Event{Type: "OrderRefunded", Data: RefundNotice{Order: *o, Refund: *refund}})
+    }
+    for _, n := range restocked {
+        events = append(events, event.Event{Type: "ListingRestocked", Data: n})
+    }
+    emitAll(events)
+    return o, nil
+}
+
+// giveBack undoes an accepted order: accepting took the money, the stock
+// and the slot, so all three are returned, along with any promotion use.
+func (s *postgresService) giveBack(tx *gorm.DB, o *Order, actor, reason string) (*payment.Refund, []listing.RestockNotice, error) {
+    refund, err := s.refundRest(tx, o, actor, reason)
+    if err != nil {
+        return nil, nil, err
+    }
+    listingIDs, err := parseListingIDs(o.ListingIDs)
+    if err != nil {
+        return nil, nil, errors.New("invalid listing IDs")
+    }
+    var restocked []listing.RestockNotice
+    for _, lid := range listingIDs {
+        n, err := listing.ReturnPortion(tx, lid, o.ID, actor)
+        if err != nil {
+            return nil, nil, err
+        }
+        if n != nil {
+            restocked = append(restocked, *n)
+        }
+    }
+    if o.PickupWindowID != "" {
+        if err := pickup.Release(tx, o.PickupWindowID, o.PickupDate); err != nil {
+            return nil, nil, err
+        }
+    }
+    if err := promo.Release(tx, o.ID); err != nil {
+        return nil, nil, err
+    }
+    return refund, restocked, nil
+}
+
+// refundRest refunds whatever of the order's payment hasn't been refunded
+// yet; it returns nil if there's nothing left or no payment.
+func (s *postgresService) refundRest(tx *gorm.DB, o *Order, actor, reason string) (*payment.Refund, error) {
+    var p payment.Payment
+    err := tx.Where("order_id = ?", o.ID).Limit(1).Find(&p).Error
+    if err != nil || p.ID == "" {
+        return nil, err
+    }
+    rest, err := p.Captured.Sub(p.Refunded)
+    if err != nil || rest.Amount <= 0 {
+        return nil, err
+    }
+    return s.refund(tx, payment.RefundRequest{OrderID: o.ID, Amount: rest, Reason: reason, Actor: actor})
+}
+
+// refund gives back req.Amount and books it.
+func (s *postgresService) refund(tx *gorm.DB, req payment.RefundRequest) (*payment.Refund, error) {
+    r, err := s.payments.Refund(tx, req)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.books.PostRefund(tx, r); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *postgresService) Refund(id string, in RefundInput, actor string) (*payment.Refund, error) {
+    var o *Order
+    var r *payment.Refund
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if o, err = lockOrder(tx, id); err != nil {
+            return err
+        }
+        if o.Status != StatusAccepted && o.Status != StatusReady && o.Status != StatusCompleted {
+            return fmt.Errorf("%w: only accepted, ready or completed orders can be refunded, this one is %s", ErrInvalidStatus, o.Status)
+        }
+        req := payment.RefundRequest{OrderID: o.ID, Reason: in.Reason, Actor: actor}
+        switch {
+        case in.LineID != "" && in.Amount != nil:
+            return fmt.Errorf("%w: give either amount or lineId, not both", ErrInvalidRefund)
+        case in.LineID != "":
+            if req.Amount, err = s.lineRefund(tx, o, in); err != nil {
+                return err
+            }
+            req.LineID, req.Quantity = &in.LineID, max(in.Quantity, 1)
+        case in.Amount != nil:
+            if in.Amount.Currency != o.Total.Currency {
+                return fmt.Errorf("%w: order is in %s", ErrInvalidRefund, o.Total.Currency)
+            }
+            req.Amount = *in.Amount
+        default:
+            return fmt.Errorf("%w: amount or lineId is required", ErrInvalidRefund)
+        }
+        r, err = s.refund(tx, req)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    o.Payment, _ = s.payments.ForOrder(o.ID)
+    emitAll([]event.Event{{Type: "OrderRefunded", Data: RefundNotice{Order: *o, Refund: *r}}})
+    return r, nil
+}
+
+// lineRefund prices a refund of missing portions of one order line,
+// checking they haven't been refunded already.
+func (s *postgresService) lineRefund(tx *gorm.DB, o *Order, in RefundInput) (money.Money, error) {
+    qty := max(in.Quantity, 1)
+    for _, l := range o.Lines {
+        if l.ID != in.LineID {
+            continue
+        }
+        var refunded int
+        err := tx.Model(&payment.Refund{}).Where("line_id = ?", l.ID).
+            Select("coalesce(sum(quantity), 0)").Scan(&refunded).Error
+        if err != nil {
+            return money.Money{}, err
+        }
+        if refunded+qty > l.Quantity {
+            return money.Money{}, fmt.Errorf("%w: %d of %d portions already refunded", ErrInvalidRefund, refunded, l.Quantity)
+        }
+        if err := tx.Where("order_id = ?", o.ID).Find(&o.Taxes).Error; err != nil {
+            return money.Money{}, err
+        }
+        amount := lineCharge(o, l, refunded+qty) - lineCharge(o, l, refunded)
+        return money.New(amount, l.UnitPrice.Currency), nil
+    }
+    return money.Money{}, fmt.Errorf("%w: order has no line %s", ErrInvalidRefund, in.LineID)
+}
+
+// lineCharge is what the buyer paid for the first n portions of line l:
+// the discounted price plus the line's share of any tax added on top.
+// Added tax is shared over the lines by the base it was worked out on (see
+// taxLines), in order of line ID and rounding down, so that the shares
+// add up to the order's tax; a line's share and discount are spread over
+// its portions the same way.
+func lineCharge(o *Order, l Line, n int) int64 {
+    added := tax.Added(o.Taxes, o.Total.Currency).Amount
+    var base, before, sum int64
+    for _, x := range o.Lines {
+        b := x.Total.Amount - x.Discount.Amount
+        sum += b
+        switch {
+        case x.ID == l.ID:
+            base = b
+        case x.ID < l.ID:
+            before += b
+        }
+    }
+    var share int64
+    if sum > 0 {
+        share = added*(before+base)/sum - added*before/sum
+    }
+    q, k := int64(l.Quantity), int64(n)
+    return l.UnitPrice.Amount*k - l.Discount.Amount*k/q + share*k/q
+}
+
+func (s *postgresService) Refunds(id string) ([]payment.Refund, error) {
+    return s.payments.Refunds(id)
+}
@@ -0,0 +1,201 @@
+package order
+
+import (
+    "errors"
+    "testing"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+func TestLineChargeAddedTax(t *testing.T) {
+    cad := func(n int64) money.Money { return money.New(n, "CAD") }
+    // 3 × 10.00 with 3.00 off, and 1 × 20.00; 13% added on 47.00
+    o := &Order{
+        Lines: []Line{
+            {ID: "b", Quantity: 1, UnitPrice: cad(2000), Total: cad(2000)},
+            {ID: "a", Quantity: 3, UnitPrice: cad(1000), Total: cad(3000), Discount: cad(300)},
+        },
+        Subtotal: cad(5000),
+        Taxes:    []tax.Line{{Name: "HST", Taxable: cad(4700), Amount: cad(611)}},
+        Total:    cad(5311),
+    }
+    a, b := o.Lines[1], o.Lines[0]
+
+    // 611 × 27/47 = 351 of the tax is on line a, 117 a portion
+    if got, want := lineCharge(o, a, 1), int64(1000-100+117); got != want {
+        t.Errorf("one portion of a: %d, want %d", got, want)
+    }
+    // refunding the other two portions later gives back the rest
+    if got, want := lineCharge(o, a, 3)-lineCharge(o, a, 1), int64(2000-200+234); got != want {
+        t.Errorf("two more portions of a: %d, want %d", got, want)
+    }
+    if got, want := lineCharge(o, b, 1), int64(2000+260); got != want {
+        t.Errorf("line b: %d, want %d", got, want)
+    }
+    if got := lineCharge(o, a, 3) + lineCharge(o, b, 1); got != o.Total.Amount {
+        t.Errorf("lines add up to %d, order total is %d", got, o.Total.Amount)
+    }
+
+    // taxes included in the prices add nothing
+    o.TaxInclusive = true
+    o.Taxes = []tax.Line{{Name: "HST", Taxable: cad(4159), Amount: cad(541), Included: true}}
+    o.Total = cad(4700)
+    if got, want := lineCharge(o, a, 1), int64(900); got != want {
+        t.Errorf("tax-inclusive portion of a: %d, want %d", got, want)
+    }
+}
+
+func TestCancelRejectRefund(t *testing.T) {
+    e := needDB(t)
+    refund := func(o *Order, in RefundInput) error {
+        in.Reason = "missing item"
+        _, err := e.svc.Refund(o.ID, in, "seller")
+        return err
+    }
+
+    // every case starts from one portion of a 5-portion listing, ordered
+    // and, with accept, accepted; the last step's error is checked
+    tests := []struct {
+        name        string
+        accept      bool
+        steps       func(t *testing.T, sh *shop, o *Order) []error
+        want        error
+        wantStatus  string
+        wantStock   int
+        wantPayment string
+    }{
+        {
+            name: "cancel pending",
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Cancel(o.ID, o.UserEmail)
+                return []error{err}
+            },
+            wantStatus: StatusCancelled, wantStock: 5, wantPayment: payment.StatusVoided,
+        },
+        {
+            name:   "cancel accepted",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Cancel(o.ID, o.UserEmail)
+                return []error{err}
+            },
+            want:       ErrInvalidStatus,
+            wantStatus: StatusAccepted, wantStock: 4, wantPayment: payment.StatusCaptured,
+        },
+        {
+            name: "cancel by someone else",
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Cancel(o.ID, "someone@example.com")
+                return []error{err}
+            },
+            want:       ErrForbidden,
+            wantStatus: StatusPending, wantStock: 5, wantPayment: payment.StatusAuthorized,
+        },
+        {
+            name: "reject pending",
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Reject(o.ID, sh.seller.ID, "seller", "closed today")
+                return []error{err}
+            },
+            wantStatus: StatusRejected, wantStock: 5, wantPayment: payment.StatusVoided,
+        },
+        {
+            name:   "reject accepted",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Reject(o.ID, sh.seller.ID, "seller", "ran out")
+                return []error{err}
+            },
+            wantStatus: StatusRejected, wantStock: 5, wantPayment: payment.StatusRefunded,
+        },
+        {
+            name:   "reject by another seller",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                _, err := e.svc.Reject(o.ID, e.newShop(t, 1).seller.ID, "seller", "not mine")
+                return []error{err}
+            },
+            want:       ErrForbidden,
+            wantStatus: StatusAccepted, wantStock: 4, wantPayment: payment.StatusCaptured,
+        },
+        {
+            name:   "refund more portions than the line has",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                return []error{refund(o, RefundInput{LineID: o.Lines[0].ID, Quantity: 2})}
+            },
+            want:       ErrInvalidRefund,
+            wantStatus: StatusAccepted, wantStock: 4, wantPayment: payment.StatusCaptured,
+        },
+        {
+            name:   "refund a line twice",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                return []error{
+                    refund(o, RefundInput{LineID: o.Lines[0].ID}),
+                    refund(o, RefundInput{LineID: o.Lines[0].ID}),
+                }
+            },
+            want:       ErrInvalidRefund,
+            wantStatus: StatusAccepted, wantStock: 4, wantPayment: payment.StatusRefunded,
+        },
+        {
+            name:   "refund the total twice",
+            accept: true,
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                return []error{
+                    refund(o, RefundInput{Amount: &o.Total}),
+                    refund(o, RefundInput{Amount: &o.Total}),
+                }
+            },
+            want:       payment.ErrInvalidState,
+            wantStatus: StatusAccepted, wantStock: 4, wantPayment: payment.StatusRefunded,
+        },
+        {
+            name: "refund pending",
+            steps: func(t *testing.T, sh *shop, o *Order) []error {
+                return []error{refund(o, RefundInput{Amount: &o.Total})}
+            },
+            want:       ErrInvalidStatus,
+            wantStatus: StatusPending, wantStock: 5, wantPayment: payment.StatusAuthorized,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            sh := e.newShop(t, 5)
+            o := e.place(t, sh)
+            if tt.accept {
+                if err := e.svc.Accept(o.ID, sh.seller.ID); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            o = e.order(t, o.ID)
+
+            errs := tt.steps(t, sh, o)
+            for _, err := range errs[:len(errs)-1] {
+                if err != nil {
+                    t.Fatalf("earlier step: %v", err)
+                }
+            }
+            if err := errs[len(errs)-1]; !errors.Is(err, tt.want) {
+                t.Fatalf("err = %v, want %v", err, tt.want)
+            }
+
+            if got := e.order(t, o.ID).Status; got != tt.wantStatus {
+                t.Errorf("status = %s, want %s", got, tt.wantStatus)
+            }
+            if got := e.stock(t, sh); got != tt.wantStock {
+                t.Errorf("stock = %d, want %d", got, tt.wantStock)
+            }
+            p := e.payment(t, o.ID)
+            if p.Status != tt.wantPayment {
+                t.Errorf("payment = %s, want %s", p.Status, tt.wantPayment)
+            }
+            if p.Refunded.Amount > p.Captured.Amount {
+                t.Errorf("refunded %s of %s captured", p.Refunded, p.Captured)
+            }
+        })
+    }
+}
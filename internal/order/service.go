@@ -1,10 +1,57 @@
 // internal/order/service.go
 package order
+
+import (
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+)
+
 type Service interface {
     Create(o *Order) error
+    // CreateGroup places one order per seller with a single payment
+    // authorization for the group's total; it all succeeds or fails
+    // together.
+    CreateGroup(g *Group) error
+    GetGroup(id string) (*Group, error)
     GetByID(id string) (*Order, error)
     ListByUser(email string) ([]Order, error)
+    // ListBySeller pages through a seller's orders, newest first, and
+    // Search does the same across all orders for admins.
+    ListBySeller(sellerID string, q Query) (*Page, error)
+    Search(q Query) (*Page, error)
+
+    // Accept lets the order's seller take a pending order: it takes the
+    // portions and the pickup slot and captures the payment.
+    Accept(id, sellerID string) error
+    // Ready lets the order's seller mark an accepted order as prepared;
+    // the buyer's pickup timeout starts then.
+    Ready(id, sellerID string) (*Order, error)
+
+    // PickupCode returns an accepted order's pickup code to its buyer, and
+    // RotatePickupCode replaces it, e.g. after handoff was locked.
+    PickupCode(id, callerEmail string) (*PickupCode, error)
+    RotatePickupCode(id, callerEmail string) (*PickupCode, error)
+    // Handoff completes an accepted order when its seller enters the
+    // buyer's pickup code, typed or scanned from the QR code. After
+    // MaxHandoffAttempts wrong codes it returns ErrHandoffLocked.
+    Handoff(id, sellerID, code string) (*Order, error)
+
+    // Cancel lets the buyer withdraw a pending order; the payment hold is
+    // voided.
+    Cancel(id, callerEmail string) (*Order, error)
+    // Reject lets the order's seller turn it down. A pending order's hold is
+    // voided; an accepted order is refunded in full and its portions and
+    // pickup slot are given back.
+    Reject(id, sellerID, actor, reason string) (*Order, error)
+    // Refund gives back part of an accepted or completed order's payment,
+    // e.g. for missing items. Stock isn't restored.
+    Refund(id string, in RefundInput, actor string) (*payment.Refund, error)
+    Refunds(id string) ([]payment.Refund, error)
 
-    Accept(id, callerEmail string) error   // ← 2 args
-    Complete(id, callerEmail string) error // ← 2 args
+    // ExpireOverdue moves pending orders not accepted within t.Accept to
+    // expired, voiding their hold, and ready orders not picked up within
+    // t.Pickup to no_show, refunding them and giving back their stock and
+    // slot. It returns the orders it changed.
+    ExpireOverdue(now time.Time, t Timeouts) ([]Order, error)
 }
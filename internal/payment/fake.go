@@ -0,0 +1,181 @@
+package payment
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/google/uuid"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// DeclineToken is a payment method the fake gateway always declines.
+const DeclineToken = "tok_decline"
+
+// FakeGateway is an in-memory Gateway for development and tests. Any
+// payment method but DeclineToken is approved. When OnEvent is set, every
+// capture, void, refund and expiry also produces a signed webhook;
+// cmd/fakepay posts them to the app.
+type FakeGateway struct {
+    mu     sync.Mutex
+    secret string
+    auths  map[string]*fakeAuth
+    now    func() time.Time
+
+    OnEvent func(payload []byte, signature string)
+}
+
+type fakeAuth struct {
+    amount   money.Money
+    captured int64
+    refunded int64
+    status   string // Status* values of Payment
+}
+
+// NewFakeGateway returns a FakeGateway signing webhooks with secret.
+func NewFakeGateway(secret string) *FakeGateway {
+    return &FakeGateway{secret: secret, auths: make(map[string]*fakeAuth), now: time.Now}
+}
+
+func (g *FakeGateway) Name() string { return "fake" }
+
+func (g *FakeGateway) Authorize(_ context.Context, req AuthorizeRequest) (string, error) {
+    if req.PaymentMethod == DeclineToken {
+        return "", ErrDeclined
+    }
+    if req.Amount.IsNegative() {
+        return "", ErrInvalidAmount
+    }
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    ref := "auth_" + uuid.NewString()
+    g.auths[ref] = &fakeAuth{amount: req.Amount, status: StatusAuthorized}
+    return ref, nil
+}
+
+func (g *FakeGateway) Capture(_ context.Context, ref string, amount money.Money) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    a, err := g.auth(ref)
+    if err != nil {
+        return err
+    }
+    if a.status != StatusAuthorized && a.status != StatusPartiallyCaptured {
+        return fmt.Errorf("%w: authorization is %s", ErrInvalidState, a.status)
+    }
+    if amount.Currency != a.amount.Currency || amount.IsNegative() || a.captured+amount.Amount > a.amount.Amount {
+        return ErrInvalidAmount
+    }
+    a.captured += amount.Amount
+    a.status = StatusCaptured
+    if a.captured < a.amount.Amount {
+        a.status = StatusPartiallyCaptured
+    }
+    g.send(EventCaptured, ref, amount)
+    return nil
+}
+
+func (g *FakeGateway) Void(_ context.Context, ref string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    a, err := g.auth(ref)
+    if err != nil {
+        return err
+    }
+    if a.status != StatusAuthorized {
+        return fmt.Errorf("%w: authorization is %s", ErrInvalidState, a.status)
+    }
+    a.status = StatusVoided
+    g.send(EventVoided, ref, a.amount)
+    return nil
+}
+
+func (g *FakeGateway) Refund(_ context.Context, ref string, amount money.Money) (string, error) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    a, err := g.auth(ref)
+    if err != nil {
+        return "", err
+    }
+    if amount.Currency != a.amount.Currency || amount.Amount <= 0 || a.refunded+amount.Amount > a.captured {
+        return "", ErrInvalidAmount
+    }
+    a.refunded += amount.Amount
+    g.send(EventRefunded, ref, amount)
+    return "re_" + uuid.NewString(), nil
+}
+
+// Expire lets an uncaptured authorization lapse, as real holds do after a
+// few days.
+func (g *FakeGateway) Expire(ref string) error {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    a, err := g.auth(ref)
+    if err != nil {
+        return err
+    }
+    if a.status != StatusAuthorized {
+        return fmt.Errorf("%w: authorization is %s", ErrInvalidState, a.status)
+    }
+    a.status = StatusExpired
+    g.send(EventExpired, ref, a.amount)
+    return nil
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+    if g.secret == "" {
+        return nil, fmt.Errorf("%w: no webhook secret configured", ErrInvalidWebhook)
+    }
+    return verifyWebhook(g.secret, payload, header, g.now())
+}
+
+func (g *FakeGateway) auth(ref string) (*fakeAuth, error) {
+    a, ok := g.auths[ref]
+    if !ok {
+        return nil, ErrUnknownReference
+    }
+    return a, nil
+}
+
+// send signs an event and hands it to OnEvent; g.mu is held.
+func (g *FakeGateway) send(typ, ref string, amount money.Money) {
+    if g.OnEvent == nil {
+        return
+    }
+    now := g.now()
+    payload, _ := json.Marshal(WebhookEvent{
+        ID:        "evt_" + uuid.NewString(),
+        Type:      typ,
+        Reference: ref,
+        Amount:    amount.Amount,
+        Currency:  amount.Currency,
+        CreatedAt: now.UTC(),
+    })
+    g.OnEvent(payload, SignWebhook(g.secret, payload, now))
+}
+
+// fakeErrors maps gateway errors to HTTP statuses for the fake server and
+// back for HTTPGateway.
+var fakeErrors = []struct {
+    err    error
+    status int
+}{
+    {ErrDeclined, http.StatusPaymentRequired},
+    {ErrUnknownReference, http.StatusNotFound},
+    {ErrInvalidState, http.StatusConflict},
+    {ErrInvalidAmount, http.StatusUnprocessableEntity},
+}
+
+func statusFor(err error) int {
+    for _, e := range fakeErrors {
+        if errors.Is(err, e.err) {
+            return e.status
+        }
+    }
+    return http.StatusInternalServerError
+}
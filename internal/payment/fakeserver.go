@@ -0,0 +1,109 @@
+package payment
+
+import (
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// wire types shared by the fake server and HTTPGateway; amounts are in
+// minor units
+type (
+    authorizeBody struct {
+        OrderID       string `json:"orderId"`
+        Amount        int64  `json:"amount"`
+        Currency      string `json:"currency" binding:"required"`
+        Customer      string `json:"customer"`
+        PaymentMethod string `json:"paymentMethod"`
+    }
+    amountBody struct {
+        Amount   int64  `json:"amount"`
+        Currency string `json:"currency" binding:"required"`
+    }
+    referenceBody struct {
+        Reference string `json:"reference"`
+    }
+)
+
+// NewFakeServer serves g over HTTP in the shape HTTPGateway expects, so the
+// app can be run against a separate payment process. Requests must carry
+// "Authorization: Bearer <apiKey>" when apiKey is set.
+func NewFakeServer(g *FakeGateway, apiKey string) http.Handler {
+    r := gin.New()
+    r.Use(gin.Recovery())
+    v1 := r.Group("/v1", func(c *gin.Context) {
+        if apiKey != "" && c.GetHeader("Authorization") != "Bearer "+apiKey {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+        }
+    })
+
+    fail := func(c *gin.Context, err error) {
+        c.JSON(statusFor(err), gin.H{"error": err.Error()})
+    }
+
+    v1.POST("/authorizations", func(c *gin.Context) {
+        var body authorizeBody
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        ref, err := g.Authorize(c, AuthorizeRequest{
+            OrderID:       body.OrderID,
+            Amount:        money.New(body.Amount, body.Currency),
+            Customer:      body.Customer,
+            PaymentMethod: body.PaymentMethod,
+        })
+        if err != nil {
+            fail(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, referenceBody{ref})
+    })
+
+    v1.POST("/authorizations/:ref/capture", func(c *gin.Context) {
+        var body amountBody
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := g.Capture(c, c.Param("ref"), money.New(body.Amount, body.Currency)); err != nil {
+            fail(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    v1.POST("/authorizations/:ref/void", func(c *gin.Context) {
+        if err := g.Void(c, c.Param("ref")); err != nil {
+            fail(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    v1.POST("/authorizations/:ref/refunds", func(c *gin.Context) {
+        var body amountBody
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        ref, err := g.Refund(c, c.Param("ref"), money.New(body.Amount, body.Currency))
+        if err != nil {
+            fail(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, referenceBody{ref})
+    })
+
+    // not part of a real provider's API: lets a hold lapse on demand
+    v1.POST("/authorizations/:ref/expire", func(c *gin.Context) {
+        if err := g.Expire(c.Param("ref")); err != nil {
+            fail(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+    return r
+}
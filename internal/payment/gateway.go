@@ -0,0 +1,79 @@
+// Package payment takes payment for orders through a provider-agnostic
+// Gateway: the buyer's card is authorized when an order is placed and the
+// hold is captured when the order is accepted.
+package payment
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+var (
+    ErrDeclined         = errors.New("payment declined")
+    ErrUnknownReference = errors.New("unknown payment reference")
+    ErrInvalidAmount    = errors.New("amount exceeds what can be charged")
+    ErrInvalidState     = errors.New("payment is not in a state that allows this")
+    ErrInvalidWebhook   = errors.New("invalid webhook")
+    ErrPaymentNotFound  = errors.New("payment not found")
+)
+
+// AuthorizeRequest asks the provider to hold Amount on the buyer's card.
+type AuthorizeRequest struct {
+    OrderID       string // the order, or for AuthorizeGroup the checkout group
+    Amount        money.Money
+    Customer      string // buyer's email
+    PaymentMethod string // provider token for the card, e.g. "tok_visa"
+}
+
+// Gateway is a payment provider. References are the provider's IDs for an
+// authorization or refund.
+type Gateway interface {
+    // Name identifies the provider in the payments table.
+    Name() string
+    // Authorize places a hold and returns its reference, or ErrDeclined.
+    Authorize(ctx context.Context, req AuthorizeRequest) (ref string, err error)
+    // Capture takes amount (at most what's authorized) from the hold.
+    Capture(ctx context.Context, ref string, amount money.Money) error
+    // Void releases a hold that hasn't been captured.
+    Void(ctx context.Context, ref string) error
+    // Refund returns amount of a captured payment and returns the refund's
+    // reference.
+    Refund(ctx context.Context, ref string, amount money.Money) (refundRef string, err error)
+    // VerifyWebhook checks a webhook's signature and decodes it.
+    VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+// GatewayFromEnv picks the gateway named by PAYMENT_GATEWAY, which must be
+// set so that a deploy missing it doesn't take orders without charging:
+//
+//   - "fake": an in-process FakeGateway that approves every card, for
+//     development and tests
+//   - "http": an HTTPGateway talking to PAYMENT_GATEWAY_URL with
+//     PAYMENT_API_KEY, e.g. cmd/fakepay
+//
+// Webhooks are verified with PAYMENT_WEBHOOK_SECRET.
+func GatewayFromEnv() (Gateway, error) {
+    secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+    switch kind := os.Getenv("PAYMENT_GATEWAY"); kind {
+    case "":
+        return nil, errors.New(`PAYMENT_GATEWAY is required: "http", or "fake" for development`)
+    case "fake":
+        return NewFakeGateway(secret), nil
+    case "http":
+        url := os.Getenv("PAYMENT_GATEWAY_URL")
+        if url == "" {
+            return nil, errors.New("PAYMENT_GATEWAY_URL is required for the http gateway")
+        }
+        if secret == "" {
+            return nil, errors.New("PAYMENT_WEBHOOK_SECRET is required for the http gateway")
+        }
+        return NewHTTPGateway(url, os.Getenv("PAYMENT_API_KEY"), secret), nil
+    default:
+        return nil, fmt.Errorf("unknown PAYMENT_GATEWAY %q", kind)
+    }
+}
@@ -0,0 +1,159 @@
+package payment
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func usd(cents int64) money.Money { return money.New(cents, "USD") }
+
+// exercise runs an order's payment through a gateway: a decline, then an
+// authorization captured in two parts and partly refunded.
+func exercise(t *testing.T, gw Gateway) {
+    ctx := context.Background()
+    if _, err := gw.Authorize(ctx, AuthorizeRequest{OrderID: "o0", Amount: usd(1000), PaymentMethod: DeclineToken}); !errors.Is(err, ErrDeclined) {
+        t.Errorf("decline token: err = %v; want ErrDeclined", err)
+    }
+
+    ref, err := gw.Authorize(ctx, AuthorizeRequest{OrderID: "o1", Amount: usd(1000), Customer: "a@example.com", PaymentMethod: "tok_visa"})
+    if err != nil || ref == "" {
+        t.Fatalf("Authorize = %q, %v", ref, err)
+    }
+    if _, err := gw.Refund(ctx, ref, usd(100)); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("refund before capture: err = %v; want ErrInvalidAmount", err)
+    }
+    if err := gw.Capture(ctx, ref, usd(600)); err != nil {
+        t.Fatalf("Capture 6.00: %v", err)
+    }
+    if err := gw.Capture(ctx, ref, usd(500)); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("capture over the hold: err = %v; want ErrInvalidAmount", err)
+    }
+    if err := gw.Capture(ctx, ref, money.New(400, "EUR")); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("capture in another currency: err = %v; want ErrInvalidAmount", err)
+    }
+    if err := gw.Capture(ctx, ref, usd(400)); err != nil {
+        t.Fatalf("Capture 4.00: %v", err)
+    }
+    if err := gw.Void(ctx, ref); !errors.Is(err, ErrInvalidState) {
+        t.Errorf("void after capture: err = %v; want ErrInvalidState", err)
+    }
+    if rref, err := gw.Refund(ctx, ref, usd(250)); err != nil || rref == "" {
+        t.Errorf("Refund = %q, %v", rref, err)
+    }
+    if _, err := gw.Refund(ctx, ref, usd(800)); !errors.Is(err, ErrInvalidAmount) {
+        t.Errorf("refund over captured: err = %v; want ErrInvalidAmount", err)
+    }
+    if err := gw.Capture(ctx, "auth_missing", usd(1)); !errors.Is(err, ErrUnknownReference) {
+        t.Errorf("unknown reference: err = %v; want ErrUnknownReference", err)
+    }
+
+    ref2, _ := gw.Authorize(ctx, AuthorizeRequest{OrderID: "o2", Amount: usd(300)})
+    if err := gw.Void(ctx, ref2); err != nil {
+        t.Errorf("Void: %v", err)
+    }
+    if err := gw.Capture(ctx, ref2, usd(300)); !errors.Is(err, ErrInvalidState) {
+        t.Errorf("capture after void: err = %v; want ErrInvalidState", err)
+    }
+}
+
+func TestFakeGateway(t *testing.T) {
+    exercise(t, NewFakeGateway("secret"))
+}
+
+func TestHTTPGatewayAgainstFakeServer(t *testing.T) {
+    fake := NewFakeGateway("secret")
+    var sent []*http.Request
+    fake.OnEvent = func(payload []byte, signature string) {
+        r := httptest.NewRequest(http.MethodPost, "/payments/webhook", nil)
+        r.Header.Set(SignatureHeader, signature)
+        r.Header.Set("X-Payload", string(payload))
+        sent = append(sent, r)
+    }
+    srv := httptest.NewServer(NewFakeServer(fake, "key"))
+    defer srv.Close()
+
+    gw := NewHTTPGateway(srv.URL, "key", "secret")
+    exercise(t, gw)
+
+    // two captures, a refund and a void
+    if len(sent) != 4 {
+        t.Fatalf("fake sent %d webhooks; want 4", len(sent))
+    }
+    for _, r := range sent {
+        ev, err := gw.VerifyWebhook([]byte(r.Header.Get("X-Payload")), r.Header)
+        if err != nil {
+            t.Fatalf("VerifyWebhook: %v", err)
+        }
+        if ev.ID == "" || ev.Reference == "" || ev.Currency != "USD" {
+            t.Errorf("event = %+v", ev)
+        }
+    }
+
+    if _, err := NewHTTPGateway(srv.URL, "wrong", "secret").Authorize(context.Background(), AuthorizeRequest{Amount: usd(1)}); err == nil {
+        t.Error("Authorize with a wrong API key succeeded")
+    }
+}
+
+func TestVerifyWebhook(t *testing.T) {
+    now := time.Unix(1751648400, 0)
+    payload := []byte(`{"id":"evt_1","type":"authorization.expired","reference":"auth_1"}`)
+    header := func(sig string) http.Header {
+        h := http.Header{}
+        h.Set(SignatureHeader, sig)
+        return h
+    }
+
+    ev, err := verifyWebhook("secret", payload, header(SignWebhook("secret", payload, now)), now.Add(time.Minute))
+    if err != nil || ev.Type != EventExpired || ev.Reference != "auth_1" {
+        t.Fatalf("valid webhook: %+v, %v", ev, err)
+    }
+    for name, tc := range map[string]struct {
+        payload []byte
+        sig     string
+        at      time.Time
+    }{
+        "tampered":  {[]byte(`{"id":"evt_1","type":"payment.captured","reference":"auth_1"}`), SignWebhook("secret", payload, now), now},
+        "wrong key": {payload, SignWebhook("other", payload, now), now},
+        "stale":     {payload, SignWebhook("secret", payload, now), now.Add(10 * time.Minute)},
+        "no header": {payload, "", now},
+        "no v1":     {payload, "t=1751648400", now},
+        "no id":     {[]byte(`{"reference":"auth_1"}`), SignWebhook("secret", []byte(`{"reference":"auth_1"}`), now), now},
+    } {
+        if _, err := verifyWebhook("secret", tc.payload, header(tc.sig), tc.at); !errors.Is(err, ErrInvalidWebhook) {
+            t.Errorf("%s: err = %v; want ErrInvalidWebhook", name, err)
+        }
+    }
+
+    if _, err := NewFakeGateway("").VerifyWebhook(payload, header(SignWebhook("", payload, time.Now()))); err == nil {
+        t.Error("fake gateway without a secret accepted a webhook")
+    }
+}
+
+func TestGatewayFromEnv(t *testing.T) {
+    t.Setenv("PAYMENT_WEBHOOK_SECRET", "whsec")
+    t.Setenv("PAYMENT_GATEWAY_URL", "http://localhost:8090")
+    for _, tc := range []struct {
+        kind string
+        want string // gateway name, or "" for an error
+    }{
+        {"", ""}, // never fall back to the fake
+        {"fake", "fake"},
+        {"http", "http"},
+        {"stripe", ""},
+    } {
+        t.Setenv("PAYMENT_GATEWAY", tc.kind)
+        gw, err := GatewayFromEnv()
+        switch {
+        case tc.want == "" && err == nil:
+            t.Errorf("PAYMENT_GATEWAY=%q: got %s, want an error", tc.kind, gw.Name())
+        case tc.want != "" && (err != nil || gw.Name() != tc.want):
+            t.Errorf("PAYMENT_GATEWAY=%q: got %v, %v; want %s", tc.kind, gw, err, tc.want)
+        }
+    }
+}
@@ -0,0 +1,39 @@
+package payment
+
+import (
+    "errors"
+    "io"
+    "log"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes bounds webhook bodies; events are a few hundred bytes.
+const maxWebhookBytes = 64 << 10
+
+func RegisterRoutes(r *gin.Engine, svc Service) {
+    // POST /payments/webhook – provider notifications, authenticated by
+    // their signature rather than a JWT
+    r.POST("/payments/webhook", func(c *gin.Context) {
+        payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBytes))
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        _, err = svc.HandleWebhook(payload, c.Request.Header)
+        switch {
+        case err == nil:
+            c.JSON(http.StatusOK, gin.H{"received": true})
+        case errors.Is(err, ErrInvalidWebhook):
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        case errors.Is(err, ErrPaymentNotFound):
+            // not ours; acknowledge so the provider stops retrying
+            log.Printf("payment webhook for unknown payment ignored")
+            c.JSON(http.StatusOK, gin.H{"received": true})
+        default:
+            log.Printf("payment webhook failed: %v", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "could not process webhook"})
+        }
+    })
+}
@@ -0,0 +1,108 @@
+package payment
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// HTTPGateway is a Gateway client for a provider speaking the API served by
+// NewFakeServer.
+type HTTPGateway struct {
+    baseURL string
+    apiKey  string
+    secret  string
+    client  *http.Client
+    now     func() time.Time
+}
+
+// NewHTTPGateway returns a client for the provider at baseURL. secret
+// verifies the provider's webhooks.
+func NewHTTPGateway(baseURL, apiKey, secret string) *HTTPGateway {
+    return &HTTPGateway{
+        baseURL: strings.TrimRight(baseURL, "/"),
+        apiKey:  apiKey,
+        secret:  secret,
+        client:  &http.Client{Timeout: 10 * time.Second},
+        now:     time.Now,
+    }
+}
+
+func (g *HTTPGateway) Name() string { return "http" }
+
+func (g *HTTPGateway) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+    var out referenceBody
+    err := g.post(ctx, "/v1/authorizations", authorizeBody{
+        OrderID:       req.OrderID,
+        Amount:        req.Amount.Amount,
+        Currency:      req.Amount.Currency,
+        Customer:      req.Customer,
+        PaymentMethod: req.PaymentMethod,
+    }, &out)
+    return out.Reference, err
+}
+
+func (g *HTTPGateway) Capture(ctx context.Context, ref string, amount money.Money) error {
+    return g.post(ctx, "/v1/authorizations/"+ref+"/capture", amountBody{amount.Amount, amount.Currency}, nil)
+}
+
+func (g *HTTPGateway) Void(ctx context.Context, ref string) error {
+    return g.post(ctx, "/v1/authorizations/"+ref+"/void", nil, nil)
+}
+
+func (g *HTTPGateway) Refund(ctx context.Context, ref string, amount money.Money) (string, error) {
+    var out referenceBody
+    err := g.post(ctx, "/v1/authorizations/"+ref+"/refunds", amountBody{amount.Amount, amount.Currency}, &out)
+    return out.Reference, err
+}
+
+func (g *HTTPGateway) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+    return verifyWebhook(g.secret, payload, header, g.now())
+}
+
+// post sends body as JSON and decodes the response into out, turning error
+// statuses back into the gateway's sentinel errors.
+func (g *HTTPGateway) post(ctx context.Context, path string, body, out interface{}) error {
+    var buf bytes.Buffer
+    if body != nil {
+        if err := json.NewEncoder(&buf).Encode(body); err != nil {
+            return err
+        }
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+path, &buf)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if g.apiKey != "" {
+        req.Header.Set("Authorization", "Bearer "+g.apiKey)
+    }
+    res, err := g.client.Do(req)
+    if err != nil {
+        return fmt.Errorf("payment gateway: %w", err)
+    }
+    defer res.Body.Close()
+
+    if res.StatusCode >= 300 {
+        var e struct {
+            Error string `json:"error"`
+        }
+        json.NewDecoder(res.Body).Decode(&e)
+        for _, fe := range fakeErrors {
+            if fe.status == res.StatusCode {
+                return fmt.Errorf("%w (%s)", fe.err, e.Error)
+            }
+        }
+        return fmt.Errorf("payment gateway: %s: %s", res.Status, e.Error)
+    }
+    if out != nil {
+        return json.NewDecoder(res.Body).Decode(out)
+    }
+    return nil
+}
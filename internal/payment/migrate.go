@@ -0,0 +1,29 @@
+package payment
+
+import "gorm.io/gorm"
+
+// referenceDDL drops the unique index references had before the orders of
+// a checkout group shared an authorization; AutoMigrate then recreates it
+// as a plain index.
+var referenceDDL = `DO $$ BEGIN
+        IF EXISTS (SELECT 1 FROM pg_indexes
+                   WHERE schemaname = current_schema() AND indexname = 'idx_payments_reference'
+                     AND indexdef LIKE 'CREATE UNIQUE INDEX%') THEN
+            DROP INDEX idx_payments_reference;
+        END IF;
+    END $$`
+
+// refundedDDL gives payments from before refunds existed a zero refunded
+// amount in their own currency rather than the column default's.
+var refundedDDL = `UPDATE payments SET refunded_currency = amount_currency
+    WHERE refunded_amount = 0 AND refunded_currency <> amount_currency`
+
+func Migrate(db *gorm.DB) error {
+    if err := db.Exec(referenceDDL).Error; err != nil {
+        return err
+    }
+    if err := db.AutoMigrate(&Payment{}, &Refund{}, &Operation{}, &WebhookReceipt{}); err != nil {
+        return err
+    }
+    return db.Exec(refundedDDL).Error
+}
@@ -0,0 +1,135 @@
+package payment
+
+import (
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// Payment statuses
+const (
+    StatusAuthorized        = "authorized"
+    StatusPartiallyCaptured = "partially_captured"
+    StatusCaptured          = "captured"
+    StatusPartiallyRefunded = "partially_refunded"
+    StatusRefunded          = "refunded"
+    StatusVoided            = "voided"
+    StatusExpired           = "expired"
+    StatusFailed            = "failed"
+)
+
+// MethodCard is how payments are made: every gateway takes card tokens.
+const MethodCard = "card"
+
+// Payment is the card payment for an order: an authorization when the
+// order is placed, captured when it's accepted. The orders of a checkout
+// group share one authorization: each has its own Payment for its share,
+// with the same Reference and GroupID.
+type Payment struct {
+    ID        string      `json:"id" gorm:"type:uuid;primaryKey"`
+    OrderID   string      `json:"orderId" gorm:"type:uuid;not null;uniqueIndex"`
+    GroupID   *string     `json:"groupId,omitempty" gorm:"type:uuid;index"`
+    Method    string      `json:"method" gorm:"type:varchar(20);not null;default:card"` // how the buyer paid, e.g. MethodCard
+    Provider  string      `json:"provider" gorm:"type:varchar(20);not null"`   // Gateway.Name
+    Reference string      `json:"reference" gorm:"type:varchar(100);not null;index"` // provider's authorization ID
+    Status    string      `json:"status" gorm:"type:varchar(20);not null"`
+    Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`     // authorized for this order
+    Captured  money.Money `json:"captured" gorm:"embedded;embeddedPrefix:captured_"` // taken so far
+    Refunded  money.Money `json:"refunded" gorm:"embedded;embeddedPrefix:refunded_"` // given back so far, see Refund
+    CreatedAt time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+    UpdatedAt time.Time   `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// Refund is one entry in the refunds ledger: money given back on a
+// captured payment, in full when an order is rejected or in part for
+// missing items.
+type Refund struct {
+    ID        string      `json:"id" gorm:"type:uuid;primaryKey"`
+    PaymentID string      `json:"paymentId" gorm:"type:uuid;not null;index"`
+    OrderID   string      `json:"orderId" gorm:"type:uuid;not null;index"`
+    Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+    Reason    string      `json:"reason" gorm:"type:text;not null"`
+    Actor     string      `json:"actor" gorm:"type:varchar(100);not null"` // seller or admin email
+    LineID    *string     `json:"lineId,omitempty" gorm:"type:uuid;index"` // order line refunded, if any
+    Quantity  int         `json:"quantity,omitempty"`                     // portions of that line
+    Reference string      `json:"reference" gorm:"type:varchar(100);not null"` // provider's refund ID
+    CreatedAt time.Time   `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+func (Refund) TableName() string { return "payment_refunds" }
+
+// Operation statuses: pending before the provider is called, then
+// succeeded or failed by its answer, and settled once the payment records
+// it.
+const (
+    OperationPending   = "pending"
+    OperationSucceeded = "succeeded"
+    OperationFailed    = "failed"
+    OperationSettled   = "settled"
+)
+
+// Operation kinds
+const (
+    OperationCapture = "capture"
+    OperationRefund  = "refund"
+)
+
+// Operation is a capture or refund asked of the provider. It is written in
+// a transaction of its own before the call and updated with the answer,
+// so money the provider moved is on record even when the caller's
+// transaction rolls back; it is settled in the caller's transaction with
+// the payment. One that succeeded but never settled is settled by a retry
+// of the same request or by the provider's webhook.
+type Operation struct {
+    ID        string      `json:"id" gorm:"type:uuid;primaryKey"`
+    PaymentID string      `json:"paymentId" gorm:"type:uuid;not null;index"`
+    Kind      string      `json:"kind" gorm:"type:varchar(10);not null"`
+    Status    string      `json:"status" gorm:"type:varchar(10);not null;index"`
+    Amount    money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+    Reason    string      `json:"reason,omitempty" gorm:"type:text"` // refunds: as in RefundRequest
+    Actor     string      `json:"actor,omitempty" gorm:"type:varchar(100)"`
+    LineID    *string     `json:"lineId,omitempty" gorm:"type:uuid"`
+    Quantity  int         `json:"quantity,omitempty"`
+    Reference string      `json:"reference,omitempty" gorm:"type:varchar(100)"` // provider's refund ID
+    CreatedAt time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+    UpdatedAt time.Time   `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Operation) TableName() string { return "payment_operations" }
+
+// same reports whether o asks for what op did: a retry of its request.
+func (op *Operation) same(o *Operation) bool {
+    if op.Kind != o.Kind || op.Amount != o.Amount || op.Quantity != o.Quantity {
+        return false
+    }
+    if op.LineID == nil || o.LineID == nil {
+        return op.LineID == nil && o.LineID == nil
+    }
+    return *op.LineID == *o.LineID
+}
+
+// Share is one order's part of an authorization covering a checkout group.
+type Share struct {
+    OrderID string
+    Amount  money.Money
+}
+
+// RefundRequest asks to give back Amount of an order's payment.
+type RefundRequest struct {
+    OrderID  string
+    Amount   money.Money
+    Reason   string
+    Actor    string
+    LineID   *string
+    Quantity int
+}
+
+// WebhookReceipt records a processed webhook so a redelivery is ignored.
+type WebhookReceipt struct {
+    EventID    string    `gorm:"type:varchar(100);primaryKey"`
+    PaymentID  string    `gorm:"type:uuid;not null;index"`
+    Type       string    `gorm:"type:varchar(40);not null"`
+    ReceivedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (WebhookReceipt) TableName() string { return "payment_webhook_receipts" }
@@ -0,0 +1,150 @@
+package payment
+
+import (
+    "fmt"
+    "log"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Captures and refunds move money at the provider, which the caller's
+// transaction can't take back: after the call it may still fail to post
+// the sale or refund to the books, or to commit. So each is recorded as
+// an Operation in a transaction of its own first, the provider's answer
+// is recorded the same way, and the operation is settled in the caller's
+// transaction along with the payment.
+
+// begin records op as pending before the provider is called.
+func (s *postgresService) begin(op *Operation) error {
+    op.ID, op.Status = uuid.NewString(), OperationPending
+    return s.db.Create(op).Error
+}
+
+// finish records the provider's answer to op. Not in the caller's
+// transaction, nor under its context: the money has moved, or not,
+// whatever happens to it.
+func (s *postgresService) finish(op *Operation, ref string, err error) {
+    op.Status, op.Reference = OperationSucceeded, ref
+    if err != nil {
+        op.Status = OperationFailed
+    }
+    if uerr := s.db.Model(op).Select("status", "reference", "updated_at").Updates(op).Error; uerr != nil {
+        log.Printf("payment: recording %s %s as %s: %v", op.Kind, op.ID, op.Status, uerr)
+    }
+}
+
+// resume looks for p's unsettled operations before want is asked of the
+// provider. One that succeeded and matches want is returned: want retries
+// a request whose transaction rolled back, and settling it must not move
+// the money again. Any other unsettled operation refuses want until it is
+// settled, by its own retry or the provider's webhook; a pending one may
+// or may not have gone through.
+func resume(tx *gorm.DB, p *Payment, want *Operation) (*Operation, error) {
+    var ops []Operation
+    err := tx.Where("payment_id = ? AND status IN ?", p.ID, []string{OperationPending, OperationSucceeded}).
+        Order("created_at").Find(&ops).Error
+    if err != nil {
+        return nil, err
+    }
+    for i := range ops {
+        if ops[i].Status == OperationSucceeded && ops[i].same(want) {
+            return &ops[i], nil
+        }
+    }
+    if len(ops) > 0 {
+        return nil, fmt.Errorf("%w: an earlier %s of %s is unsettled", ErrInvalidState, ops[0].Kind, ops[0].Amount)
+    }
+    return nil, nil
+}
+
+// settleCapture records op's capture on p, in tx.
+func settleCapture(tx *gorm.DB, p *Payment, op *Operation) error {
+    captured, err := p.Captured.Add(op.Amount)
+    if err != nil {
+        return err
+    }
+    p.Captured, p.Status = captured, StatusPartiallyCaptured
+    if captured.Amount >= p.Amount.Amount {
+        p.Status = StatusCaptured
+    }
+    if err := tx.Select("captured_amount", "captured_currency", "status", "updated_at").Updates(p).Error; err != nil {
+        return err
+    }
+    return tx.Model(op).Update("status", OperationSettled).Error
+}
+
+// settleRefund records op's refund on p and in the refunds ledger, in tx.
+func settleRefund(tx *gorm.DB, p *Payment, op *Operation) (*Refund, error) {
+    refunded, err := p.Refunded.Add(op.Amount)
+    if err != nil {
+        return nil, err
+    }
+    r := &Refund{
+        ID:        uuid.NewString(),
+        PaymentID: p.ID,
+        OrderID:   p.OrderID,
+        Amount:    op.Amount,
+        Reason:    op.Reason,
+        Actor:     op.Actor,
+        LineID:    op.LineID,
+        Quantity:  op.Quantity,
+        Reference: op.Reference,
+    }
+    if err := tx.Create(r).Error; err != nil {
+        return nil, err
+    }
+    p.Refunded, p.Status = refunded, StatusPartiallyRefunded
+    if refunded == p.Captured {
+        p.Status = StatusRefunded
+    }
+    if err := tx.Select("refunded_amount", "refunded_currency", "status", "updated_at").Updates(p).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Model(op).Update("status", OperationSettled).Error; err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+// reconcile settles the unsettled operation a captured or refunded
+// webhook reports, if there is one: ours, made for one of ps, whose
+// caller's transaction rolled back after the provider moved the money. It
+// returns the payment settled, or nil.
+func reconcile(tx *gorm.DB, ps []Payment, ev *WebhookEvent) (*Payment, error) {
+    kind := OperationCapture
+    if ev.Type == EventRefunded {
+        kind = OperationRefund
+    }
+    ids := make([]string, len(ps))
+    for i := range ps {
+        ids[i] = ps[i].ID
+    }
+    var ops []Operation
+    err := tx.Where("payment_id IN ? AND kind = ? AND status IN ? AND amount_amount = ? AND amount_currency = ?",
+        ids, kind, []string{OperationPending, OperationSucceeded}, ev.Amount, ev.Currency).
+        Order("created_at").Find(&ops).Error
+    if err != nil || len(ops) == 0 {
+        return nil, err
+    }
+    // one we know went through before one whose answer we never got
+    op := &ops[0]
+    for i := range ops {
+        if ops[i].Status == OperationSucceeded {
+            op = &ops[i]
+            break
+        }
+    }
+    for i := range ps {
+        p := &ps[i]
+        if p.ID != op.PaymentID {
+            continue
+        }
+        if kind == OperationCapture {
+            return p, settleCapture(tx, p, op)
+        }
+        _, err := settleRefund(tx, p, op)
+        return p, err
+    }
+    return nil, nil
+}
@@ -0,0 +1,198 @@
+package payment
+
+import (
+    "errors"
+    "net/http"
+    "testing"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+var errLedger = errors.New("ledger unavailable")
+
+// webhooks collects what a FakeGateway sends.
+type webhooks struct {
+    payloads [][]byte
+    headers  []http.Header
+}
+
+func newFake(hooks *webhooks) *FakeGateway {
+    gw := NewFakeGateway("test")
+    gw.OnEvent = func(payload []byte, signature string) {
+        h := http.Header{}
+        h.Set(SignatureHeader, signature)
+        hooks.payloads = append(hooks.payloads, payload)
+        hooks.headers = append(hooks.headers, h)
+    }
+    return gw
+}
+
+// captured authorizes and captures a payment of 10.00 for a new order.
+func captured(t *testing.T, db *gorm.DB, svc Service) *Payment {
+    t.Helper()
+    orderID := uuid.NewString()
+    var p *Payment
+    err := db.Transaction(func(tx *gorm.DB) error {
+        if _, err := svc.Authorize(tx, AuthorizeRequest{OrderID: orderID, Amount: usd(1000), PaymentMethod: "tok_visa"}); err != nil {
+            return err
+        }
+        var err error
+        p, err = svc.Capture(tx, orderID)
+        return err
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return p
+}
+
+func operations(t *testing.T, db *gorm.DB, paymentID string) []Operation {
+    t.Helper()
+    var ops []Operation
+    if err := db.Where("payment_id = ?", paymentID).Order("created_at").Find(&ops).Error; err != nil {
+        t.Fatal(err)
+    }
+    return ops
+}
+
+func TestRefundRetryAfterRollbackDoesNotRefundAgain(t *testing.T) {
+    db := needDB(t)
+    svc := NewPostgresService(db, NewFakeGateway("test"))
+    p := captured(t, db, svc)
+    req := RefundRequest{OrderID: p.OrderID, Amount: usd(1000), Reason: "rejected", Actor: "seller@example.com"}
+
+    // the refund goes through at the provider, then posting it fails
+    err := db.Transaction(func(tx *gorm.DB) error {
+        if _, err := svc.Refund(tx, req); err != nil {
+            return err
+        }
+        return errLedger
+    })
+    if !errors.Is(err, errLedger) {
+        t.Fatalf("refund: err = %v; want the ledger's", err)
+    }
+    got, _ := svc.ForOrder(p.OrderID)
+    if got.Status != StatusCaptured || got.Refunded.Amount != 0 {
+        t.Errorf("after rollback: %s, refunded %s; want captured, nothing refunded", got.Status, got.Refunded)
+    }
+    if ops := operations(t, db, p.ID); len(ops) != 2 || ops[1].Status != OperationSucceeded || ops[1].Reference == "" {
+        t.Fatalf("operations after rollback: %+v; want the refund succeeded", ops)
+    }
+
+    // another refund waits for that one to settle
+    other := req
+    other.Amount = usd(500)
+    err = db.Transaction(func(tx *gorm.DB) error {
+        _, err := svc.Refund(tx, other)
+        return err
+    })
+    if !errors.Is(err, ErrInvalidState) {
+        t.Errorf("other refund: err = %v; want ErrInvalidState", err)
+    }
+
+    // the retry settles it: the fake would refuse a second full refund
+    var r *Refund
+    err = db.Transaction(func(tx *gorm.DB) error {
+        var err error
+        r, err = svc.Refund(tx, req)
+        return err
+    })
+    if err != nil {
+        t.Fatalf("retry: %v", err)
+    }
+    got, _ = svc.ForOrder(p.OrderID)
+    if got.Status != StatusRefunded || got.Refunded != usd(1000) {
+        t.Errorf("after retry: %s, refunded %s; want refunded, 10.00", got.Status, got.Refunded)
+    }
+    if refunds, _ := svc.Refunds(p.OrderID); len(refunds) != 1 || refunds[0].ID != r.ID || refunds[0].Reference == "" {
+        t.Errorf("refunds: %+v; want the one", refunds)
+    }
+    if ops := operations(t, db, p.ID); ops[1].Status != OperationSettled {
+        t.Errorf("refund operation %s; want settled", ops[1].Status)
+    }
+}
+
+func TestWebhookSettlesRolledBackRefund(t *testing.T) {
+    db := needDB(t)
+    var hooks webhooks
+    svc := NewPostgresService(db, newFake(&hooks))
+    p := captured(t, db, svc)
+
+    err := db.Transaction(func(tx *gorm.DB) error {
+        if _, err := svc.Refund(tx, RefundRequest{OrderID: p.OrderID, Amount: usd(400), Reason: "missing item", Actor: "seller@example.com"}); err != nil {
+            return err
+        }
+        return errLedger
+    })
+    if !errors.Is(err, errLedger) {
+        t.Fatalf("refund: err = %v; want the ledger's", err)
+    }
+    // a capture's then the refund's
+    if len(hooks.payloads) != 2 {
+        t.Fatalf("fake sent %d webhooks; want 2", len(hooks.payloads))
+    }
+    for i := range hooks.payloads {
+        if _, err := svc.HandleWebhook(hooks.payloads[i], hooks.headers[i]); err != nil {
+            t.Fatalf("webhook %d: %v", i, err)
+        }
+    }
+
+    got, _ := svc.ForOrder(p.OrderID)
+    if got.Status != StatusPartiallyRefunded || got.Refunded != usd(400) {
+        t.Errorf("after webhook: %s, refunded %s; want partially refunded, 4.00", got.Status, got.Refunded)
+    }
+    if refunds, _ := svc.Refunds(p.OrderID); len(refunds) != 1 || refunds[0].Reason != "missing item" {
+        t.Errorf("refunds: %+v; want the missing item's", refunds)
+    }
+}
+
+func TestWebhookSettlesRolledBackGroupCapture(t *testing.T) {
+    db := needDB(t)
+    var hooks webhooks
+    svc := NewPostgresService(db, newFake(&hooks))
+    a, b := uuid.NewString(), uuid.NewString()
+    err := db.Transaction(func(tx *gorm.DB) error {
+        _, err := svc.AuthorizeGroup(tx, AuthorizeRequest{OrderID: uuid.NewString(), Amount: usd(1000), PaymentMethod: "tok_visa"},
+            []Share{{OrderID: a, Amount: usd(600)}, {OrderID: b, Amount: usd(400)}})
+        return err
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // accepting a captures its share, then fails to post the sale
+    err = db.Transaction(func(tx *gorm.DB) error {
+        if _, err := svc.Capture(tx, a); err != nil {
+            return err
+        }
+        return errLedger
+    })
+    if !errors.Is(err, errLedger) {
+        t.Fatalf("capture: err = %v; want the ledger's", err)
+    }
+    if len(hooks.payloads) != 1 {
+        t.Fatalf("fake sent %d webhooks; want 1", len(hooks.payloads))
+    }
+    if _, err := svc.HandleWebhook(hooks.payloads[0], hooks.headers[0]); err != nil {
+        t.Fatal(err)
+    }
+
+    pa, _ := svc.ForOrder(a)
+    pb, _ := svc.ForOrder(b)
+    if pa.Status != StatusCaptured || pa.Captured != usd(600) {
+        t.Errorf("a after webhook: %s, captured %s; want captured, 6.00", pa.Status, pa.Captured)
+    }
+    if pb.Status != StatusAuthorized || pb.Captured.Amount != 0 {
+        t.Errorf("b after webhook: %s, captured %s; want authorized, nothing", pb.Status, pb.Captured)
+    }
+
+    // accepting a again finds its capture done
+    err = db.Transaction(func(tx *gorm.DB) error {
+        _, err := svc.Capture(tx, a)
+        return err
+    })
+    if err != nil {
+        t.Errorf("capture again: %v", err)
+    }
+}
@@ -0,0 +1,363 @@
+package payment
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+type postgresService struct {
+    db *gorm.DB
+    gw Gateway
+}
+
+// NewPostgresService returns a payment Service recording payments in
+// Postgres and charging through gw.
+func NewPostgresService(db *gorm.DB, gw Gateway) Service {
+    return &postgresService{db: db, gw: gw}
+}
+
+func (s *postgresService) Authorize(tx *gorm.DB, req AuthorizeRequest) (*Payment, error) {
+    ctx := tx.Statement.Context
+    ref, err := s.gw.Authorize(ctx, req)
+    if err != nil {
+        return nil, err
+    }
+    p := &Payment{
+        ID:        uuid.NewString(),
+        OrderID:   req.OrderID,
+        Method:    MethodCard,
+        Provider:  s.gw.Name(),
+        Reference: ref,
+        Status:    StatusAuthorized,
+        Amount:    req.Amount,
+        Captured:  money.New(0, req.Amount.Currency),
+        Refunded:  money.New(0, req.Amount.Currency),
+    }
+    if err := tx.Create(p).Error; err != nil {
+        // don't leave a hold we have no record of
+        if verr := s.gw.Void(ctx, ref); verr != nil {
+            log.Printf("payment: void of unrecorded authorization %s failed: %v", ref, verr)
+        }
+        return nil, err
+    }
+    return p, nil
+}
+
+func (s *postgresService) AuthorizeGroup(tx *gorm.DB, req AuthorizeRequest, shares []Share) ([]Payment, error) {
+    amounts := make([]money.Money, len(shares))
+    for i, sh := range shares {
+        amounts[i] = sh.Amount
+    }
+    if sum, err := money.Sum(amounts...); err != nil || sum != req.Amount {
+        return nil, fmt.Errorf("%w: shares don't add up to %s", ErrInvalidAmount, req.Amount)
+    }
+    ctx := tx.Statement.Context
+    ref, err := s.gw.Authorize(ctx, req)
+    if err != nil {
+        return nil, err
+    }
+    groupID := req.OrderID
+    ps := make([]Payment, len(shares))
+    for i, sh := range shares {
+        ps[i] = Payment{
+            ID:        uuid.NewString(),
+            OrderID:   sh.OrderID,
+            GroupID:   &groupID,
+            Method:    MethodCard,
+            Provider:  s.gw.Name(),
+            Reference: ref,
+            Status:    StatusAuthorized,
+            Amount:    sh.Amount,
+            Captured:  money.New(0, sh.Amount.Currency),
+            Refunded:  money.New(0, sh.Amount.Currency),
+        }
+    }
+    if err := tx.Create(&ps).Error; err != nil {
+        if verr := s.gw.Void(ctx, ref); verr != nil {
+            log.Printf("payment: void of unrecorded authorization %s failed: %v", ref, verr)
+        }
+        return nil, err
+    }
+    return ps, nil
+}
+
+// locked loads an order's payment, locking it for the rest of tx.
+func locked(tx *gorm.DB, orderID string) (*Payment, error) {
+    var p Payment
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, "order_id = ?", orderID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrPaymentNotFound
+    }
+    return &p, err
+}
+
+func (s *postgresService) Capture(tx *gorm.DB, orderID string) (*Payment, error) {
+    p, err := locked(tx, orderID)
+    if err != nil {
+        return nil, err
+    }
+    switch p.Status {
+    case StatusCaptured:
+        return p, nil
+    case StatusAuthorized, StatusPartiallyCaptured:
+    default:
+        return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.Status)
+    }
+    rest, err := p.Amount.Sub(p.Captured)
+    if err != nil {
+        return nil, err
+    }
+    want := &Operation{PaymentID: p.ID, Kind: OperationCapture, Amount: rest}
+    op, err := resume(tx, p, want)
+    if err != nil {
+        return nil, err
+    }
+    if op == nil {
+        op = want
+        if err := s.begin(op); err != nil {
+            return nil, err
+        }
+        err := s.gw.Capture(tx.Statement.Context, p.Reference, rest)
+        s.finish(op, "", err)
+        if err != nil {
+            return nil, err
+        }
+    }
+    if err := settleCapture(tx, p, op); err != nil {
+        return nil, err
+    }
+    return p, nil
+}
+
+func (s *postgresService) Void(tx *gorm.DB, orderID string) (*Payment, error) {
+    p, group, err := lockedWithGroup(tx, orderID)
+    if err != nil {
+        return nil, err
+    }
+    switch p.Status {
+    case StatusVoided, StatusExpired:
+        return p, nil // the hold is gone either way
+    case StatusAuthorized:
+    default:
+        return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.Status)
+    }
+    // a group's hold is shared: leave it while another order of the group
+    // is still authorized or has taken part of it. A partly captured
+    // hold's remainder lapses with it.
+    release := true
+    for _, other := range group {
+        if other.ID != p.ID && other.Status != StatusVoided && other.Status != StatusExpired {
+            release = false
+        }
+    }
+    if release {
+        if err := s.gw.Void(tx.Statement.Context, p.Reference); err != nil {
+            return nil, err
+        }
+    }
+    p.Status = StatusVoided
+    if err := tx.Model(p).Update("status", p.Status).Error; err != nil {
+        return nil, err
+    }
+    return p, nil
+}
+
+// lockedWithGroup is locked for an order that may be part of a checkout
+// group; the group's payments are all locked, in ID order so concurrent
+// calls for sibling orders queue rather than deadlock, and returned too.
+func lockedWithGroup(tx *gorm.DB, orderID string) (*Payment, []Payment, error) {
+    var p Payment
+    err := tx.First(&p, "order_id = ?", orderID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil, ErrPaymentNotFound
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+    if p.GroupID == nil {
+        lp, err := locked(tx, orderID)
+        return lp, nil, err
+    }
+    var group []Payment
+    err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("group_id = ?", *p.GroupID).Order("id").Find(&group).Error
+    if err != nil {
+        return nil, nil, err
+    }
+    for i := range group {
+        if group[i].OrderID == orderID {
+            return &group[i], group, nil
+        }
+    }
+    return nil, nil, ErrPaymentNotFound
+}
+
+func (s *postgresService) Refund(tx *gorm.DB, req RefundRequest) (*Refund, error) {
+    p, err := locked(tx, req.OrderID)
+    if err != nil {
+        return nil, err
+    }
+    if p.Status != StatusCaptured && p.Status != StatusPartiallyRefunded {
+        return nil, fmt.Errorf("%w: payment is %s", ErrInvalidState, p.Status)
+    }
+    refunded, err := p.Refunded.Add(req.Amount)
+    if err != nil {
+        return nil, err
+    }
+    if req.Amount.Amount <= 0 || refunded.Amount > p.Captured.Amount {
+        left, _ := p.Captured.Sub(p.Refunded)
+        return nil, fmt.Errorf("%w: %s can still be refunded", ErrInvalidAmount, left)
+    }
+    want := &Operation{
+        PaymentID: p.ID,
+        Kind:      OperationRefund,
+        Amount:    req.Amount,
+        Reason:    req.Reason,
+        Actor:     req.Actor,
+        LineID:    req.LineID,
+        Quantity:  req.Quantity,
+    }
+    op, err := resume(tx, p, want)
+    if err != nil {
+        return nil, err
+    }
+    if op == nil {
+        op = want
+        if err := s.begin(op); err != nil {
+            return nil, err
+        }
+        ref, err := s.gw.Refund(tx.Statement.Context, p.Reference, req.Amount)
+        s.finish(op, ref, err)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return settleRefund(tx, p, op)
+}
+
+func (s *postgresService) Refunds(orderID string) ([]Refund, error) {
+    var out []Refund
+    if err := s.db.Where("order_id = ?", orderID).Order("created_at").Find(&out).Error; err != nil {
+        return nil, err
+    }
+    return out, nil
+}
+
+func (s *postgresService) ForOrder(orderID string) (*Payment, error) {
+    var p Payment
+    if err := s.db.First(&p, "order_id = ?", orderID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, ErrPaymentNotFound
+        }
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (s *postgresService) HandleWebhook(payload []byte, header http.Header) ([]Payment, error) {
+    ev, err := s.gw.VerifyWebhook(payload, header)
+    if err != nil {
+        return nil, err
+    }
+    var changed []Payment
+    err = s.db.Transaction(func(tx *gorm.DB) error {
+        // one payment, or one per order of a checkout group
+        var ps []Payment
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("provider = ? AND reference = ?", s.gw.Name(), ev.Reference).Order("id").Find(&ps).Error
+        if err != nil {
+            return err
+        }
+        if len(ps) == 0 {
+            return ErrPaymentNotFound
+        }
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+            Create(&WebhookReceipt{EventID: ev.ID, PaymentID: ps[0].ID, Type: ev.Type})
+        if res.Error != nil || res.RowsAffected == 0 {
+            return res.Error // redelivery
+        }
+
+        if ev.Type == EventCaptured || ev.Type == EventRefunded {
+            p, err := reconcile(tx, ps, ev)
+            if err != nil {
+                return err
+            }
+            if p != nil {
+                changed = append(changed, *p)
+                return nil
+            }
+        }
+        for i := range ps {
+            p := &ps[i]
+            next, err := applyEvent(p, ev, len(ps) > 1)
+            if err != nil {
+                return err
+            }
+            if next == p.Status {
+                continue
+            }
+            p.Status = next
+            if err := tx.Select("captured_amount", "captured_currency", "status", "updated_at").Updates(p).Error; err != nil {
+                return err
+            }
+            changed = append(changed, *p)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    for _, p := range changed {
+        go func(ev event.Event) {
+            event.Bus <- ev
+        }(event.Event{Type: "PaymentUpdated", Data: p})
+    }
+    return changed, nil
+}
+
+// applyEvent returns the status p moves to on ev, adding to p.Captured for
+// a capture. Our own captures, refunds and voids were recorded when we
+// made them, or are settled by reconcile; otherwise the webhook only
+// matters when the change happened at the provider. A capture of a
+// group's shared hold can't be attributed to one order, so only ours
+// count there. A refund can't be told from one of ours already settled,
+// so refunds are left to reconcile.
+func applyEvent(p *Payment, ev *WebhookEvent, shared bool) (string, error) {
+    switch ev.Type {
+    case EventExpired, EventVoided:
+        if p.Status == StatusAuthorized {
+            if ev.Type == EventExpired {
+                return StatusExpired, nil
+            }
+            return StatusVoided, nil
+        }
+    case EventFailed:
+        if p.Status == StatusAuthorized || p.Status == StatusPartiallyCaptured {
+            return StatusFailed, nil
+        }
+    case EventCaptured:
+        if shared {
+            break
+        }
+        if p.Status == StatusAuthorized || p.Status == StatusPartiallyCaptured {
+            captured, err := p.Captured.Add(money.New(ev.Amount, ev.Currency))
+            if err != nil {
+                return p.Status, err
+            }
+            p.Captured = captured
+            if captured.Amount >= p.Amount.Amount {
+                return StatusCaptured, nil
+            }
+            return StatusPartiallyCaptured, nil
+        }
+    }
+    return p.Status, nil
+}
@@ -0,0 +1,35 @@
+package payment
+
+import (
+    "net/http"
+
+    "gorm.io/gorm"
+)
+
+// Service takes payments for orders. The tx arguments let the order
+// service authorize and capture inside its own transaction, so a declined
+// card rolls back the order change; pass the plain db outside one.
+type Service interface {
+    // Authorize holds req.Amount on the buyer's card and records the payment.
+    Authorize(tx *gorm.DB, req AuthorizeRequest) (*Payment, error)
+    // AuthorizeGroup holds req.Amount once for a checkout group and records
+    // a payment per share; req.OrderID is the group's ID and the shares
+    // must add up to req.Amount.
+    AuthorizeGroup(tx *gorm.DB, req AuthorizeRequest, shares []Share) ([]Payment, error)
+    // Capture takes the rest of the order's authorized amount. Capturing a
+    // captured payment is a no-op.
+    Capture(tx *gorm.DB, orderID string) (*Payment, error)
+    // Void releases the hold on an order that won't be fulfilled. A group's
+    // shared hold is only released once none of its orders still need it.
+    Void(tx *gorm.DB, orderID string) (*Payment, error)
+    // Refund gives back part or all of what was captured and records it in
+    // the refunds ledger.
+    Refund(tx *gorm.DB, req RefundRequest) (*Refund, error)
+    ForOrder(orderID string) (*Payment, error)
+    Refunds(orderID string) ([]Refund, error)
+
+    // HandleWebhook verifies a provider webhook and applies it to the
+    // payments of its authorization, returning those that changed.
+    // Redelivered events are ignored.
+    HandleWebhook(payload []byte, header http.Header) ([]Payment, error)
+}
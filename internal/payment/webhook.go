@@ -0,0 +1,84 @@
+package payment
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Webhook event types
+const (
+    EventCaptured = "payment.captured"
+    EventVoided   = "payment.voided"
+    EventRefunded = "payment.refunded"
+    EventExpired  = "authorization.expired"
+    EventFailed   = "payment.failed"
+)
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>" of
+// "<t>.<body>", so a captured webhook can't be replayed much later.
+const SignatureHeader = "Payment-Signature"
+
+// signatureTolerance is how old a webhook's timestamp may be.
+const signatureTolerance = 5 * time.Minute
+
+// WebhookEvent is a provider's notification that a payment changed.
+type WebhookEvent struct {
+    ID        string    `json:"id"` // unique per event, for de-duplication
+    Type      string    `json:"type"`
+    Reference string    `json:"reference"` // authorization reference
+    Amount    int64     `json:"amount"`    // minor units, for captures and refunds
+    Currency  string    `json:"currency"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// SignWebhook returns the SignatureHeader value for payload sent at t.
+func SignWebhook(secret string, payload []byte, t time.Time) string {
+    ts := strconv.FormatInt(t.Unix(), 10)
+    return "t=" + ts + ",v1=" + mac(secret, ts, payload)
+}
+
+func mac(secret, ts string, payload []byte) string {
+    h := hmac.New(sha256.New, []byte(secret))
+    h.Write([]byte(ts + "."))
+    h.Write(payload)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifyWebhook checks the signature header and decodes the event.
+func verifyWebhook(secret string, payload []byte, header http.Header, now time.Time) (*WebhookEvent, error) {
+    var ts, sig string
+    for _, part := range strings.Split(header.Get(SignatureHeader), ",") {
+        k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+        switch k {
+        case "t":
+            ts = v
+        case "v1":
+            sig = v
+        }
+    }
+    sec, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil || sig == "" {
+        return nil, fmt.Errorf("%w: malformed %s header", ErrInvalidWebhook, SignatureHeader)
+    }
+    if age := now.Sub(time.Unix(sec, 0)); age > signatureTolerance || age < -signatureTolerance {
+        return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhook)
+    }
+    if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, payload))) {
+        return nil, ErrInvalidWebhook
+    }
+    var ev WebhookEvent
+    if err := json.Unmarshal(payload, &ev); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+    }
+    if ev.ID == "" || ev.Reference == "" {
+        return nil, fmt.Errorf("%w: event is missing id or reference", ErrInvalidWebhook)
+    }
+    return &ev, nil
+}
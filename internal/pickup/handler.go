@@ -0,0 +1,180 @@
+package pickup
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+const (
+    defaultSlotDays = 7
+    maxSlotDays     = 31
+)
+
+// windowInput is the body of window create/update requests. Pointers tell
+// a missing field from Sunday or midnight.
+type windowInput struct {
+    Weekday  *time.Weekday `json:"weekday" binding:"required"`
+    Start    *Clock        `json:"start" binding:"required"`
+    End      *Clock        `json:"end" binding:"required"`
+    Capacity int           `json:"capacity" binding:"required"`
+}
+
+func (in windowInput) window() Window {
+    return Window{Weekday: *in.Weekday, Start: *in.Start, End: *in.End, Capacity: in.Capacity}
+}
+
+// RegisterRoutes mounts the seller's pickup window endpoints and the
+// per-listing window and slot endpoints.
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service, listings listing.Service) {
+    requireSeller := []gin.HandlerFunc{auth.Middleware(), seller.RequireSeller(sellers)}
+
+    // the authenticated seller's windows
+    me := r.Group("/sellers/me/pickup-windows", requireSeller...)
+
+    // GET /sellers/me/pickup-windows
+    me.GET("", func(c *gin.Context) {
+        ws, err := svc.ListWindows(seller.FromContext(c).ID)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, ws)
+    })
+
+    // POST /sellers/me/pickup-windows — body {"weekday", "start", "end", "capacity"}
+    me.POST("", func(c *gin.Context) {
+        var in windowInput
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        w := in.window()
+        w.SellerID = seller.FromContext(c).ID
+        if err := svc.CreateWindow(&w); err != nil {
+            windowError(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, w)
+    })
+
+    // PUT /sellers/me/pickup-windows/:windowId
+    me.PUT("/:windowId", func(c *gin.Context) {
+        var in windowInput
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        w, err := svc.UpdateWindow(seller.FromContext(c).ID, c.Param("windowId"), in.window())
+        if err != nil {
+            windowError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, w)
+    })
+
+    // DELETE /sellers/me/pickup-windows/:windowId
+    me.DELETE("/:windowId", func(c *gin.Context) {
+        if err := svc.DeleteWindow(seller.FromContext(c).ID, c.Param("windowId")); err != nil {
+            windowError(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    // GET /sellers/:id/pickup-windows — public
+    r.GET("/sellers/:id/pickup-windows", func(c *gin.Context) {
+        ws, err := svc.ListWindows(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, ws)
+    })
+
+    // GET /listings/:id/pickup-windows — the windows the listing declared
+    r.GET("/listings/:id/pickup-windows", func(c *gin.Context) {
+        ws, err := svc.ListingWindows(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, ws)
+    })
+
+    // GET /listings/:id/pickup-slots?from=YYYY-MM-DD&days=7 — upcoming slots with remaining capacity
+    r.GET("/listings/:id/pickup-slots", func(c *gin.Context) {
+        from := time.Now()
+        if raw := c.Query("from"); raw != "" {
+            day, err := time.ParseInLocation(DateLayout, raw, Location())
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+                return
+            }
+            if day.After(from) {
+                from = day
+            }
+        }
+        days := defaultSlotDays
+        if raw := c.Query("days"); raw != "" {
+            n, err := strconv.Atoi(raw)
+            if err != nil || n < 1 || n > maxSlotDays {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 31"})
+                return
+            }
+            days = n
+        }
+        slots, err := svc.ListingSlots(c.Param("id"), from, days)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+            return
+        }
+        c.JSON(http.StatusOK, slots)
+    })
+
+    // PUT /listings/:id/pickup-windows — body {"windowIds": [...]}; an empty list allows all the seller's windows
+    r.PUT("/listings/:id/pickup-windows", append(requireSeller, func(c *gin.Context) {
+        l, err := listings.GetByID(c.Param("id"))
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+            return
+        }
+        sl := seller.FromContext(c)
+        if l.SellerID != sl.ID {
+            c.JSON(http.StatusForbidden, gin.H{"error": "not your listing"})
+            return
+        }
+        var body struct {
+            WindowIDs []string `json:"windowIds"`
+        }
+        if err := c.ShouldBindJSON(&body); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := svc.SetListingWindows(l.ID, sl.ID, body.WindowIDs); err != nil {
+            windowError(c, err)
+            return
+        }
+        ws, _ := svc.ListingWindows(l.ID)
+        c.JSON(http.StatusOK, ws)
+    })...)
+}
+
+// windowError maps pickup window errors to responses.
+func windowError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, ErrWindowNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrInvalidWindow):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
@@ -0,0 +1,8 @@
+package pickup
+
+import "gorm.io/gorm"
+
+// Migrate creates the pickup window, listing window and booking tables.
+func Migrate(db *gorm.DB) error {
+    return db.AutoMigrate(&Window{}, &ListingWindow{}, &SlotBooking{})
+}
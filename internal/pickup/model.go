@@ -0,0 +1,160 @@
+package pickup
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "sort"
+    "time"
+
+    "gorm.io/gorm"
+)
+
+var (
+    ErrWindowNotFound = errors.New("pickup window not found")
+    ErrInvalidWindow  = errors.New("invalid pickup window")
+    ErrInvalidSlot    = errors.New("invalid pickup slot")
+    ErrSlotFull       = errors.New("pickup slot is full")
+)
+
+// DateLayout is the format of slot dates, e.g. "2025-07-04".
+const DateLayout = "2006-01-02"
+
+// Clock is a time of day in minutes since midnight. It reads and writes
+// JSON as "HH:MM".
+type Clock int
+
+func (c Clock) String() string {
+    return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+    return json.Marshal(c.String())
+}
+
+func (c *Clock) UnmarshalJSON(b []byte) error {
+    var s string
+    if err := json.Unmarshal(b, &s); err != nil {
+        return err
+    }
+    t, err := time.Parse("15:04", s)
+    if err != nil {
+        return fmt.Errorf("time of day must be HH:MM, got %q", s)
+    }
+    *c = Clock(t.Hour()*60 + t.Minute())
+    return nil
+}
+
+// Window is a recurring weekly pickup window, e.g. Fridays 17:00–19:00.
+// Each date it falls on is a slot that takes at most Capacity orders.
+type Window struct {
+    ID        string         `json:"id" gorm:"type:uuid;primaryKey"`
+    SellerID  string         `json:"sellerId" gorm:"type:uuid;not null;index"`
+    Weekday   time.Weekday   `json:"weekday" gorm:"not null"` // 0 = Sunday
+    Start     Clock          `json:"start" gorm:"column:start_minute;not null"`
+    End       Clock          `json:"end" gorm:"column:end_minute;not null"`
+    Capacity  int            `json:"capacity" gorm:"not null"` // orders per slot
+    CreatedAt time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+    UpdatedAt time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+    DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Window) TableName() string { return "pickup_windows" }
+
+// validate checks the fields a seller sets.
+func (w Window) validate() error {
+    switch {
+    case w.Weekday < time.Sunday || w.Weekday > time.Saturday:
+        return fmt.Errorf("%w: weekday must be 0 (Sunday) to 6", ErrInvalidWindow)
+    case w.Start < 0 || w.End > 24*60 || w.Start >= w.End:
+        return fmt.Errorf("%w: start must be before end", ErrInvalidWindow)
+    case w.Capacity < 1:
+        return fmt.Errorf("%w: capacity must be at least 1", ErrInvalidWindow)
+    }
+    return nil
+}
+
+// ListingWindow declares that a listing can be picked up in a window.
+// Listings with no declared windows can be picked up in any of their
+// seller's windows.
+type ListingWindow struct {
+    ListingID string `gorm:"type:uuid;primaryKey"`
+    WindowID  string `gorm:"type:uuid;primaryKey;index"`
+}
+
+func (ListingWindow) TableName() string { return "listing_pickup_windows" }
+
+// SlotBooking counts the accepted orders in one slot.
+type SlotBooking struct {
+    WindowID string `gorm:"type:uuid;primaryKey"`
+    Date     string `gorm:"type:varchar(10);primaryKey"`
+    Booked   int    `gorm:"not null;default:0"`
+}
+
+func (SlotBooking) TableName() string { return "pickup_slot_bookings" }
+
+// Slot is one occurrence of a window.
+type Slot struct {
+    WindowID  string    `json:"windowId"`
+    Date      string    `json:"date"`
+    Start     time.Time `json:"start"`
+    End       time.Time `json:"end"`
+    Capacity  int       `json:"capacity"`
+    Booked    int       `json:"booked"`
+    Remaining int       `json:"remaining"`
+}
+
+// Location is the time zone pickup windows are in, from PICKUP_TIMEZONE
+// (an IANA name such as "America/Toronto"; default UTC).
+func Location() *time.Location {
+    if loc, err := time.LoadLocation(os.Getenv("PICKUP_TIMEZONE")); err == nil {
+        return loc
+    }
+    return time.UTC
+}
+
+// SlotOn returns the window's slot on date (a DateLayout string), or
+// ErrInvalidSlot if the window doesn't fall on that day.
+func (w Window) SlotOn(date string, loc *time.Location) (Slot, error) {
+    day, err := time.ParseInLocation(DateLayout, date, loc)
+    if err != nil {
+        return Slot{}, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidSlot)
+    }
+    if day.Weekday() != w.Weekday {
+        return Slot{}, fmt.Errorf("%w: window is on %s, %s is a %s", ErrInvalidSlot, w.Weekday, date, day.Weekday())
+    }
+    return Slot{
+        WindowID:  w.ID,
+        Date:      date,
+        Start:     atClock(day, w.Start),
+        End:       atClock(day, w.End),
+        Capacity:  w.Capacity,
+        Remaining: w.Capacity,
+    }, nil
+}
+
+// atClock is day at the given time of day. Going through time.Date keeps
+// the wall-clock time right across DST changes.
+func atClock(day time.Time, c Clock) time.Time {
+    return time.Date(day.Year(), day.Month(), day.Day(), int(c)/60, int(c)%60, 0, 0, day.Location())
+}
+
+// Upcoming lists the slots of windows on the days starting at from's date,
+// skipping slots that have already started, ordered by start time.
+func Upcoming(windows []Window, from time.Time, days int, loc *time.Location) []Slot {
+    from = from.In(loc)
+    var out []Slot
+    for d := 0; d < days; d++ {
+        date := from.AddDate(0, 0, d).Format(DateLayout)
+        for _, w := range windows {
+            s, err := w.SlotOn(date, loc)
+            if err != nil || !s.Start.After(from) {
+                continue
+            }
+            out = append(out, s)
+        }
+    }
+    sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+    return out
+}
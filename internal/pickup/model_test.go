@@ -0,0 +1,89 @@
+package pickup
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestClockJSON(t *testing.T) {
+    var c Clock
+    if err := json.Unmarshal([]byte(`"17:30"`), &c); err != nil || c != 17*60+30 {
+        t.Fatalf("unmarshal 17:30 = %d, %v", c, err)
+    }
+    if b, _ := json.Marshal(Clock(9 * 60)); string(b) != `"09:00"` {
+        t.Errorf("marshal 09:00 = %s", b)
+    }
+    if err := json.Unmarshal([]byte(`"25:00"`), &c); err == nil {
+        t.Error("unmarshal 25:00 succeeded")
+    }
+}
+
+func TestWindowValidate(t *testing.T) {
+    ok := Window{Weekday: time.Friday, Start: 17 * 60, End: 19 * 60, Capacity: 5}
+    if err := ok.validate(); err != nil {
+        t.Fatalf("valid window: %v", err)
+    }
+    for name, w := range map[string]Window{
+        "end before start": {Weekday: time.Friday, Start: 19 * 60, End: 17 * 60, Capacity: 5},
+        "past midnight":    {Weekday: time.Friday, Start: 23 * 60, End: 25 * 60, Capacity: 5},
+        "no capacity":      {Weekday: time.Friday, Start: 17 * 60, End: 19 * 60},
+        "bad weekday":      {Weekday: 7, Start: 17 * 60, End: 19 * 60, Capacity: 5},
+    } {
+        if err := w.validate(); !errors.Is(err, ErrInvalidWindow) {
+            t.Errorf("%s: err=%v; want ErrInvalidWindow", name, err)
+        }
+    }
+}
+
+func TestSlotOn(t *testing.T) {
+    loc, err := time.LoadLocation("America/Toronto")
+    if err != nil {
+        t.Skip("no tzdata:", err)
+    }
+    w := Window{ID: "w1", Weekday: time.Sunday, Start: 10 * 60, End: 12 * 60, Capacity: 3}
+
+    // 2025-11-02 is the Sunday DST ends in Toronto
+    s, err := w.SlotOn("2025-11-02", loc)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := s.Start.Format("15:04 MST"); got != "10:00 EST" {
+        t.Errorf("start = %s; want 10:00 EST", got)
+    }
+    if s.End.Sub(s.Start) != 2*time.Hour || s.Remaining != 3 {
+        t.Errorf("slot = %+v", s)
+    }
+    if _, err := w.SlotOn("2025-11-03", loc); !errors.Is(err, ErrInvalidSlot) {
+        t.Errorf("Monday: err=%v; want ErrInvalidSlot", err)
+    }
+    if _, err := w.SlotOn("11/02/2025", loc); !errors.Is(err, ErrInvalidSlot) {
+        t.Errorf("bad date: err=%v; want ErrInvalidSlot", err)
+    }
+}
+
+func TestUpcoming(t *testing.T) {
+    ws := []Window{
+        {ID: "fri", Weekday: time.Friday, Start: 17 * 60, End: 19 * 60, Capacity: 1},
+        {ID: "wed-am", Weekday: time.Wednesday, Start: 8 * 60, End: 9 * 60, Capacity: 1},
+        {ID: "wed-pm", Weekday: time.Wednesday, Start: 18 * 60, End: 20 * 60, Capacity: 1},
+    }
+    // Wednesday 2025-07-02 at noon: the morning slot has started
+    from := time.Date(2025, 7, 2, 12, 0, 0, 0, time.UTC)
+    slots := Upcoming(ws, from, 9, time.UTC)
+
+    var got []string
+    for _, s := range slots {
+        got = append(got, s.WindowID+" "+s.Date)
+    }
+    want := []string{"wed-pm 2025-07-02", "fri 2025-07-04", "wed-am 2025-07-09", "wed-pm 2025-07-09"}
+    if len(got) != len(want) {
+        t.Fatalf("slots = %v; want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Fatalf("slots = %v; want %v", got, want)
+        }
+    }
+}
@@ -0,0 +1,254 @@
+package pickup
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+)
+
+// postgresService persists pickup windows in Postgres via GORM.
+type postgresService struct {
+    db *gorm.DB
+}
+
+// NewPostgresService returns a pickup Service backed by Postgres.
+func NewPostgresService(db *gorm.DB) Service {
+    return &postgresService{db: db}
+}
+
+func (s *postgresService) CreateWindow(w *Window) error {
+    if err := w.validate(); err != nil {
+        return err
+    }
+    w.ID = uuid.NewString()
+    return s.db.Create(w).Error
+}
+
+func (s *postgresService) UpdateWindow(sellerID, id string, w Window) (*Window, error) {
+    if err := w.validate(); err != nil {
+        return nil, err
+    }
+    existing, err := s.window(s.db, sellerID, id)
+    if err != nil {
+        return nil, err
+    }
+    // booked slots keep their orders even if capacity drops below them
+    existing.Weekday, existing.Start, existing.End, existing.Capacity = w.Weekday, w.Start, w.End, w.Capacity
+    if err := s.db.Save(existing).Error; err != nil {
+        return nil, err
+    }
+    return existing, nil
+}
+
+func (s *postgresService) DeleteWindow(sellerID, id string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        w, err := s.window(tx, sellerID, id)
+        if err != nil {
+            return err
+        }
+        // orders already placed in the window keep their slot
+        if err := tx.Where("window_id = ?", w.ID).Delete(&ListingWindow{}).Error; err != nil {
+            return err
+        }
+        return tx.Delete(w).Error
+    })
+}
+
+func (s *postgresService) ListWindows(sellerID string) ([]Window, error) {
+    var ws []Window
+    if err := s.db.Where("seller_id = ?", sellerID).Order("weekday, start_minute").Find(&ws).Error; err != nil {
+        return nil, err
+    }
+    return ws, nil
+}
+
+func (s *postgresService) SetListingWindows(listingID, sellerID string, windowIDs []string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        if len(windowIDs) > 0 {
+            var n int64
+            if err := tx.Model(&Window{}).Where("id IN ? AND seller_id = ?", windowIDs, sellerID).Count(&n).Error; err != nil {
+                return err
+            }
+            if int(n) != len(unique(windowIDs)) {
+                return ErrWindowNotFound
+            }
+        }
+        if err := tx.Where("listing_id = ?", listingID).Delete(&ListingWindow{}).Error; err != nil {
+            return err
+        }
+        for _, id := range unique(windowIDs) {
+            if err := tx.Create(&ListingWindow{ListingID: listingID, WindowID: id}).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (s *postgresService) ListingWindows(listingID string) ([]Window, error) {
+    var ws []Window
+    err := s.db.Joins("JOIN listing_pickup_windows lw ON lw.window_id = pickup_windows.id").
+        Where("lw.listing_id = ?", listingID).
+        Order("weekday, start_minute").
+        Find(&ws).Error
+    if err != nil {
+        return nil, err
+    }
+    return ws, nil
+}
+
+func (s *postgresService) ListingSlots(listingID string, from time.Time, days int) ([]Slot, error) {
+    var l listing.Listing
+    if err := s.db.Select("id, seller_id").First(&l, "id = ?", listingID).Error; err != nil {
+        return nil, err
+    }
+    ws, err := s.ListingWindows(listingID)
+    if err != nil {
+        return nil, err
+    }
+    if len(ws) == 0 {
+        if ws, err = s.ListWindows(l.SellerID); err != nil {
+            return nil, err
+        }
+    }
+    slots := Upcoming(ws, from, days, Location())
+    if len(slots) == 0 {
+        return slots, nil
+    }
+
+    ids := make([]string, len(ws))
+    for i, w := range ws {
+        ids[i] = w.ID
+    }
+    var bookings []SlotBooking
+    err = s.db.Where("window_id IN ? AND date BETWEEN ? AND ?", ids, slots[0].Date, slots[len(slots)-1].Date).
+        Find(&bookings).Error
+    if err != nil {
+        return nil, err
+    }
+    booked := make(map[[2]string]int, len(bookings))
+    for _, b := range bookings {
+        booked[[2]string{b.WindowID, b.Date}] = b.Booked
+    }
+    for i := range slots {
+        slots[i].Booked = booked[[2]string{slots[i].WindowID, slots[i].Date}]
+        slots[i].Remaining = max(slots[i].Capacity-slots[i].Booked, 0)
+    }
+    return slots, nil
+}
+
+// window loads one of the seller's windows.
+func (s *postgresService) window(db *gorm.DB, sellerID, id string) (*Window, error) {
+    var w Window
+    if err := db.First(&w, "id = ? AND seller_id = ?", id, sellerID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, ErrWindowNotFound
+        }
+        return nil, err
+    }
+    return &w, nil
+}
+
+// CheckSlot validates a slot for a new order: the window must be the
+// seller's, fall on date, not have started, be allowed by every listing
+// and still have room. Capacity is only booked by Reserve when the order
+// is accepted, so a slot can still fill up in between.
+func CheckSlot(db *gorm.DB, sellerID string, listingIDs []string, windowID, date string) (*Slot, error) {
+    var w Window
+    if err := db.First(&w, "id = ? AND seller_id = ?", windowID, sellerID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil, ErrWindowNotFound
+        }
+        return nil, err
+    }
+    slot, err := w.SlotOn(date, Location())
+    if err != nil {
+        return nil, err
+    }
+    if !slot.Start.After(time.Now()) {
+        return nil, fmt.Errorf("%w: slot has already started", ErrInvalidSlot)
+    }
+
+    var foreign []string
+    if err := db.Model(&listing.Listing{}).Where("id IN ? AND seller_id <> ?", listingIDs, sellerID).Pluck("id", &foreign).Error; err != nil {
+        return nil, err
+    }
+    if len(foreign) > 0 {
+        return nil, fmt.Errorf("%w: listing %s is not sold by this seller", ErrInvalidSlot, foreign[0])
+    }
+    // listings that declare windows must declare this one
+    var declared []ListingWindow
+    if err := db.Where("listing_id IN ?", listingIDs).Find(&declared).Error; err != nil {
+        return nil, err
+    }
+    allowed := make(map[string]bool)
+    for _, lw := range declared {
+        allowed[lw.ListingID] = allowed[lw.ListingID] || lw.WindowID == windowID
+    }
+    for id, ok := range allowed {
+        if !ok {
+            return nil, fmt.Errorf("%w: listing %s is not available in this window", ErrInvalidSlot, id)
+        }
+    }
+
+    var b SlotBooking
+    err = db.Where("window_id = ? AND date = ?", windowID, date).Limit(1).Find(&b).Error
+    if err != nil {
+        return nil, err
+    }
+    if b.Booked >= w.Capacity {
+        return nil, ErrSlotFull
+    }
+    slot.Booked, slot.Remaining = b.Booked, w.Capacity-b.Booked
+    return &slot, nil
+}
+
+// Reserve books one order into a slot, failing with ErrSlotFull if it's at
+// capacity. Run it in the transaction that accepts the order; the upsert
+// locks the booking row, so concurrent accepts can't overbook.
+func Reserve(tx *gorm.DB, windowID, date string) error {
+    // orders placed before their window was deleted still get their slot
+    var w Window
+    if err := tx.Unscoped().First(&w, "id = ?", windowID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return ErrWindowNotFound
+        }
+        return err
+    }
+    res := tx.Exec(`INSERT INTO pickup_slot_bookings (window_id, date, booked) VALUES (?, ?, 1)
+        ON CONFLICT (window_id, date) DO UPDATE SET booked = pickup_slot_bookings.booked + 1
+        WHERE pickup_slot_bookings.booked < ?`, windowID, date, w.Capacity)
+    if res.Error != nil {
+        return res.Error
+    }
+    if res.RowsAffected == 0 {
+        return ErrSlotFull
+    }
+    return nil
+}
+
+// Release gives back a slot booked by Reserve, e.g. when an accepted order
+// is cancelled.
+func Release(tx *gorm.DB, windowID, date string) error {
+    return tx.Model(&SlotBooking{}).
+        Where("window_id = ? AND date = ? AND booked > 0", windowID, date).
+        UpdateColumn("booked", gorm.Expr("booked - 1")).Error
+}
+
+// unique drops repeated IDs, keeping the first occurrence.
+func unique(ids []string) []string {
+    seen := make(map[string]bool, len(ids))
+    var out []string
+    for _, id := range ids {
+        if !seen[id] {
+            seen[id] = true
+            out = append(out, id)
+        }
+    }
+    return out
+}
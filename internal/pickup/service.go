@@ -0,0 +1,22 @@
+package pickup
+
+import "time"
+
+// Service manages sellers' pickup windows and which listings use them.
+// Slot capacity is booked with Reserve/Release inside the order's
+// transaction.
+type Service interface {
+    CreateWindow(w *Window) error
+    UpdateWindow(sellerID, id string, w Window) (*Window, error)
+    DeleteWindow(sellerID, id string) error
+    ListWindows(sellerID string) ([]Window, error)
+
+    // SetListingWindows replaces the windows a listing can be picked up in;
+    // every window must belong to sellerID. An empty list allows them all.
+    SetListingWindows(listingID, sellerID string, windowIDs []string) error
+    ListingWindows(listingID string) ([]Window, error)
+
+    // ListingSlots lists upcoming slots the listing can be ordered for,
+    // with their remaining capacity.
+    ListingSlots(listingID string, from time.Time, days int) ([]Slot, error)
+}
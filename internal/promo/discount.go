@@ -0,0 +1,148 @@
+package promo
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// Discount checks that the promotion applies to items bought at now and
+// works out what it takes off each of them. Items it doesn't cover get a
+// zero discount. Limits are checked separately, by Redeem.
+func (p *Promotion) Discount(items []Item, now time.Time) ([]money.Money, error) {
+    if !p.Active {
+        return nil, ErrUnknownCode
+    }
+    if p.StartsAt != nil && now.Before(*p.StartsAt) {
+        return nil, fmt.Errorf("%w: it starts %s", ErrNotApplicable, p.StartsAt.UTC().Format(time.RFC3339))
+    }
+    if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+        return nil, fmt.Errorf("%w: it has ended", ErrNotApplicable)
+    }
+    if len(items) == 0 {
+        return nil, fmt.Errorf("%w: nothing to discount", ErrNotApplicable)
+    }
+
+    currency := items[0].Amount.Currency
+    var subtotal, eligible int64
+    for _, it := range items {
+        if it.Amount.Currency != currency {
+            return nil, money.ErrCurrencyMismatch
+        }
+        subtotal += it.Amount.Amount
+        if p.covers(it) {
+            eligible += it.Amount.Amount
+        }
+    }
+    if eligible == 0 {
+        return nil, fmt.Errorf("%w: none of these items qualify", ErrNotApplicable)
+    }
+    if !p.MinSubtotal.IsZero() {
+        if p.MinSubtotal.Currency != currency {
+            return nil, fmt.Errorf("%w: it is for orders in %s", ErrNotApplicable, p.MinSubtotal.Currency)
+        }
+        if subtotal < p.MinSubtotal.Amount {
+            return nil, fmt.Errorf("%w: orders must come to at least %s", ErrNotApplicable, p.MinSubtotal)
+        }
+    }
+
+    var off int64
+    switch p.Kind {
+    case KindPercent:
+        off = (eligible*int64(p.PercentOff) + 50) / 100
+    case KindFixed:
+        if p.AmountOff.Currency != currency {
+            return nil, fmt.Errorf("%w: it is for orders in %s", ErrNotApplicable, p.AmountOff.Currency)
+        }
+        off = min(p.AmountOff.Amount, eligible)
+    }
+
+    // spread the discount over the covered items by their amounts; the
+    // last one takes the rounding
+    out := make([]money.Money, len(items))
+    last, given := -1, int64(0)
+    for i, it := range items {
+        out[i] = money.New(0, currency)
+        if p.covers(it) {
+            out[i].Amount = off * it.Amount.Amount / eligible
+            given += out[i].Amount
+            last = i
+        }
+    }
+    out[last].Amount += off - given
+    return out, nil
+}
+
+// Find loads the promotion with code.
+func Find(db *gorm.DB, code string) (*Promotion, error) {
+    var p Promotion
+    err := db.First(&p, "code = ?", NormalizeCode(code)).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrUnknownCode
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &p, nil
+}
+
+// Redeem records one checkout's use of a promotion, one redemption per
+// order it discounted. It locks the promotion so concurrent checkouts
+// can't exceed its limits between the check and the insert.
+func Redeem(tx *gorm.DB, promotionID, userEmail string, rs []Redemption) error {
+    if len(rs) == 0 {
+        return nil // it rounded to nothing
+    }
+    var p Promotion
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, "id = ?", promotionID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return ErrUnknownCode
+    }
+    if err != nil {
+        return err
+    }
+    if !p.Active {
+        return ErrUnknownCode
+    }
+    if p.MaxUses > 0 {
+        n, err := uses(tx.Where("promotion_id = ?", p.ID))
+        if err != nil {
+            return err
+        }
+        if n >= p.MaxUses {
+            return ErrLimitReached
+        }
+    }
+    if p.MaxUsesPerUser > 0 {
+        n, err := uses(tx.Where("promotion_id = ? AND user_email = ?", p.ID, userEmail))
+        if err != nil {
+            return err
+        }
+        if n >= p.MaxUsesPerUser {
+            return fmt.Errorf("%w: you have already used it %d times", ErrLimitReached, n)
+        }
+    }
+    for i := range rs {
+        rs[i].ID, rs[i].PromotionID, rs[i].UserEmail = uuid.NewString(), p.ID, userEmail
+    }
+    return tx.Create(&rs).Error
+}
+
+// uses counts the checkouts among the redemptions q selects.
+func uses(q *gorm.DB) (int, error) {
+    var n int64
+    err := q.Model(&Redemption{}).Select("count(DISTINCT COALESCE(group_id, order_id))").Scan(&n).Error
+    return int(n), err
+}
+
+// Release gives back an order's redemption when the order falls through
+// before the buyer got anything, so the use doesn't count.
+func Release(tx *gorm.DB, orderID string) error {
+    return tx.Where("order_id = ?", orderID).Delete(&Redemption{}).Error
+}
@@ -0,0 +1,88 @@
+package promo
+
+import (
+    "errors"
+    "slices"
+    "testing"
+    "time"
+
+    "gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func cad(cents int64) money.Money { return money.New(cents, "CAD") }
+
+func amounts(ms []money.Money) []int64 {
+    out := make([]int64, len(ms))
+    for i, m := range ms {
+        out[i] = m.Amount
+    }
+    return out
+}
+
+func TestDiscount(t *testing.T) {
+    now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+    later := now.Add(time.Hour)
+    items := []Item{
+        {SellerID: "s1", ListingID: "l1", Amount: cad(1000)},
+        {SellerID: "s1", ListingID: "l2", Amount: cad(500)},
+        {SellerID: "s2", ListingID: "l3", Amount: cad(333)},
+    }
+    for _, tc := range []struct {
+        name string
+        p    Promotion
+        want []int64
+        err  error
+    }{
+        {"percent of everything", Promotion{Kind: KindPercent, PercentOff: 10}, []int64{99, 49, 35}, nil},
+        {"percent rounds half up", Promotion{Kind: KindPercent, PercentOff: 15, ListingIDs: datatypes.JSONSlice[string]{"l3"}}, []int64{0, 0, 50}, nil},
+        {"fixed spread by amount", Promotion{Kind: KindFixed, AmountOff: cad(100), SellerIDs: datatypes.JSONSlice[string]{"s1"}}, []int64{66, 34, 0}, nil},
+        {"fixed capped at eligible", Promotion{Kind: KindFixed, AmountOff: cad(5000), ListingIDs: datatypes.JSONSlice[string]{"l2"}}, []int64{0, 500, 0}, nil},
+        {"minimum met", Promotion{Kind: KindPercent, PercentOff: 50, MinSubtotal: cad(1833), ListingIDs: datatypes.JSONSlice[string]{"l1"}}, []int64{500, 0, 0}, nil},
+        {"minimum not met", Promotion{Kind: KindPercent, PercentOff: 50, MinSubtotal: cad(1834)}, nil, ErrNotApplicable},
+        {"no items covered", Promotion{Kind: KindPercent, PercentOff: 10, SellerIDs: datatypes.JSONSlice[string]{"s9"}}, nil, ErrNotApplicable},
+        {"other currency", Promotion{Kind: KindFixed, AmountOff: money.New(100, "USD")}, nil, ErrNotApplicable},
+        {"not started", Promotion{Kind: KindPercent, PercentOff: 10, StartsAt: &later}, nil, ErrNotApplicable},
+        {"ended", Promotion{Kind: KindPercent, PercentOff: 10, EndsAt: &now}, nil, ErrNotApplicable},
+    } {
+        tc.p.Active = true
+        got, err := tc.p.Discount(items, now)
+        if !errors.Is(err, tc.err) {
+            t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+            continue
+        }
+        if err == nil && !slices.Equal(amounts(got), tc.want) {
+            t.Errorf("%s: discount = %v, want %v", tc.name, amounts(got), tc.want)
+        }
+    }
+
+    inactive := Promotion{Kind: KindPercent, PercentOff: 10}
+    if _, err := inactive.Discount(items, now); !errors.Is(err, ErrUnknownCode) {
+        t.Errorf("inactive promotion: err = %v, want %v", err, ErrUnknownCode)
+    }
+}
+
+func TestNormalizePromotion(t *testing.T) {
+    seller := "s1"
+    p := Promotion{Code: " spring-10 ", Kind: KindPercent, PercentOff: 10, Funding: FundedBySeller,
+        SellerID: &seller, SellerIDs: datatypes.JSONSlice[string]{"s2"}}
+    if err := p.normalize(); err != nil {
+        t.Fatal(err)
+    }
+    if p.Code != "SPRING-10" || len(p.SellerIDs) != 1 || p.SellerIDs[0] != "s1" {
+        t.Errorf("normalized to code %q, sellers %v", p.Code, p.SellerIDs)
+    }
+
+    for _, bad := range []Promotion{
+        {Code: "X", Kind: KindPercent, PercentOff: 10, Funding: FundedByPlatform},
+        {Code: "TEN", Kind: KindPercent, PercentOff: 0, Funding: FundedByPlatform},
+        {Code: "TEN", Kind: KindFixed, Funding: FundedByPlatform},
+        {Code: "TEN", Kind: KindPercent, PercentOff: 10, Funding: FundedBySeller},
+        {Code: "TEN", Kind: KindPercent, PercentOff: 10, Funding: FundedByPlatform, MaxUses: -1},
+    } {
+        if err := bad.normalize(); !errors.Is(err, ErrInvalidPromotion) {
+            t.Errorf("normalize(%+v) = %v, want %v", bad, err, ErrInvalidPromotion)
+        }
+    }
+}
@@ -0,0 +1,118 @@
+package promo
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service) {
+    admin := r.Group("/admin/promotions", auth.Middleware(), auth.RequireAdmin())
+
+    // POST /admin/promotions – platform- or seller-funded
+    admin.POST("", func(c *gin.Context) {
+        var p Promotion
+        if err := c.ShouldBindJSON(&p); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        p.CreatedBy = c.GetString(string(auth.CtxEmailKey))
+        if err := svc.Create(&p); err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, p)
+    })
+
+    // GET /admin/promotions – every promotion, optionally one seller's
+    admin.GET("", func(c *gin.Context) {
+        list, err := svc.List(c.Query("sellerId"))
+        if err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, list)
+    })
+
+    admin.GET("/:id", func(c *gin.Context) {
+        p, err := svc.Get(c.Param("id"))
+        if err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, p)
+    })
+
+    // DELETE /admin/promotions/:id – deactivate; redemptions are kept
+    admin.DELETE("/:id", func(c *gin.Context) {
+        p, err := svc.Deactivate(c.Param("id"))
+        if err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, p)
+    })
+
+    mine := r.Group("/sellers/me/promotions", auth.Middleware(), seller.RequireSeller(sellers))
+
+    // POST /sellers/me/promotions – always funded by the seller and
+    // limited to their listings
+    mine.POST("", func(c *gin.Context) {
+        var p Promotion
+        if err := c.ShouldBindJSON(&p); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        sl := seller.FromContext(c)
+        p.Funding, p.SellerID, p.CreatedBy = FundedBySeller, &sl.ID, sl.Email
+        if err := svc.Create(&p); err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, p)
+    })
+
+    mine.GET("", func(c *gin.Context) {
+        list, err := svc.List(seller.FromContext(c).ID)
+        if err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, list)
+    })
+
+    // DELETE /sellers/me/promotions/:id – deactivate one of the seller's
+    mine.DELETE("/:id", func(c *gin.Context) {
+        p, err := svc.Get(c.Param("id"))
+        if err != nil {
+            promoError(c, err)
+            return
+        }
+        if p.SellerID == nil || *p.SellerID != seller.FromContext(c).ID {
+            c.JSON(http.StatusNotFound, gin.H{"error": ErrPromotionNotFound.Error()})
+            return
+        }
+        if p, err = svc.Deactivate(p.ID); err != nil {
+            promoError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, p)
+    })
+}
+
+func promoError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, ErrInvalidPromotion):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrPromotionNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrCodeTaken):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
@@ -0,0 +1,7 @@
+package promo
+
+import "gorm.io/gorm"
+
+func Migrate(db *gorm.DB) error {
+    return db.AutoMigrate(&Promotion{}, &Redemption{}, &Line{})
+}
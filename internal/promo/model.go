@@ -0,0 +1,181 @@
+// Package promo handles promotion codes: what they take off an order,
+// who they apply to, and how often they can be redeemed.
+package promo
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "slices"
+    "strings"
+    "time"
+
+    "gorm.io/datatypes"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+var (
+    ErrInvalidPromotion  = errors.New("invalid promotion")
+    ErrPromotionNotFound = errors.New("promotion not found")
+    ErrCodeTaken         = errors.New("promotion code already exists")
+    ErrUnknownCode       = errors.New("unknown or inactive promotion code")
+    ErrNotApplicable     = errors.New("promotion code does not apply")
+    ErrLimitReached      = errors.New("promotion code has been used up")
+)
+
+// Kinds of promotion
+const (
+    KindPercent = "percent" // PercentOff of the eligible items
+    KindFixed   = "fixed"   // AmountOff the eligible items, at most their total
+)
+
+// Who pays for the discount: the seller gets less, or the platform makes
+// up the difference.
+const (
+    FundedBySeller   = "seller"
+    FundedByPlatform = "platform"
+)
+
+// Promotion is a code buyers enter at checkout. Empty SellerIDs and
+// ListingIDs mean it applies to any seller or listing; zero limits and
+// minimums mean none.
+type Promotion struct {
+    ID          string `json:"id" gorm:"type:uuid;primaryKey"`
+    Code        string `json:"code" gorm:"type:varchar(32);not null;uniqueIndex"` // stored upper-case
+    Description string `json:"description" gorm:"type:varchar(200)"`
+
+    Kind        string      `json:"kind" gorm:"type:varchar(10);not null"`
+    PercentOff  int         `json:"percentOff,omitempty" gorm:"not null;default:0"` // 1-100
+    AmountOff   money.Money `json:"amountOff" gorm:"embedded;embeddedPrefix:amount_off_"`
+    MinSubtotal money.Money `json:"minSubtotal" gorm:"embedded;embeddedPrefix:min_subtotal_"` // of the whole order
+
+    // Funding says who pays; a seller-funded promotion belongs to
+    // SellerID and only applies to that seller's listings
+    Funding  string  `json:"funding" gorm:"type:varchar(10);not null"`
+    SellerID *string `json:"sellerId,omitempty" gorm:"type:uuid;index"`
+
+    SellerIDs  datatypes.JSONSlice[string] `json:"sellerIds" gorm:"type:jsonb;not null;default:'[]'"`
+    ListingIDs datatypes.JSONSlice[string] `json:"listingIds" gorm:"type:jsonb;not null;default:'[]'"`
+
+    MaxUses        int        `json:"maxUses" gorm:"not null;default:0"` // checkouts, across all buyers
+    MaxUsesPerUser int        `json:"maxUsesPerUser" gorm:"not null;default:0"`
+    StartsAt       *time.Time `json:"startsAt,omitempty"`
+    EndsAt         *time.Time `json:"endsAt,omitempty"`
+    Active         bool       `json:"active" gorm:"not null;default:true"`
+    Uses           int        `json:"uses" gorm:"-"` // see Service.Get
+
+    CreatedBy string    `json:"createdBy" gorm:"type:varchar(100);not null"`
+    CreatedAt time.Time `json:"createdAt"`
+    UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Redemption records a promotion used on an order. The orders of one
+// checkout group redeem it together and count as one use.
+type Redemption struct {
+    ID          string      `json:"id" gorm:"type:uuid;primaryKey"`
+    PromotionID string      `json:"promotionId" gorm:"type:uuid;not null;index"`
+    UserEmail   string      `json:"userEmail" gorm:"type:varchar(100);not null;index"`
+    OrderID     string      `json:"orderId" gorm:"type:uuid;not null;uniqueIndex"`
+    GroupID     *string     `json:"groupId,omitempty" gorm:"type:uuid"`
+    Amount      money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+    CreatedAt   time.Time   `json:"createdAt"`
+}
+
+func (Redemption) TableName() string { return "promo_redemptions" }
+
+// Line is the discount a promotion took off an order.
+type Line struct {
+    ID          string      `json:"-" gorm:"type:uuid;primaryKey"`
+    OrderID     string      `json:"-" gorm:"type:uuid;not null;index"`
+    PromotionID string      `json:"promotionId" gorm:"type:uuid;not null"`
+    Code        string      `json:"code" gorm:"type:varchar(32);not null"`
+    Description string      `json:"description" gorm:"type:varchar(200)"`
+    Funding     string      `json:"funding" gorm:"type:varchar(10);not null"`
+    Amount      money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // taken off the order
+}
+
+func (Line) TableName() string { return "order_discounts" }
+
+// Label names the discount for receipts.
+func (l Line) Label() string {
+    return "Discount " + l.Code
+}
+
+var codeRe = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// NormalizeCode is how codes are stored and looked up.
+func NormalizeCode(code string) string {
+    return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// normalize cleans up and validates a new promotion.
+func (p *Promotion) normalize() error {
+    p.Code = NormalizeCode(p.Code)
+    p.Description = strings.TrimSpace(p.Description)
+    if !codeRe.MatchString(p.Code) {
+        return fmt.Errorf("%w: code must be 3-32 letters, digits, _ or -", ErrInvalidPromotion)
+    }
+    switch p.Kind {
+    case KindPercent:
+        if p.PercentOff < 1 || p.PercentOff > 100 {
+            return fmt.Errorf("%w: percentOff must be between 1 and 100", ErrInvalidPromotion)
+        }
+        p.AmountOff = money.Money{}
+    case KindFixed:
+        if p.AmountOff.Amount <= 0 || !money.ValidCurrency(p.AmountOff.Currency) {
+            return fmt.Errorf("%w: amountOff must be positive", ErrInvalidPromotion)
+        }
+        p.PercentOff = 0
+    default:
+        return fmt.Errorf("%w: kind must be %s or %s", ErrInvalidPromotion, KindPercent, KindFixed)
+    }
+    if p.MinSubtotal.IsNegative() || (!p.MinSubtotal.IsZero() && !money.ValidCurrency(p.MinSubtotal.Currency)) {
+        return fmt.Errorf("%w: invalid minSubtotal", ErrInvalidPromotion)
+    }
+    if p.Kind == KindFixed && !p.MinSubtotal.IsZero() && p.MinSubtotal.Currency != p.AmountOff.Currency {
+        return fmt.Errorf("%w: minSubtotal and amountOff must be in one currency", ErrInvalidPromotion)
+    }
+    switch p.Funding {
+    case FundedBySeller:
+        if p.SellerID == nil || *p.SellerID == "" {
+            return fmt.Errorf("%w: a seller-funded promotion needs sellerId", ErrInvalidPromotion)
+        }
+        // only the funding seller's listings can be discounted
+        p.SellerIDs = datatypes.JSONSlice[string]{*p.SellerID}
+    case FundedByPlatform:
+        p.SellerID = nil
+    default:
+        return fmt.Errorf("%w: funding must be %s or %s", ErrInvalidPromotion, FundedBySeller, FundedByPlatform)
+    }
+    if p.SellerIDs == nil {
+        p.SellerIDs = datatypes.JSONSlice[string]{}
+    }
+    if p.ListingIDs == nil {
+        p.ListingIDs = datatypes.JSONSlice[string]{}
+    }
+    slices.Sort(p.SellerIDs)
+    p.SellerIDs = slices.Compact(p.SellerIDs)
+    slices.Sort(p.ListingIDs)
+    p.ListingIDs = slices.Compact(p.ListingIDs)
+    if p.MaxUses < 0 || p.MaxUsesPerUser < 0 {
+        return fmt.Errorf("%w: limits can't be negative", ErrInvalidPromotion)
+    }
+    if p.StartsAt != nil && p.EndsAt != nil && !p.StartsAt.Before(*p.EndsAt) {
+        return fmt.Errorf("%w: startsAt must be before endsAt", ErrInvalidPromotion)
+    }
+    return nil
+}
+
+// Item is an order line a promotion might discount.
+type Item struct {
+    SellerID  string
+    ListingID string
+    Amount    money.Money
+}
+
+// covers reports whether the promotion can discount it.
+func (p *Promotion) covers(it Item) bool {
+    return (len(p.SellerIDs) == 0 || slices.Contains(p.SellerIDs, it.SellerID)) &&
+        (len(p.ListingIDs) == 0 || slices.Contains(p.ListingIDs, it.ListingID))
+}
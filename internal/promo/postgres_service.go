@@ -0,0 +1,126 @@
+package promo
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/listing"
+)
+
+type postgresService struct {
+    db *gorm.DB
+}
+
+func NewPostgresService(db *gorm.DB) Service {
+    return &postgresService{db: db}
+}
+
+func (s *postgresService) Create(p *Promotion) error {
+    if err := p.normalize(); err != nil {
+        return err
+    }
+    if err := s.checkListings(p); err != nil {
+        return err
+    }
+    p.ID, p.Active = uuid.NewString(), true
+    err := s.db.Create(p).Error
+    if err != nil && (errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "idx_promotions_code")) {
+        return ErrCodeTaken
+    }
+    return err
+}
+
+// checkListings makes sure the listings a promotion is limited to exist
+// and, for seller-funded ones, belong to the seller.
+func (s *postgresService) checkListings(p *Promotion) error {
+    if len(p.ListingIDs) == 0 {
+        return nil
+    }
+    q := s.db.Model(&listing.Listing{}).Where("id IN ?", []string(p.ListingIDs))
+    if p.SellerID != nil {
+        q = q.Where("seller_id = ?", *p.SellerID)
+    }
+    var n int64
+    if err := q.Count(&n).Error; err != nil {
+        return err
+    }
+    if int(n) != len(p.ListingIDs) {
+        return fmt.Errorf("%w: unknown listing, or one of another seller", ErrInvalidPromotion)
+    }
+    return nil
+}
+
+func (s *postgresService) List(sellerID string) ([]Promotion, error) {
+    q := s.db.Order("created_at DESC, id")
+    if sellerID != "" {
+        q = q.Where("seller_id = ?", sellerID)
+    }
+    var list []Promotion
+    if err := q.Find(&list).Error; err != nil {
+        return nil, err
+    }
+    if err := s.countUses(list); err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (s *postgresService) Get(id string) (*Promotion, error) {
+    var p Promotion
+    err := s.db.First(&p, "id = ?", id).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrPromotionNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    list := []Promotion{p}
+    if err := s.countUses(list); err != nil {
+        return nil, err
+    }
+    return &list[0], nil
+}
+
+func (s *postgresService) Deactivate(id string) (*Promotion, error) {
+    res := s.db.Model(&Promotion{}).Where("id = ?", id).Update("active", false)
+    if res.Error != nil {
+        return nil, res.Error
+    }
+    if res.RowsAffected == 0 {
+        return nil, ErrPromotionNotFound
+    }
+    return s.Get(id)
+}
+
+// countUses fills in Uses, counting a checkout group once.
+func (s *postgresService) countUses(list []Promotion) error {
+    if len(list) == 0 {
+        return nil
+    }
+    ids := make([]string, len(list))
+    for i, p := range list {
+        ids[i] = p.ID
+    }
+    var counts []struct {
+        PromotionID string
+        Uses        int
+    }
+    err := s.db.Model(&Redemption{}).
+        Select("promotion_id, count(DISTINCT COALESCE(group_id, order_id)) AS uses").
+        Where("promotion_id IN ?", ids).Group("promotion_id").Scan(&counts).Error
+    if err != nil {
+        return err
+    }
+    byID := make(map[string]int, len(counts))
+    for _, c := range counts {
+        byID[c.PromotionID] = c.Uses
+    }
+    for i := range list {
+        list[i].Uses = byID[list[i].ID]
+    }
+    return nil
+}
@@ -0,0 +1,14 @@
+package promo
+
+// Service manages promotions. Applying and redeeming them happens while
+// orders are placed, see Promotion.Discount and Redeem.
+type Service interface {
+    Create(p *Promotion) error
+    // List returns the promotions funded by sellerID, or all of them if
+    // sellerID is empty, newest first.
+    List(sellerID string) ([]Promotion, error)
+    Get(id string) (*Promotion, error)
+    // Deactivate stops a promotion from being used; orders placed with it
+    // keep their discount.
+    Deactivate(id string) (*Promotion, error)
+}
@@ -0,0 +1,60 @@
+package receipt
+
+import (
+    "errors"
+    "log"
+    "net/http"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+    "github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, orders order.Service, sellers seller.Service) {
+    // GET /orders/:id/receipt – buyer, seller or admin; PDF unless ?format=json
+    r.GET("/orders/:id/receipt", auth.Middleware(), func(c *gin.Context) {
+        o, err := orders.GetByID(c.Param("id"))
+        if err != nil {
+            if errors.Is(err, order.ErrOrderNotFound) {
+                c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                return
+            }
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        if !canView(c, sellers, o) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "no access"})
+            return
+        }
+        rc, err := svc.ForOrder(o)
+        if err != nil {
+            if errors.Is(err, ErrNotCompleted) {
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+                return
+            }
+            log.Printf("receipt for order %s: %v", o.ID, err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        switch c.DefaultQuery("format", "pdf") {
+        case "json":
+            c.JSON(http.StatusOK, rc)
+        case "pdf":
+            c.Header("Content-Disposition", `inline; filename="`+Filename(rc)+`"`)
+            c.Data(http.StatusOK, "application/pdf", RenderPDF(rc))
+        default:
+            c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or json"})
+        }
+    })
+}
+
+// canView reports whether the caller is the order's buyer, its seller or an admin.
+func canView(c *gin.Context, sellers seller.Service, o *order.Order) bool {
+    email := c.GetString(string(auth.CtxEmailKey))
+    if email == o.UserEmail || auth.IsAdmin(email) {
+        return true
+    }
+    sl, err := sellers.GetByEmail(email)
+    return err == nil && sl.ID == o.SellerID
+}
@@ -0,0 +1,111 @@
+// Package receipt builds receipts for completed orders, as JSON or as a
+// PDF, and emails them to buyers.
+package receipt
+
+import (
+    "errors"
+    "strings"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+var ErrNotCompleted = errors.New("receipts are issued once an order is completed")
+
+// Receipt is what the buyer paid for one completed order.
+type Receipt struct {
+    Number       string          `json:"number"`
+    OrderID      string          `json:"orderId"`
+    IssuedAt     time.Time       `json:"issuedAt"` // when the order was handed over
+    BuyerEmail   string          `json:"buyerEmail"`
+    Seller       SellerDetails   `json:"seller"`
+    Lines        []Line          `json:"lines"`
+    Subtotal     money.Money     `json:"subtotal"`
+    Discounts    []promo.Line    `json:"discounts"`
+    Taxes        []tax.Line      `json:"taxes"`
+    TaxInclusive bool            `json:"taxInclusive"` // the taxes are part of the prices, not added to Subtotal
+    Total        money.Money     `json:"total"`
+    Refunded     money.Money     `json:"refunded"`
+    Payment      *PaymentDetails `json:"payment,omitempty"`
+    PickupStart  *time.Time      `json:"pickupStart,omitempty"`
+    PickupEnd    *time.Time      `json:"pickupEnd,omitempty"`
+}
+
+type SellerDetails struct {
+    ID    string `json:"id"`
+    Name  string `json:"name"`
+    Email string `json:"email"`
+    Phone string `json:"phone"`
+}
+
+type Line struct {
+    Title     string      `json:"title"`
+    Quantity  int         `json:"quantity"`
+    UnitPrice money.Money `json:"unitPrice"`
+    Total     money.Money `json:"total"`
+}
+
+// PaymentDetails says how the order was paid. Card details stay with the
+// provider; the reference identifies the payment there.
+type PaymentDetails struct {
+    Method    string      `json:"method"`
+    Provider  string      `json:"provider"`
+    Reference string      `json:"reference"`
+    Status    string      `json:"status"`
+    Captured  money.Money `json:"captured"`
+}
+
+// Number is the receipt number printed for an order.
+func Number(orderID string) string {
+    id := strings.ToUpper(strings.ReplaceAll(orderID, "-", ""))
+    return "R-" + id[:min(len(id), 12)]
+}
+
+// Build makes the receipt for a completed order sold by s. now dates
+// receipts of orders completed before completion times were recorded.
+func Build(o *order.Order, s *seller.Seller, now time.Time) (*Receipt, error) {
+    if o.Status != order.StatusCompleted {
+        return nil, ErrNotCompleted
+    }
+    r := &Receipt{
+        Number:       Number(o.ID),
+        OrderID:      o.ID,
+        IssuedAt:     now,
+        BuyerEmail:   o.UserEmail,
+        Seller:       SellerDetails{ID: s.ID, Name: s.Name, Email: s.Email, Phone: s.Phone},
+        Lines:        []Line{},
+        Subtotal:     o.Subtotal,
+        Discounts:    o.Discounts,
+        Taxes:        o.Taxes,
+        TaxInclusive: o.TaxInclusive,
+        Total:        o.Total,
+        Refunded:     money.New(0, o.Total.Currency),
+        PickupStart:  o.PickupStart,
+        PickupEnd:    o.PickupEnd,
+    }
+    if r.Discounts == nil {
+        r.Discounts = []promo.Line{}
+    }
+    if r.Taxes == nil {
+        r.Taxes = []tax.Line{}
+    }
+    if o.CompletedAt != nil {
+        r.IssuedAt = *o.CompletedAt
+    }
+    for _, l := range o.Lines {
+        r.Lines = append(r.Lines, Line{Title: l.Title, Quantity: l.Quantity, UnitPrice: l.UnitPrice, Total: l.Total})
+    }
+    if len(r.Lines) == 0 {
+        // orders from before lines were recorded only have a total
+        r.Lines = append(r.Lines, Line{Title: "Order items", Quantity: 1, UnitPrice: o.Subtotal, Total: o.Subtotal})
+    }
+    if p := o.Payment; p != nil {
+        r.Payment = &PaymentDetails{Method: p.Method, Provider: p.Provider, Reference: p.Reference, Status: p.Status, Captured: p.Captured}
+        r.Refunded = p.Refunded
+    }
+    return r, nil
+}
@@ -0,0 +1,242 @@
+package receipt
+
+import (
+    "bytes"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+// The receipt PDF is written by hand: one A4 page per screenful of lines,
+// using the standard 14 fonts so nothing has to be embedded.
+
+const (
+    pageWidth  = 595.0
+    pageHeight = 842.0
+    margin     = 50.0
+    lineHeight = 16.0
+)
+
+// fonts as named in each page's resource dictionary
+const (
+    fontRegular = "F1" // Helvetica
+    fontBold    = "F2" // Helvetica-Bold
+    fontMono    = "F3" // Courier, so amounts line up on the right
+)
+
+// page collects the content stream of the page being laid out.
+type page struct {
+    content bytes.Buffer
+    y       float64
+}
+
+type pdfWriter struct {
+    pages []*page
+}
+
+func (w *pdfWriter) current() *page {
+    if len(w.pages) == 0 {
+        w.newPage()
+    }
+    return w.pages[len(w.pages)-1]
+}
+
+func (w *pdfWriter) newPage() {
+    w.pages = append(w.pages, &page{y: pageHeight - margin})
+}
+
+// line moves down one line, starting a new page when this one is full.
+func (w *pdfWriter) line(gap float64) *page {
+    p := w.current()
+    if p.y-gap < margin {
+        w.newPage()
+        p = w.current()
+    }
+    p.y -= gap
+    return p
+}
+
+func (p *page) text(font string, size, x float64, s string) {
+    fmt.Fprintf(&p.content, "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.y, escapePDF(s))
+}
+
+// right writes s in the fixed-width font so that it ends at x.
+func (p *page) right(size, x float64, s string) {
+    width := 0.6 * size * float64(len([]rune(s)))
+    p.text(fontMono, size, x-width, s)
+}
+
+func (p *page) rule() {
+    fmt.Fprintf(&p.content, "%.2f %.2f m %.2f %.2f l S\n", margin, p.y-6, pageWidth-margin, p.y-6)
+}
+
+// RenderPDF lays the receipt out as a PDF document.
+func RenderPDF(r *Receipt) []byte {
+    w := &pdfWriter{}
+    right := pageWidth - margin
+
+    p := w.line(0)
+    p.text(fontBold, 20, margin, "Receipt")
+    p.right(10, right, r.Number)
+    p = w.line(lineHeight * 1.5)
+    p.text(fontRegular, 10, margin, "Order "+r.OrderID)
+    p.right(10, right, r.IssuedAt.UTC().Format("2006-01-02 15:04 UTC"))
+
+    p = w.line(lineHeight * 2)
+    p.text(fontBold, 11, margin, "Sold by")
+    p.text(fontBold, 11, 320, "Billed to")
+    p = w.line(lineHeight)
+    p.text(fontRegular, 10, margin, r.Seller.Name)
+    p.text(fontRegular, 10, 320, r.BuyerEmail)
+    p = w.line(lineHeight)
+    p.text(fontRegular, 10, margin, r.Seller.Email)
+    if r.Seller.Phone != "" {
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, margin, r.Seller.Phone)
+    }
+    if r.PickupStart != nil && r.PickupEnd != nil {
+        p = w.line(lineHeight * 1.5)
+        p.text(fontBold, 10, margin, "Pickup")
+        p.text(fontRegular, 10, margin+60, pickupWindow(*r.PickupStart, *r.PickupEnd))
+    }
+
+    p = w.line(lineHeight * 2)
+    p.text(fontBold, 10, margin, "Item")
+    p.text(fontBold, 10, 330, "Qty")
+    p.text(fontBold, 10, 390, "Unit price")
+    p.text(fontBold, 10, right-30, "Total")
+    p.rule()
+    p = w.line(6)
+    for _, l := range r.Lines {
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, margin, truncate(l.Title, 48))
+        p.right(10, 350, fmt.Sprint(l.Quantity))
+        p.right(10, 460, l.UnitPrice.Decimal())
+        p.right(10, right, l.Total.Decimal())
+    }
+    p.rule()
+    p = w.line(6)
+
+    p = w.line(lineHeight)
+    p.text(fontRegular, 10, 330, "Subtotal")
+    p.right(10, right, r.Subtotal.Decimal())
+    for _, d := range r.Discounts {
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, 330, d.Label())
+        p.right(10, right, "-"+d.Amount.Decimal())
+    }
+    for _, t := range r.Taxes {
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, 330, taxLabel(t))
+        p.right(10, right, t.Amount.Decimal())
+    }
+    p = w.line(lineHeight)
+    p.text(fontBold, 11, 330, "Total")
+    p.right(11, right, r.Total.String())
+    if !r.Refunded.IsZero() {
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, 330, "Refunded")
+        p.right(10, right, "-"+r.Refunded.String())
+    }
+
+    if pay := r.Payment; pay != nil {
+        p = w.line(lineHeight * 2)
+        p.text(fontBold, 10, margin, "Payment")
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, margin, fmt.Sprintf("Paid by %s via %s, %s", pay.Method, pay.Provider, strings.ReplaceAll(pay.Status, "_", " ")))
+        p = w.line(lineHeight)
+        p.text(fontRegular, 10, margin, "Reference "+pay.Reference)
+    }
+    return w.bytes()
+}
+
+// taxLabel names a tax line, noting taxes that were already in the prices.
+func taxLabel(t tax.Line) string {
+    label := truncate(t.Label(), 20)
+    if t.Included {
+        label += " (incl.)"
+    }
+    return label
+}
+
+func pickupWindow(start, end time.Time) string {
+    start, end = start.UTC(), end.UTC()
+    if start.Format("2006-01-02") == end.Format("2006-01-02") {
+        return start.Format("2006-01-02 15:04") + " - " + end.Format("15:04 UTC")
+    }
+    return start.Format("2006-01-02 15:04") + " - " + end.Format("2006-01-02 15:04 UTC")
+}
+
+func truncate(s string, n int) string {
+    r := []rune(s)
+    if len(r) <= n {
+        return s
+    }
+    return string(r[:n-3]) + "..."
+}
+
+// bytes writes the document: catalog, page tree, fonts, then a page and
+// content stream per page, followed by the cross-reference table.
+func (w *pdfWriter) bytes() []byte {
+    var out bytes.Buffer
+    var offsets []int
+    obj := func(body string) {
+        offsets = append(offsets, out.Len())
+        fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+    }
+
+    out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+    pages := w.pages
+    if len(pages) == 0 {
+        w.newPage()
+        pages = w.pages
+    }
+    // objects 1-5 are fixed; page i is object 6+2i and its content 7+2i
+    kids := make([]string, len(pages))
+    for i := range pages {
+        kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+    }
+    obj("<< /Type /Catalog /Pages 2 0 R >>")
+    obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+    for _, name := range []string{"Helvetica", "Helvetica-Bold", "Courier"} {
+        obj("<< /Type /Font /Subtype /Type1 /BaseFont /" + name + " /Encoding /WinAnsiEncoding >>")
+    }
+    for i, p := range pages {
+        obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] "+
+            "/Resources << /Font << /%s 3 0 R /%s 4 0 R /%s 5 0 R >> >> /Contents %d 0 R >>",
+            pageWidth, pageHeight, fontRegular, fontBold, fontMono, 7+2*i))
+        obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+    }
+
+    xref := out.Len()
+    fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+    for _, off := range offsets {
+        fmt.Fprintf(&out, "%010d 00000 n \n", off)
+    }
+    fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+    return out.Bytes()
+}
+
+// escapePDF turns s into the body of a PDF string in WinAnsiEncoding.
+// Characters the standard fonts cannot show become '?'.
+func escapePDF(s string) string {
+    var b strings.Builder
+    for _, r := range s {
+        switch {
+        case r == '(' || r == ')' || r == '\\':
+            b.WriteByte('\\')
+            b.WriteRune(r)
+        case r == '€':
+            b.WriteString(`\200`)
+        case r >= 0x20 && r < 0x7f:
+            b.WriteRune(r)
+        case r >= 0xa0 && r <= 0xff:
+            fmt.Fprintf(&b, "\\%03o", r)
+        default:
+            b.WriteByte('?')
+        }
+    }
+    return b.String()
+}
@@ -0,0 +1,133 @@
+package receipt
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "regexp"
+    "strconv"
+    "testing"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/payment"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/promo"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/tax"
+)
+
+func completedOrder() *order.Order {
+    done := time.Date(2026, 3, 14, 18, 5, 0, 0, time.UTC)
+    return &order.Order{
+        ID:          "3f2a9c1e-7b44-4d1a-9e0f-123456789abc",
+        UserEmail:   "buyer@example.com",
+        SellerID:    "s1",
+        Status:      order.StatusCompleted,
+        Subtotal:    money.New(2350, "CAD"),
+        Total:       money.New(2390, "CAD"),
+        CompletedAt: &done,
+        Lines: []order.Line{
+            {Title: "Butter chicken (large)", Quantity: 2, UnitPrice: money.New(900, "CAD"), Total: money.New(1800, "CAD")},
+            {Title: "Naan", Quantity: 1, UnitPrice: money.New(550, "CAD"), Total: money.New(550, "CAD")},
+        },
+        Taxes: []tax.Line{
+            {Jurisdiction: "Ontario", Name: "HST", Percent: 130000, Taxable: money.New(2115, "CAD"), Amount: money.New(275, "CAD")},
+        },
+        Discounts: []promo.Line{
+            {Code: "SPRING10", Funding: promo.FundedByPlatform, Amount: money.New(235, "CAD")},
+        },
+        Payment: &payment.Payment{
+            Method:    payment.MethodCard,
+            Provider:  "fake",
+            Reference: "auth_123",
+            Status:    payment.StatusPartiallyRefunded,
+            Captured:  money.New(2390, "CAD"),
+            Refunded:  money.New(550, "CAD"),
+        },
+    }
+}
+
+func TestBuild(t *testing.T) {
+    o := completedOrder()
+    s := &seller.Seller{ID: "s1", Name: "Asha's Kitchen", Email: "asha@example.com", Phone: "555-0100"}
+    r, err := Build(o, s, time.Now())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if r.Number != "R-3F2A9C1E7B44" {
+        t.Errorf("number = %s", r.Number)
+    }
+    if !r.IssuedAt.Equal(*o.CompletedAt) {
+        t.Errorf("issued at %v, want completion time", r.IssuedAt)
+    }
+    if len(r.Lines) != 2 || r.Subtotal != money.New(2350, "CAD") || r.Total != money.New(2390, "CAD") {
+        t.Errorf("lines %d, subtotal %v, total %v", len(r.Lines), r.Subtotal, r.Total)
+    }
+    if len(r.Discounts) != 1 || r.Discounts[0].Label() != "Discount SPRING10" {
+        t.Errorf("discounts = %+v", r.Discounts)
+    }
+    if len(r.Taxes) != 1 || r.Taxes[0].Label() != "HST 13%" {
+        t.Errorf("taxes = %+v", r.Taxes)
+    }
+    if r.Refunded != money.New(550, "CAD") || r.Payment == nil || r.Payment.Reference != "auth_123" || r.Payment.Method != payment.MethodCard {
+        t.Errorf("payment %+v, refunded %v", r.Payment, r.Refunded)
+    }
+
+    o.Lines, o.Taxes, o.Discounts, o.Payment = nil, nil, nil, nil
+    r, _ = Build(o, s, time.Now())
+    if len(r.Lines) != 1 || r.Lines[0].Total != o.Subtotal || !r.Refunded.IsZero() || r.Taxes == nil || r.Discounts == nil {
+        t.Errorf("order without lines: %+v", r)
+    }
+
+    o.Status = order.StatusReady
+    if _, err := Build(o, s, time.Now()); !errors.Is(err, ErrNotCompleted) {
+        t.Errorf("ready order: err = %v", err)
+    }
+}
+
+func TestRenderPDF(t *testing.T) {
+    s := &seller.Seller{ID: "s1", Name: "Café (Main St) \\ 北", Email: "cafe@example.com"}
+    o := completedOrder()
+    for i := 0; i < 60; i++ {
+        o.Lines = append(o.Lines, order.Line{Title: fmt.Sprint("Item ", i), Quantity: 1, UnitPrice: money.New(1, "CAD"), Total: money.New(1, "CAD")})
+    }
+    r, err := Build(o, s, time.Now())
+    if err != nil {
+        t.Fatal(err)
+    }
+    doc := RenderPDF(r)
+
+    if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+        t.Fatal("missing PDF header or trailer")
+    }
+    if !bytes.Contains(doc, []byte(`(Caf\351 \(Main St\) \\ ?) Tj`)) {
+        t.Error("seller name not escaped for WinAnsi")
+    }
+    if !bytes.Contains(doc, []byte("(HST 13%) Tj")) {
+        t.Error("tax line missing")
+    }
+    if !bytes.Contains(doc, []byte("(Discount SPRING10) Tj")) || !bytes.Contains(doc, []byte("(-2.35) Tj")) {
+        t.Error("discount line missing")
+    }
+    if !bytes.Contains(doc, []byte("/Count 2")) {
+        t.Error("62 lines should need a second page")
+    }
+
+    // every xref entry must point at the start of its object
+    m := regexp.MustCompile(`(?s)startxref\n(\d+)\n`).FindSubmatch(doc)
+    if m == nil {
+        t.Fatal("no startxref")
+    }
+    start, _ := strconv.Atoi(string(m[1]))
+    xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[start:], -1)
+    if len(xref) == 0 {
+        t.Fatal("empty xref table")
+    }
+    for i, e := range xref {
+        off, _ := strconv.Atoi(string(e[1]))
+        if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(doc[off:], []byte(want)) {
+            t.Errorf("xref entry %d points at %q", i+1, doc[off:off+10])
+        }
+    }
+}
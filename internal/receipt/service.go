@@ -0,0 +1,85 @@
+package receipt
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/mailer"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+// Service issues receipts for completed orders.
+type Service interface {
+    // ForOrder builds the receipt for o, which must be completed.
+    ForOrder(o *order.Order) (*Receipt, error)
+    // Email sends the receipt for the order to its buyer, with the PDF attached.
+    Email(ctx context.Context, orderID string) error
+}
+
+type service struct {
+    orders  order.Service
+    sellers seller.Service
+    mail    mailer.Mailer
+    now     func() time.Time
+}
+
+func NewService(orders order.Service, sellers seller.Service, mail mailer.Mailer) Service {
+    return &service{orders: orders, sellers: sellers, mail: mail, now: time.Now}
+}
+
+func (s *service) ForOrder(o *order.Order) (*Receipt, error) {
+    if o.Status != order.StatusCompleted {
+        return nil, ErrNotCompleted
+    }
+    sl, err := s.sellers.GetByID(o.SellerID)
+    if err != nil {
+        return nil, fmt.Errorf("seller %s: %w", o.SellerID, err)
+    }
+    return Build(o, sl, s.now())
+}
+
+func (s *service) Email(ctx context.Context, orderID string) error {
+    o, err := s.orders.GetByID(orderID)
+    if err != nil {
+        return err
+    }
+    r, err := s.ForOrder(o)
+    if err != nil {
+        return err
+    }
+    return s.mail.Send(ctx, mailer.Message{
+        To:      []string{r.BuyerEmail},
+        Subject: fmt.Sprintf("Your receipt from %s (%s)", r.Seller.Name, r.Number),
+        Text:    emailText(r),
+        Attachments: []mailer.Attachment{{
+            Filename:    Filename(r),
+            ContentType: "application/pdf",
+            Data:        RenderPDF(r),
+        }},
+    })
+}
+
+// Filename is the name receipts are downloaded and attached under.
+func Filename(r *Receipt) string {
+    return "receipt-" + r.Number + ".pdf"
+}
+
+func emailText(r *Receipt) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "Thanks for your order from %s.\n\n", r.Seller.Name)
+    for _, l := range r.Lines {
+        fmt.Fprintf(&b, "%d x %s  %s\n", l.Quantity, l.Title, l.Total.Decimal())
+    }
+    for _, d := range r.Discounts {
+        fmt.Fprintf(&b, "%s  -%s\n", d.Label(), d.Amount.Decimal())
+    }
+    for _, t := range r.Taxes {
+        fmt.Fprintf(&b, "%s  %s\n", taxLabel(t), t.Amount.Decimal())
+    }
+    fmt.Fprintf(&b, "\nTotal: %s\n", r.Total)
+    fmt.Fprintf(&b, "Receipt %s for order %s is attached.\n", r.Number, r.OrderID)
+    return b.String()
+}
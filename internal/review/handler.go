@@ -0,0 +1,199 @@
+package review
+
+import (
+    "errors"
+    "log"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service) {
+    orders := r.Group("/orders", auth.Middleware())
+
+    // POST /orders/:id/review – the buyer reviews a completed order
+    orders.POST("/:id/review", func(c *gin.Context) {
+        var in Input
+        if err := c.ShouldBindJSON(&in); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        rv, err := svc.Create(c.Param("id"), c.GetString(string(auth.CtxEmailKey)), in)
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, rv)
+    })
+
+    // GET /orders/:id/review – for the buyer, the seller and admins
+    orders.GET("/:id/review", func(c *gin.Context) {
+        rv, err := svc.ForOrder(c.Param("id"))
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        email := c.GetString(string(auth.CtxEmailKey))
+        if rv.UserEmail != email && !auth.IsAdmin(email) {
+            if sl, err := sellers.GetByEmail(email); err != nil || sl.ID != rv.SellerID {
+                reviewError(c, ErrForbidden)
+                return
+            }
+        }
+        c.JSON(http.StatusOK, rv)
+    })
+
+    // GET /sellers/:id/reviews and /listings/:id/reviews – visible reviews,
+    // newest first, paged by limit and cursor
+    r.GET("/sellers/:id/reviews", func(c *gin.Context) {
+        publicList(c, svc, Query{SellerID: c.Param("id")})
+    })
+    r.GET("/listings/:id/reviews", func(c *gin.Context) {
+        publicList(c, svc, Query{ListingID: c.Param("id")})
+    })
+
+    grp := r.Group("/reviews", auth.Middleware())
+
+    // PUT /reviews/:id/reply – the reviewed seller answers publicly
+    grp.PUT("/:id/reply", seller.RequireSeller(sellers), func(c *gin.Context) {
+        var payload struct {
+            Reply string `json:"reply"`
+        }
+        if err := c.ShouldBindJSON(&payload); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        rv, err := svc.Reply(c.Param("id"), seller.FromContext(c).ID, payload.Reply)
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, rv.Public())
+    })
+
+    // POST /reviews/:id/flags – report a review to the admins
+    grp.POST("/:id/flags", func(c *gin.Context) {
+        var payload struct {
+            Reason string `json:"reason"`
+        }
+        if err := c.ShouldBindJSON(&payload); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if _, err := svc.Flag(c.Param("id"), c.GetString(string(auth.CtxEmailKey)), payload.Reason); err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    admin := r.Group("/admin/reviews", auth.Middleware(), auth.RequireAdmin())
+
+    // GET /admin/reviews?flagged=true&hidden=false – the moderation queue
+    admin.GET("", func(c *gin.Context) {
+        q := Query{SellerID: c.Query("sellerId"), ListingID: c.Query("listingId")}
+        if raw := c.Query("flagged"); raw != "" {
+            flagged, err := strconv.ParseBool(raw)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "flagged must be true or false"})
+                return
+            }
+            q.Flagged = flagged
+        }
+        if raw := c.Query("hidden"); raw != "" {
+            hidden, err := strconv.ParseBool(raw)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "hidden must be true or false"})
+                return
+            }
+            q.Hidden = &hidden
+        }
+        if err := pageFromQuery(c, &q); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        page, err := svc.List(q)
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, page)
+    })
+
+    admin.POST("/:id/hide", func(c *gin.Context) {
+        var payload struct {
+            Reason string `json:"reason"`
+        }
+        if err := c.ShouldBindJSON(&payload); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        rv, err := svc.Hide(c.Param("id"), c.GetString(string(auth.CtxEmailKey)), payload.Reason)
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, rv)
+    })
+
+    admin.POST("/:id/unhide", func(c *gin.Context) {
+        rv, err := svc.Unhide(c.Param("id"))
+        if err != nil {
+            reviewError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, rv)
+    })
+}
+
+// publicList writes a page of q's visible reviews without buyers' emails
+// or moderation details.
+func publicList(c *gin.Context, svc Service, q Query) {
+    visible := false
+    q.Hidden = &visible
+    if err := pageFromQuery(c, &q); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    page, err := svc.List(q)
+    if err != nil {
+        reviewError(c, err)
+        return
+    }
+    for i, rv := range page.Reviews {
+        page.Reviews[i] = rv.Public()
+    }
+    c.JSON(http.StatusOK, page)
+}
+
+func pageFromQuery(c *gin.Context, q *Query) error {
+    q.Cursor = c.Query("cursor")
+    if raw := c.Query("limit"); raw != "" {
+        n, err := strconv.Atoi(raw)
+        if err != nil {
+            return errors.New("limit must be a number")
+        }
+        q.Limit = n
+    }
+    return nil
+}
+
+func reviewError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, ErrInvalidReview):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrReviewNotFound), errors.Is(err, ErrOrderNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrForbidden):
+        c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrNotCompleted), errors.Is(err, ErrAlreadyReviewed):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        log.Printf("review request failed: %v", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
@@ -0,0 +1,7 @@
+package review
+
+import "gorm.io/gorm"
+
+func Migrate(db *gorm.DB) error {
+    return db.AutoMigrate(&Review{}, &Flag{})
+}
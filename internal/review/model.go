@@ -0,0 +1,98 @@
+// Package review lets buyers rate and review completed orders, sellers
+// reply, and admins hide reviews that others flag. Sellers and listings
+// keep the average rating and count of their visible reviews.
+package review
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "gorm.io/datatypes"
+)
+
+var (
+    ErrInvalidReview   = errors.New("invalid review")
+    ErrReviewNotFound  = errors.New("review not found")
+    ErrOrderNotFound   = errors.New("order not found")
+    ErrNotCompleted    = errors.New("orders can be reviewed once they are completed")
+    ErrAlreadyReviewed = errors.New("order already reviewed")
+    ErrForbidden       = errors.New("forbidden")
+)
+
+const (
+    MinRating = 1
+    MaxRating = 5
+
+    maxComment = 2000
+    maxReply   = 2000
+    maxReason  = 500
+)
+
+// Review is a buyer's rating of one completed order. It counts towards
+// the seller and every listing on the order.
+type Review struct {
+    ID         string                      `json:"id" gorm:"type:uuid;primaryKey"`
+    OrderID    string                      `json:"orderId" gorm:"type:uuid;not null;uniqueIndex"`
+    SellerID   string                      `json:"sellerId" gorm:"type:uuid;not null;index"`
+    UserEmail  string                      `json:"userEmail,omitempty" gorm:"type:varchar(100);not null;index"` // left out of public lists
+    ListingIDs datatypes.JSONSlice[string] `json:"listingIds" gorm:"type:jsonb;not null;default:'[]'"`
+    Rating     int                         `json:"rating" gorm:"not null"`
+    Comment    string                      `json:"comment" gorm:"type:text;not null;default:''"`
+
+    Reply     string     `json:"reply,omitempty" gorm:"type:text;not null;default:''"`
+    RepliedAt *time.Time `json:"repliedAt,omitempty"`
+
+    // moderation: flags come from anyone signed in, hiding from admins
+    FlagCount    int        `json:"flagCount,omitempty" gorm:"not null;default:0"`
+    Hidden       bool       `json:"hidden,omitempty" gorm:"not null;default:false;index"`
+    HiddenBy     string     `json:"hiddenBy,omitempty" gorm:"type:varchar(100);not null;default:''"`
+    HiddenReason string     `json:"hiddenReason,omitempty" gorm:"type:text;not null;default:''"`
+    HiddenAt     *time.Time `json:"hiddenAt,omitempty"`
+
+    CreatedAt time.Time `json:"createdAt" gorm:"index"`
+    UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Flag is one user's report of a review.
+type Flag struct {
+    ID        string    `json:"id" gorm:"type:uuid;primaryKey"`
+    ReviewID  string    `json:"reviewId" gorm:"type:uuid;not null;uniqueIndex:idx_review_flag_reporter,priority:1"`
+    Reporter  string    `json:"reporter" gorm:"type:varchar(100);not null;uniqueIndex:idx_review_flag_reporter,priority:2"`
+    Reason    string    `json:"reason" gorm:"type:text;not null"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+func (Flag) TableName() string { return "review_flags" }
+
+// Input is what a buyer sends to review an order.
+type Input struct {
+    Rating  int    `json:"rating"`
+    Comment string `json:"comment"`
+}
+
+func (in *Input) normalize() error {
+    in.Comment = strings.TrimSpace(in.Comment)
+    if in.Rating < MinRating || in.Rating > MaxRating {
+        return fmt.Errorf("%w: rating must be between %d and %d", ErrInvalidReview, MinRating, MaxRating)
+    }
+    return checkLength("comment", in.Comment, maxComment)
+}
+
+// checkLength rejects text longer than max characters.
+func checkLength(name, text string, max int) error {
+    if utf8.RuneCountInString(text) > max {
+        return fmt.Errorf("%w: %s can be at most %d characters", ErrInvalidReview, name, max)
+    }
+    return nil
+}
+
+// Public is the review as anyone may see it: without the buyer's email
+// or moderation details.
+func (r Review) Public() Review {
+    r.UserEmail, r.FlagCount = "", 0
+    r.HiddenBy, r.HiddenReason, r.HiddenAt = "", "", nil
+    return r
+}
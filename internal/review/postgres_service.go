@@ -0,0 +1,311 @@
+package review
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/datatypes"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/event"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/order"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+type postgresService struct {
+    db *gorm.DB
+}
+
+func NewPostgresService(db *gorm.DB) Service {
+    return &postgresService{db: db}
+}
+
+func emit(ev event.Event) {
+    go func() {
+        event.Bus <- ev
+    }()
+}
+
+// lockSeller serializes review changes for one seller, so each rating
+// refresh sees the others' committed reviews.
+func lockSeller(tx *gorm.DB, sellerID string) error {
+    var sl seller.Seller
+    return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&sl, "id = ?", sellerID).Error
+}
+
+// Ratings are averaged to two decimals over visible reviews; zero
+// reviews give a zero rating.
+const (
+    sellerRatingSQL = `UPDATE sellers SET rating_avg = r.avg, rating_count = r.count
+        FROM (SELECT coalesce(round(avg(rating), 2), 0) AS avg, count(*) AS count
+              FROM reviews WHERE seller_id = ? AND NOT hidden) AS r
+        WHERE sellers.id = ?`
+    listingRatingSQL = `UPDATE listings SET rating_avg = r.avg, rating_count = r.count
+        FROM (SELECT coalesce(round(avg(rating), 2), 0) AS avg, count(*) AS count
+              FROM reviews WHERE listing_ids @> ?::jsonb AND NOT hidden) AS r
+        WHERE listings.id = ?`
+)
+
+// refreshRatings recomputes the ratings of the seller and listings r
+// counts towards.
+func refreshRatings(tx *gorm.DB, r *Review) error {
+    if err := tx.Exec(sellerRatingSQL, r.SellerID, r.SellerID).Error; err != nil {
+        return err
+    }
+    for _, id := range r.ListingIDs {
+        ids, _ := json.Marshal([]string{id})
+        if err := tx.Exec(listingRatingSQL, string(ids), id).Error; err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// orderListings are the distinct listings on an order; orders from
+// before lines were recorded only have their listing IDs.
+func orderListings(tx *gorm.DB, o *order.Order) ([]string, error) {
+    var ids []string
+    if err := tx.Model(&order.Line{}).Where("order_id = ?", o.ID).Pluck("listing_id", &ids).Error; err != nil {
+        return nil, err
+    }
+    if len(ids) == 0 && len(o.ListingIDs) > 0 {
+        if err := json.Unmarshal(o.ListingIDs, &ids); err != nil {
+            return nil, err
+        }
+    }
+    slices.Sort(ids)
+    return slices.Compact(ids), nil
+}
+
+func (s *postgresService) Create(orderID, userEmail string, in Input) (*Review, error) {
+    if err := in.normalize(); err != nil {
+        return nil, err
+    }
+    var r *Review
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        var o order.Order
+        err := tx.First(&o, "id = ?", orderID).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return ErrOrderNotFound
+        }
+        if err != nil {
+            return err
+        }
+        if o.UserEmail != userEmail {
+            return ErrForbidden
+        }
+        if o.Status != order.StatusCompleted {
+            return fmt.Errorf("%w: this one is %s", ErrNotCompleted, o.Status)
+        }
+        listingIDs, err := orderListings(tx, &o)
+        if err != nil {
+            return err
+        }
+        if err := lockSeller(tx, o.SellerID); err != nil {
+            return err
+        }
+        r = &Review{
+            ID:         uuid.NewString(),
+            OrderID:    o.ID,
+            SellerID:   o.SellerID,
+            UserEmail:  userEmail,
+            ListingIDs: datatypes.JSONSlice[string](listingIDs),
+            Rating:     in.Rating,
+            Comment:    in.Comment,
+        }
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(r)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return ErrAlreadyReviewed
+        }
+        return refreshRatings(tx, r)
+    })
+    if err != nil {
+        return nil, err
+    }
+    emit(event.Event{Type: "ReviewPosted", Data: *r})
+    return r, nil
+}
+
+func (s *postgresService) Get(id string) (*Review, error) {
+    var r Review
+    err := s.db.First(&r, "id = ?", id).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrReviewNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &r, nil
+}
+
+func (s *postgresService) ForOrder(orderID string) (*Review, error) {
+    var r Review
+    err := s.db.First(&r, "order_id = ?", orderID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrReviewNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &r, nil
+}
+
+func (s *postgresService) List(q Query) (*Page, error) {
+    if err := q.validate(); err != nil {
+        return nil, err
+    }
+    tx := s.db.Model(&Review{})
+    if q.SellerID != "" {
+        tx = tx.Where("seller_id = ?", q.SellerID)
+    }
+    if q.ListingID != "" {
+        ids, _ := json.Marshal([]string{q.ListingID})
+        tx = tx.Where("listing_ids @> ?::jsonb", string(ids))
+    }
+    if q.Hidden != nil {
+        tx = tx.Where("hidden = ?", *q.Hidden)
+    }
+    if q.Flagged {
+        tx = tx.Where("flag_count > 0")
+    }
+    if q.Cursor != "" {
+        c, err := decodeCursor(q.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        tx = tx.Where("(created_at, id) < (?, ?)", c.CreatedAt, c.ID)
+    }
+
+    // one extra row tells us whether there's another page
+    list := []Review{}
+    if err := tx.Order("created_at DESC, id DESC").Limit(q.Limit + 1).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    page := &Page{Reviews: list}
+    if len(list) > q.Limit {
+        page.Reviews = list[:q.Limit]
+        last := page.Reviews[q.Limit-1]
+        page.NextCursor = cursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+    }
+    return page, nil
+}
+
+// update locks review id, lets change modify it, and saves it along with
+// the ratings it affects. change returns the columns it set.
+func (s *postgresService) update(id string, change func(r *Review) ([]string, error)) (*Review, error) {
+    var r Review
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        err := tx.First(&r, "id = ?", id).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return ErrReviewNotFound
+        }
+        if err != nil {
+            return err
+        }
+        if err := lockSeller(tx, r.SellerID); err != nil {
+            return err
+        }
+        // reload now that no other change to the seller's reviews is running
+        if err := tx.First(&r, "id = ?", id).Error; err != nil {
+            return err
+        }
+        wasHidden := r.Hidden
+        cols, err := change(&r)
+        if err != nil {
+            return err
+        }
+        if err := tx.Model(&r).Select(append(cols, "updated_at")).Updates(&r).Error; err != nil {
+            return err
+        }
+        if r.Hidden != wasHidden {
+            return refreshRatings(tx, &r)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &r, nil
+}
+
+func (s *postgresService) Reply(id, sellerID, text string) (*Review, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return nil, fmt.Errorf("%w: reply is required", ErrInvalidReview)
+    }
+    if err := checkLength("reply", text, maxReply); err != nil {
+        return nil, err
+    }
+    r, err := s.update(id, func(r *Review) ([]string, error) {
+        if r.SellerID != sellerID {
+            return nil, ErrForbidden
+        }
+        now := time.Now()
+        r.Reply, r.RepliedAt = text, &now
+        return []string{"reply", "replied_at"}, nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    emit(event.Event{Type: "ReviewReplied", Data: *r})
+    return r, nil
+}
+
+func (s *postgresService) Flag(id, reporter, reason string) (*Review, error) {
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return nil, fmt.Errorf("%w: reason is required", ErrInvalidReview)
+    }
+    if err := checkLength("reason", reason, maxReason); err != nil {
+        return nil, err
+    }
+    var r Review
+    err := s.db.Transaction(func(tx *gorm.DB) error {
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&r, "id = ?", id).Error
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return ErrReviewNotFound
+        }
+        if err != nil {
+            return err
+        }
+        f := Flag{ID: uuid.NewString(), ReviewID: id, Reporter: reporter, Reason: reason}
+        res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&f)
+        if res.Error != nil || res.RowsAffected == 0 {
+            return res.Error // flagged it already
+        }
+        r.FlagCount++
+        return tx.Model(&r).Update("flag_count", r.FlagCount).Error
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &r, nil
+}
+
+func (s *postgresService) Hide(id, admin, reason string) (*Review, error) {
+    reason = strings.TrimSpace(reason)
+    if err := checkLength("reason", reason, maxReason); err != nil {
+        return nil, err
+    }
+    return s.update(id, func(r *Review) ([]string, error) {
+        now := time.Now()
+        r.Hidden, r.HiddenBy, r.HiddenReason, r.HiddenAt = true, admin, reason, &now
+        return []string{"hidden", "hidden_by", "hidden_reason", "hidden_at"}, nil
+    })
+}
+
+func (s *postgresService) Unhide(id string) (*Review, error) {
+    return s.update(id, func(r *Review) ([]string, error) {
+        r.Hidden, r.HiddenBy, r.HiddenReason, r.HiddenAt = false, "", "", nil
+        return []string{"hidden", "hidden_by", "hidden_reason", "hidden_at"}, nil
+    })
+}
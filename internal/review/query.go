@@ -0,0 +1,64 @@
+package review
+
+import (
+    "encoding/base64"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    DefaultPageSize = 20
+    MaxPageSize     = 100
+)
+
+// Query filters a list of reviews, newest first. Empty fields don't filter.
+type Query struct {
+    SellerID  string
+    ListingID string
+    Hidden    *bool // public lists only show visible reviews
+    Flagged   bool  // only reviews someone flagged
+    Limit     int   // page size, DefaultPageSize if 0
+    Cursor    string
+}
+
+// Page is one page of a Query's results. NextCursor is empty on the last
+// page.
+type Page struct {
+    Reviews    []Review `json:"reviews"`
+    NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+func (q *Query) validate() error {
+    switch {
+    case q.Limit == 0:
+        q.Limit = DefaultPageSize
+    case q.Limit < 0 || q.Limit > MaxPageSize:
+        return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidReview, MaxPageSize)
+    }
+    return nil
+}
+
+// cursor marks the last review of a page by its place in the ordering.
+type cursor struct {
+    CreatedAt time.Time
+    ID        string
+}
+
+func (c cursor) encode() string {
+    return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.ID))
+}
+
+func decodeCursor(s string) (cursor, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return cursor{}, fmt.Errorf("%w: bad cursor", ErrInvalidReview)
+    }
+    ts, id, ok := strings.Cut(string(raw), ":")
+    nanos, err := strconv.ParseInt(ts, 10, 64)
+    if !ok || err != nil || id == "" {
+        return cursor{}, fmt.Errorf("%w: bad cursor", ErrInvalidReview)
+    }
+    return cursor{CreatedAt: time.Unix(0, nanos), ID: id}, nil
+}
@@ -0,0 +1,72 @@
+package review
+
+import (
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestInputNormalize(t *testing.T) {
+    for _, tc := range []struct {
+        name string
+        in   Input
+        ok   bool
+    }{
+        {"lowest", Input{Rating: 1}, true},
+        {"highest with comment", Input{Rating: 5, Comment: "  best biryani in town \n"}, true},
+        {"no rating", Input{Comment: "good"}, false},
+        {"too high", Input{Rating: 6}, false},
+        {"long comment", Input{Rating: 4, Comment: strings.Repeat("é", maxComment+1)}, false},
+        {"long comment after trimming", Input{Rating: 4, Comment: " " + strings.Repeat("é", maxComment) + " "}, true},
+    } {
+        err := tc.in.normalize()
+        if tc.ok && err != nil {
+            t.Errorf("%s: %v", tc.name, err)
+        }
+        if !tc.ok && !errors.Is(err, ErrInvalidReview) {
+            t.Errorf("%s: got %v, want ErrInvalidReview", tc.name, err)
+        }
+    }
+
+    in := Input{Rating: 5, Comment: "  best biryani in town \n"}
+    in.normalize()
+    if in.Comment != "best biryani in town" {
+        t.Errorf("comment %q was not trimmed", in.Comment)
+    }
+}
+
+func TestPublic(t *testing.T) {
+    now := time.Now()
+    r := Review{
+        ID: "r1", UserEmail: "buyer@example.com", Rating: 2, Comment: "cold",
+        Reply: "sorry!", FlagCount: 3, Hidden: true, HiddenBy: "admin@example.com",
+        HiddenReason: "abusive", HiddenAt: &now,
+    }
+    p := r.Public()
+    if p.UserEmail != "" || p.FlagCount != 0 || p.HiddenBy != "" || p.HiddenReason != "" || p.HiddenAt != nil {
+        t.Errorf("public review leaks private fields: %+v", p)
+    }
+    if p.Rating != 2 || p.Comment != "cold" || p.Reply != "sorry!" {
+        t.Errorf("public review lost its content: %+v", p)
+    }
+    if r.UserEmail == "" {
+        t.Error("Public changed the original review")
+    }
+}
+
+func TestCursor(t *testing.T) {
+    c := cursor{CreatedAt: time.Date(2026, 3, 14, 18, 0, 0, 123456000, time.UTC), ID: "0b6c1a9e-3f1d-4c55-9a8e-2d7f3e6b1c40"}
+    got, err := decodeCursor(c.encode())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+        t.Errorf("decoded %+v, want %+v", got, c)
+    }
+    for _, bad := range []string{"%%%", "bm90LWEtbnVtYmVy", "MTIz"} { // not base64, "not-a-number", "123"
+        if _, err := decodeCursor(bad); !errors.Is(err, ErrInvalidReview) {
+            t.Errorf("decodeCursor(%q) = %v, want ErrInvalidReview", bad, err)
+        }
+    }
+}
@@ -0,0 +1,20 @@
+package review
+
+// Service manages reviews. Every change that affects which reviews are
+// visible also updates the ratings of the seller and listings reviewed.
+type Service interface {
+    // Create reviews a completed order; only its buyer can, and only once.
+    Create(orderID, userEmail string, in Input) (*Review, error)
+    Get(id string) (*Review, error)
+    ForOrder(orderID string) (*Review, error)
+    List(q Query) (*Page, error)
+
+    // Reply sets the seller's public reply, replacing any earlier one.
+    Reply(id, sellerID, text string) (*Review, error)
+    // Flag reports a review to the admins; a second flag from the same
+    // reporter is ignored.
+    Flag(id, reporter, reason string) (*Review, error)
+    // Hide takes a review out of public lists and ratings; Unhide puts it back.
+    Hide(id, admin, reason string) (*Review, error)
+    Unhide(id string) (*Review, error)
+}
@@ -20,6 +20,10 @@ func RegisterRoutes(r *gin.Engine, svc Service, store auth.Store) {
             c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
             return
         }
+        if err := s.TaxProfile.Normalize(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
         if err := svc.Register(s); err != nil {
             c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
             return
@@ -68,6 +72,26 @@ func RegisterRoutes(r *gin.Engine, svc Service, store auth.Store) {
         c.JSON(http.StatusOK, gin.H{"token": signed})
     })
 
+    // PUT /sellers/me/tax-profile – set where the seller sells from and
+    // whether their prices include tax
+    grp.PUT("/me/tax-profile", auth.Middleware(), RequireSeller(svc), func(c *gin.Context) {
+        var p TaxProfile
+        if err := c.ShouldBindJSON(&p); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := p.Normalize(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        sl, err := svc.UpdateTaxProfile(FromContext(c).ID, p)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, sl)
+    })
+
     // Fetch a seller by ID
     grp.GET("/:id", func(c *gin.Context) {
         id := c.Param("id")
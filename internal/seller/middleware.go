@@ -0,0 +1,31 @@
+package seller
+
+import (
+    "net/http"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/gin-gonic/gin"
+)
+
+const ctxSellerKey = "seller"
+
+// RequireSeller resolves the authenticated email to a seller account and
+// rejects callers that aren't sellers. It must run after auth.Middleware.
+func RequireSeller(svc Service) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        sl, err := svc.GetByEmail(c.GetString(string(auth.CtxEmailKey)))
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "seller account required"})
+            return
+        }
+        c.Set(ctxSellerKey, sl)
+        c.Next()
+    }
+}
+
+// FromContext returns the seller stored by RequireSeller, or nil.
+func FromContext(c *gin.Context) *Seller {
+    sl, _ := c.Get(ctxSellerKey)
+    s, _ := sl.(*Seller)
+    return s
+}
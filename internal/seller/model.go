@@ -1,6 +1,10 @@
 package seller
 
 import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strings"
     "time"
 
     "gorm.io/gorm"
@@ -16,5 +20,39 @@ type Seller struct {
     CreatedAt time.Time      `json:"created_at"`
     UpdatedAt time.Time      `json:"updated_at"`
     DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`                // optional soft‑delete
+    TaxProfile
+
+    // over the seller's visible reviews, kept up to date by the review package
+    RatingAvg   float64 `json:"ratingAvg" gorm:"type:numeric(3,2);not null;default:0"`
+    RatingCount int     `json:"ratingCount" gorm:"not null;default:0"`
+}
+
+var ErrInvalidTaxProfile = errors.New("invalid tax profile")
+
+// TaxProfile is where a seller sells from, which decides the taxes on
+// their orders (see the tax package), and whether their prices include
+// those taxes. Sellers without a country charge no tax.
+type TaxProfile struct {
+    Country      string `json:"country" gorm:"type:varchar(2);not null;default:''"` // ISO 3166-1 alpha-2
+    Region       string `json:"region" gorm:"type:varchar(3);not null;default:''"`  // ISO 3166-2 subdivision, e.g. "ON"
+    TaxInclusive bool   `json:"taxInclusive" gorm:"not null;default:false"`
+}
+
+var (
+    countryRe = regexp.MustCompile(`^[A-Z]{2}$`)
+    regionRe  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+)
+
+// Normalize upper-cases the codes and checks them.
+func (p *TaxProfile) Normalize() error {
+    p.Country = strings.ToUpper(strings.TrimSpace(p.Country))
+    p.Region = strings.ToUpper(strings.TrimSpace(p.Region))
+    if p.Country != "" && !countryRe.MatchString(p.Country) {
+        return fmt.Errorf("%w: country must be a 2-letter code", ErrInvalidTaxProfile)
+    }
+    if p.Region != "" && (p.Country == "" || !regionRe.MatchString(p.Region)) {
+        return fmt.Errorf("%w: region must be up to 3 letters or digits, with a country", ErrInvalidTaxProfile)
+    }
+    return nil
 }
 
@@ -38,6 +38,7 @@ func (s *postgresService) Register(sl Seller) error {
     }
     sl.Password = string(h)
     sl.Verified = false
+    sl.RatingAvg, sl.RatingCount = 0, 0
 
     return s.db.Create(&sl).Error
 }
@@ -84,3 +85,12 @@ func (s *postgresService) GetByEmail(email string) (*Seller, error) {
     }
     return &sl, nil
 }
+
+func (s *postgresService) UpdateTaxProfile(id string, p TaxProfile) (*Seller, error) {
+    err := s.db.Model(&Seller{}).Where("id = ?", id).
+        Select("country", "region", "tax_inclusive").Updates(Seller{TaxProfile: p}).Error
+    if err != nil {
+        return nil, err
+    }
+    return s.GetByID(id)
+}
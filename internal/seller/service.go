@@ -7,4 +7,5 @@ type Service interface {
     ListAll() []Seller
     Authenticate(email, password string) (*Seller, error)
     GetByEmail(email string) (*Seller, error)
+    UpdateTaxProfile(id string, p TaxProfile) (*Seller, error)
 }
@@ -0,0 +1,174 @@
+package tax
+
+import (
+    "slices"
+
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// whole is the Percent of 100%.
+const whole = 100 * percentScale
+
+// Item is something taxed on an order: a line's total and its category.
+type Item struct {
+    Category string
+    Amount   money.Money
+}
+
+// Calculate works out the taxes on items sold by a seller in country and
+// region. Sellers without a country are charged no tax.
+func Calculate(db *gorm.DB, country, region string, inclusive bool, items []Item) ([]Line, error) {
+    if country == "" {
+        return nil, nil
+    }
+    js, err := InEffect(db, country, region)
+    if err != nil {
+        return nil, err
+    }
+    return Compute(js, inclusive, items)
+}
+
+// InEffect loads the jurisdictions whose taxes apply in country and
+// region: the country's, then the region's.
+func InEffect(db *gorm.DB, country, region string) ([]Jurisdiction, error) {
+    var js []Jurisdiction
+    err := db.Preload("Rates").
+        Where("country = ? AND region IN ?", country, []string{"", region}).
+        Order("region").Find(&js).Error
+    if err != nil {
+        return nil, err
+    }
+    if len(js) == 2 && js[1].ReplacesCountry {
+        js = js[1:]
+    }
+    return js, nil
+}
+
+// Compute works out the taxes on items under the jurisdictions js. Items
+// of a category are taxed together, and each tax's lines are merged across
+// categories. With inclusive prices the taxes are backed out of the
+// amounts; otherwise they are charged on top.
+func Compute(js []Jurisdiction, inclusive bool, items []Item) ([]Line, error) {
+    if len(items) == 0 {
+        return nil, nil
+    }
+    currency := items[0].Amount.Currency
+    byCategory := make(map[string]int64)
+    for _, it := range items {
+        if it.Amount.Currency != currency {
+            return nil, money.ErrCurrencyMismatch
+        }
+        cat := it.Category
+        if cat == "" {
+            cat = CategoryPrepared
+        }
+        byCategory[cat] += it.Amount.Amount
+    }
+    categories := make([]string, 0, len(byCategory))
+    for cat := range byCategory {
+        categories = append(categories, cat)
+    }
+    slices.Sort(categories)
+
+    var lines []Line
+    for _, cat := range categories {
+        for _, l := range taxCategory(js, cat, inclusive, byCategory[cat]) {
+            l.Taxable.Currency, l.Amount.Currency = currency, currency
+            lines = merge(lines, l)
+        }
+    }
+    return lines, nil
+}
+
+// taxCategory taxes amount, all of one category.
+func taxCategory(js []Jurisdiction, category string, inclusive bool, amount int64) []Line {
+    var lines []Line
+    var combined int64
+    for _, j := range js {
+        for _, r := range ratesFor(j, category) {
+            lines = append(lines, Line{Jurisdiction: j.Name, Name: r.Name, Percent: r.Percent, Included: inclusive})
+            combined += int64(r.Percent)
+        }
+    }
+    if len(lines) == 0 {
+        return nil
+    }
+
+    base := amount
+    if inclusive {
+        base = divRound(amount*whole, whole+combined)
+    }
+    var taxed int64
+    for i := range lines {
+        lines[i].Taxable.Amount = base
+        lines[i].Amount.Amount = divRound(base*int64(lines[i].Percent), whole)
+        taxed += lines[i].Amount.Amount
+    }
+    if inclusive {
+        // the taxes and base add back up to the price; the last tax
+        // absorbs the rounding
+        lines[len(lines)-1].Amount.Amount += amount - base - taxed
+    }
+    return lines
+}
+
+// ratesFor picks each of j's taxes' rate for category, falling back to the
+// rate without one. Zero rates are left out.
+func ratesFor(j Jurisdiction, category string) []Rate {
+    var names []string
+    picked := make(map[string]Rate)
+    for _, r := range j.Rates {
+        if r.Category != "" && r.Category != category {
+            continue
+        }
+        cur, ok := picked[r.Name]
+        if !ok {
+            names = append(names, r.Name)
+        }
+        if !ok || cur.Category == "" {
+            picked[r.Name] = r
+        }
+    }
+    var out []Rate
+    for _, n := range names {
+        if r := picked[n]; r.Percent > 0 {
+            out = append(out, r)
+        }
+    }
+    return out
+}
+
+// merge adds l to lines, combining it with a line for the same tax at the
+// same rate.
+func merge(lines []Line, l Line) []Line {
+    for i := range lines {
+        m := &lines[i]
+        if m.Jurisdiction == l.Jurisdiction && m.Name == l.Name && m.Percent == l.Percent {
+            m.Taxable.Amount += l.Taxable.Amount
+            m.Amount.Amount += l.Amount.Amount
+            return lines
+        }
+    }
+    return append(lines, l)
+}
+
+// divRound divides, rounding halves away from zero.
+func divRound(n, d int64) int64 {
+    if n < 0 {
+        return -divRound(-n, d)
+    }
+    return (n + d/2) / d
+}
+
+// Added sums the taxes charged on top of the prices.
+func Added(lines []Line, currency string) money.Money {
+    sum := money.New(0, currency)
+    for _, l := range lines {
+        if !l.Included {
+            sum.Amount += l.Amount.Amount
+        }
+    }
+    return sum
+}
@@ -0,0 +1,124 @@
+package tax
+
+import (
+    "encoding/json"
+    "errors"
+    "testing"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+func cad(n int64) money.Money { return money.New(n, "CAD") }
+
+func TestParsePercent(t *testing.T) {
+    for _, tc := range []struct {
+        in   string
+        want Percent
+        str  string
+    }{
+        {"13", 130000, "13"},
+        {"9.975", 99750, "9.975"},
+        {" 0.5 ", 5000, "0.5"},
+        {"100", 1000000, "100"},
+        {"0", 0, "0"},
+    } {
+        got, err := ParsePercent(tc.in)
+        if err != nil || got != tc.want || got.String() != tc.str {
+            t.Errorf("ParsePercent(%q) = %d (%s), %v; want %d", tc.in, got, got, err, tc.want)
+        }
+    }
+    for _, bad := range []string{"", "-5", "100.5", "1.23456", "abc", "5%"} {
+        if _, err := ParsePercent(bad); !errors.Is(err, ErrInvalidJurisdiction) {
+            t.Errorf("ParsePercent(%q): err = %v", bad, err)
+        }
+    }
+
+    var r Rate
+    if err := json.Unmarshal([]byte(`{"name":"QST","percent":"9.975"}`), &r); err != nil || r.Percent != 99750 {
+        t.Errorf("unmarshal string percent: %+v, %v", r, err)
+    }
+    if err := json.Unmarshal([]byte(`{"name":"GST","percent":5}`), &r); err != nil || r.Percent != 50000 {
+        t.Errorf("unmarshal number percent: %+v, %v", r, err)
+    }
+    if b, _ := json.Marshal(Percent(99750)); string(b) != `"9.975"` {
+        t.Errorf("marshal = %s", b)
+    }
+}
+
+func TestComputeExclusive(t *testing.T) {
+    ontario := []Jurisdiction{{Name: "Ontario", Rates: []Rate{
+        {Name: "HST", Percent: 130000},
+        {Name: "HST", Category: CategoryPackaged, Percent: 0},
+    }}}
+    lines, err := Compute(ontario, false, []Item{
+        {Amount: cad(1000)},
+        {Category: CategoryPrepared, Amount: cad(255)},
+        {Category: CategoryPackaged, Amount: cad(550)},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    // 13% of 12.55 is 1.6315; packaged food is zero-rated
+    want := Line{Jurisdiction: "Ontario", Name: "HST", Percent: 130000, Taxable: cad(1255), Amount: cad(163)}
+    if len(lines) != 1 || lines[0] != want {
+        t.Fatalf("lines = %+v", lines)
+    }
+    if got := Added(lines, "CAD"); got != cad(163) {
+        t.Errorf("added = %v", got)
+    }
+}
+
+func TestComputeInclusive(t *testing.T) {
+    quebec := []Jurisdiction{
+        {Name: "Canada", Rates: []Rate{{Name: "GST", Percent: 50000}}},
+        {Name: "Quebec", Rates: []Rate{{Name: "QST", Percent: 99750}}},
+    }
+    lines, err := Compute(quebec, true, []Item{{Amount: cad(1000)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(lines) != 2 {
+        t.Fatalf("lines = %+v", lines)
+    }
+    gst, qst := lines[0], lines[1]
+    // 10.00 = 8.70 + 5% + 9.975%, with QST taking the rounding
+    if gst.Name != "GST" || gst.Taxable != cad(870) || gst.Amount != cad(44) || !gst.Included {
+        t.Errorf("gst = %+v", gst)
+    }
+    if qst.Name != "QST" || qst.Taxable != cad(870) || qst.Amount != cad(86) {
+        t.Errorf("qst = %+v", qst)
+    }
+    if got := Added(lines, "CAD"); !got.IsZero() {
+        t.Errorf("inclusive taxes added %v", got)
+    }
+}
+
+func TestComputeNoRates(t *testing.T) {
+    lines, err := Compute(nil, false, []Item{{Amount: cad(1000)}})
+    if err != nil || len(lines) != 0 {
+        t.Errorf("no jurisdictions: %+v, %v", lines, err)
+    }
+    _, err = Compute(nil, false, []Item{{Amount: cad(1000)}, {Amount: money.New(1000, "USD")}})
+    if !errors.Is(err, money.ErrCurrencyMismatch) {
+        t.Errorf("mixed currencies: err = %v", err)
+    }
+}
+
+func TestNormalizeJurisdiction(t *testing.T) {
+    j := Jurisdiction{Country: " ca", Region: "on ", Name: "Ontario", ReplacesCountry: true,
+        Rates: []Rate{{Name: "HST", Category: " Packaged_Food"}}}
+    if err := j.normalize(); err != nil || j.Country != "CA" || j.Region != "ON" || j.Rates[0].Category != CategoryPackaged {
+        t.Errorf("normalize: %+v, %v", j, err)
+    }
+    for _, bad := range []Jurisdiction{
+        {Country: "CAN", Name: "Canada"},
+        {Country: "CA"},
+        {Country: "CA", Name: "Canada", ReplacesCountry: true},
+        {Country: "CA", Name: "Canada", Rates: []Rate{{Name: "GST", Category: "drinks"}}},
+        {Country: "CA", Name: "Canada", Rates: []Rate{{Name: "GST"}, {Name: "GST"}}},
+    } {
+        if err := bad.normalize(); !errors.Is(err, ErrInvalidJurisdiction) {
+            t.Errorf("normalize(%+v): err = %v", bad, err)
+        }
+    }
+}
@@ -0,0 +1,133 @@
+package tax
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/auth"
+    "github.com/albus-droid/Capstone-Project-Backend/internal/seller"
+)
+
+func RegisterRoutes(r *gin.Engine, svc Service, sellers seller.Service) {
+    admin := r.Group("/admin/tax/jurisdictions", auth.Middleware(), auth.RequireAdmin())
+
+    // GET /admin/tax/jurisdictions – every jurisdiction with its rates
+    admin.GET("", func(c *gin.Context) {
+        js, err := svc.ListJurisdictions()
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, js)
+    })
+
+    // POST /admin/tax/jurisdictions
+    admin.POST("", func(c *gin.Context) {
+        var j Jurisdiction
+        if err := c.ShouldBindJSON(&j); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := svc.CreateJurisdiction(&j); err != nil {
+            jurisdictionError(c, err)
+            return
+        }
+        c.JSON(http.StatusCreated, j)
+    })
+
+    // GET /admin/tax/jurisdictions/:id
+    admin.GET("/:id", func(c *gin.Context) {
+        j, err := svc.GetJurisdiction(c.Param("id"))
+        if err != nil {
+            jurisdictionError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, j)
+    })
+
+    // PUT /admin/tax/jurisdictions/:id – replace it, rates included
+    admin.PUT("/:id", func(c *gin.Context) {
+        var j Jurisdiction
+        if err := c.ShouldBindJSON(&j); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err := svc.UpdateJurisdiction(c.Param("id"), &j); err != nil {
+            jurisdictionError(c, err)
+            return
+        }
+        updated, err := svc.GetJurisdiction(c.Param("id"))
+        if err != nil {
+            jurisdictionError(c, err)
+            return
+        }
+        c.JSON(http.StatusOK, updated)
+    })
+
+    // DELETE /admin/tax/jurisdictions/:id
+    admin.DELETE("/:id", func(c *gin.Context) {
+        if err := svc.DeleteJurisdiction(c.Param("id")); err != nil {
+            jurisdictionError(c, err)
+            return
+        }
+        c.Status(http.StatusNoContent)
+    })
+
+    // GET /sellers/me/tax-report – sales and taxes over completed orders,
+    // this month unless from and to are given
+    r.GET("/sellers/me/tax-report", auth.Middleware(), seller.RequireSeller(sellers), func(c *gin.Context) {
+        now := time.Now().UTC()
+        from, err := reportTime(c, "from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        to, err := reportTime(c, "to", now)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if !from.Before(to) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+            return
+        }
+        rep, err := svc.Report(seller.FromContext(c).ID, from, to)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusOK, rep)
+    })
+}
+
+// reportTime reads the name query parameter as RFC 3339 or YYYY-MM-DD.
+func reportTime(c *gin.Context, name string, def time.Time) (time.Time, error) {
+    raw := c.Query(name)
+    if raw == "" {
+        return def, nil
+    }
+    t, err := time.Parse(time.RFC3339, raw)
+    if err != nil {
+        if t, err = time.Parse(time.DateOnly, raw); err != nil {
+            return t, fmt.Errorf("%s must be RFC 3339 or YYYY-MM-DD", name)
+        }
+    }
+    return t, nil
+}
+
+func jurisdictionError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, ErrInvalidJurisdiction):
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrJurisdictionNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+    case errors.Is(err, ErrJurisdictionExists):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+    default:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+    }
+}
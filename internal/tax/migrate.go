@@ -0,0 +1,7 @@
+package tax
+
+import "gorm.io/gorm"
+
+func Migrate(db *gorm.DB) error {
+    return db.AutoMigrate(&Jurisdiction{}, &Rate{}, &Line{})
+}
@@ -0,0 +1,173 @@
+// Package tax works out the taxes on orders from the seller's location:
+// jurisdictions hold rates per tax category, and sellers price either
+// with tax included or with tax added on top.
+package tax
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+var (
+    ErrInvalidJurisdiction  = errors.New("invalid jurisdiction")
+    ErrJurisdictionNotFound = errors.New("jurisdiction not found")
+    ErrJurisdictionExists   = errors.New("a jurisdiction for this country and region already exists")
+)
+
+// Tax categories listings are filed under. Listings without one are
+// prepared food.
+const (
+    CategoryPrepared = "prepared_food"
+    CategoryPackaged = "packaged_food"
+)
+
+var Categories = []string{CategoryPrepared, CategoryPackaged}
+
+// Jurisdiction is a country, or a region of one, that levies taxes. A
+// region's rates add to its country's unless ReplacesCountry is set, as
+// for a harmonized tax that already includes the national one.
+type Jurisdiction struct {
+    ID              string    `json:"id" gorm:"type:uuid;primaryKey"`
+    Country         string    `json:"country" gorm:"type:varchar(2);not null;uniqueIndex:idx_jurisdiction_place"` // ISO 3166-1 alpha-2
+    Region          string    `json:"region" gorm:"type:varchar(3);not null;default:'';uniqueIndex:idx_jurisdiction_place"` // ISO 3166-2 subdivision, "" for the whole country
+    Name            string    `json:"name" gorm:"type:varchar(100);not null"`
+    ReplacesCountry bool      `json:"replacesCountry" gorm:"not null;default:false"`
+    Rates           []Rate    `json:"rates" gorm:"foreignKey:JurisdictionID"`
+    CreatedAt       time.Time `json:"createdAt"`
+    UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+func (Jurisdiction) TableName() string { return "tax_jurisdictions" }
+
+// Rate is one tax of a jurisdiction. A tax can have a rate per category;
+// the rate without a category applies to every other category.
+type Rate struct {
+    ID             string  `json:"-" gorm:"type:uuid;primaryKey"`
+    JurisdictionID string  `json:"-" gorm:"type:uuid;not null;index"`
+    Name           string  `json:"name" gorm:"type:varchar(50);not null"` // shown on receipts, e.g. "HST"
+    Category       string  `json:"category,omitempty" gorm:"type:varchar(20);not null;default:''"`
+    Percent        Percent `json:"percent" gorm:"not null"`
+}
+
+func (Rate) TableName() string { return "tax_rates" }
+
+// Line is a tax charged on an order. Taxable is the amount it was charged
+// on; with tax-inclusive prices that is the price less all taxes.
+type Line struct {
+    ID           string      `json:"-" gorm:"type:uuid;primaryKey"`
+    OrderID      string      `json:"-" gorm:"type:uuid;not null;index"`
+    Jurisdiction string      `json:"jurisdiction" gorm:"type:varchar(100);not null"`
+    Name         string      `json:"name" gorm:"type:varchar(50);not null"`
+    Percent      Percent     `json:"percent" gorm:"not null"`
+    Taxable      money.Money `json:"taxable" gorm:"embedded;embeddedPrefix:taxable_"`
+    Amount       money.Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+    Included     bool        `json:"included" gorm:"not null;default:false"` // part of the prices rather than added on top
+}
+
+func (Line) TableName() string { return "order_tax_lines" }
+
+// Label names the line for receipts, e.g. "HST 13%".
+func (l Line) Label() string {
+    return l.Name + " " + l.Percent.String() + "%"
+}
+
+// Percent is a tax rate in millionths, so 13% is 130000. It reads and
+// writes as a decimal percentage string such as "9.975".
+type Percent int64
+
+// percentScale is the Percent of 1%.
+const percentScale = 10000
+
+var percentRe = regexp.MustCompile(`^(\d{1,3})(?:\.(\d{1,4}))?$`)
+
+func ParsePercent(s string) (Percent, error) {
+    m := percentRe.FindStringSubmatch(strings.TrimSpace(s))
+    if m == nil {
+        return 0, fmt.Errorf("%w: bad percentage %q", ErrInvalidJurisdiction, s)
+    }
+    whole, _ := strconv.ParseInt(m[1], 10, 64)
+    frac, _ := strconv.ParseInt((m[2] + "0000")[:4], 10, 64)
+    p := Percent(whole*percentScale + frac)
+    if p > 100*percentScale {
+        return 0, fmt.Errorf("%w: percentage %q over 100", ErrInvalidJurisdiction, s)
+    }
+    return p, nil
+}
+
+func (p Percent) String() string {
+    s := strconv.FormatInt(int64(p)/percentScale, 10)
+    if frac := int64(p) % percentScale; frac != 0 {
+        s += "." + strings.TrimRight(fmt.Sprintf("%04d", frac), "0")
+    }
+    return s
+}
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+    return []byte(strconv.Quote(p.String())), nil
+}
+
+// UnmarshalJSON accepts the percentage as a string or a number.
+func (p *Percent) UnmarshalJSON(b []byte) error {
+    s := string(b)
+    if unq, err := strconv.Unquote(s); err == nil {
+        s = unq
+    }
+    v, err := ParsePercent(s)
+    if err != nil {
+        return err
+    }
+    *p = v
+    return nil
+}
+
+var (
+    countryRe = regexp.MustCompile(`^[A-Z]{2}$`)
+    regionRe  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+)
+
+// validPlace reports whether country and region are codes a jurisdiction
+// or seller can use. The region may be empty.
+func validPlace(country, region string) bool {
+    return countryRe.MatchString(country) && (region == "" || regionRe.MatchString(region))
+}
+
+// normalize cleans up and validates j and its rates.
+func (j *Jurisdiction) normalize() error {
+    j.Country = strings.ToUpper(strings.TrimSpace(j.Country))
+    j.Region = strings.ToUpper(strings.TrimSpace(j.Region))
+    j.Name = strings.TrimSpace(j.Name)
+    if !validPlace(j.Country, j.Region) {
+        return fmt.Errorf("%w: country must be a 2-letter code and region up to 3 letters or digits", ErrInvalidJurisdiction)
+    }
+    if j.Name == "" {
+        return fmt.Errorf("%w: name is required", ErrInvalidJurisdiction)
+    }
+    if j.ReplacesCountry && j.Region == "" {
+        return fmt.Errorf("%w: only a region can replace its country's taxes", ErrInvalidJurisdiction)
+    }
+    type key struct{ name, category string }
+    seen := make(map[key]bool)
+    for i := range j.Rates {
+        r := &j.Rates[i]
+        r.Name = strings.TrimSpace(r.Name)
+        r.Category = strings.ToLower(strings.TrimSpace(r.Category))
+        if r.Name == "" {
+            return fmt.Errorf("%w: every rate needs a name", ErrInvalidJurisdiction)
+        }
+        if r.Category != "" && !slices.Contains(Categories, r.Category) {
+            return fmt.Errorf("%w: unknown category %q", ErrInvalidJurisdiction, r.Category)
+        }
+        if seen[key{r.Name, r.Category}] {
+            return fmt.Errorf("%w: %s has two rates for the same category", ErrInvalidJurisdiction, r.Name)
+        }
+        seen[key{r.Name, r.Category}] = true
+    }
+    return nil
+}
@@ -0,0 +1,172 @@
+package tax
+
+import (
+    "errors"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+type postgresService struct {
+    db *gorm.DB
+}
+
+func NewPostgresService(db *gorm.DB) Service {
+    return &postgresService{db: db}
+}
+
+func (s *postgresService) ListJurisdictions() ([]Jurisdiction, error) {
+    var js []Jurisdiction
+    err := s.db.Preload("Rates", func(db *gorm.DB) *gorm.DB {
+        return db.Order("name, category")
+    }).Order("country, region").Find(&js).Error
+    return js, err
+}
+
+func (s *postgresService) GetJurisdiction(id string) (*Jurisdiction, error) {
+    var j Jurisdiction
+    err := s.db.Preload("Rates", func(db *gorm.DB) *gorm.DB {
+        return db.Order("name, category")
+    }).First(&j, "id = ?", id).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrJurisdictionNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &j, nil
+}
+
+func (s *postgresService) CreateJurisdiction(j *Jurisdiction) error {
+    if err := j.normalize(); err != nil {
+        return err
+    }
+    j.ID = uuid.NewString()
+    for i := range j.Rates {
+        j.Rates[i].ID, j.Rates[i].JurisdictionID = uuid.NewString(), j.ID
+    }
+    return placeTaken(s.db.Create(j).Error)
+}
+
+func (s *postgresService) UpdateJurisdiction(id string, j *Jurisdiction) error {
+    if err := j.normalize(); err != nil {
+        return err
+    }
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        var cur Jurisdiction
+        if err := tx.First(&cur, "id = ?", id).Error; err != nil {
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return ErrJurisdictionNotFound
+            }
+            return err
+        }
+        j.ID, j.CreatedAt = id, cur.CreatedAt
+        err := tx.Model(&cur).Select("country", "region", "name", "replaces_country").Updates(j).Error
+        if err := placeTaken(err); err != nil {
+            return err
+        }
+        if err := tx.Where("jurisdiction_id = ?", id).Delete(&Rate{}).Error; err != nil {
+            return err
+        }
+        for i := range j.Rates {
+            j.Rates[i].ID, j.Rates[i].JurisdictionID = uuid.NewString(), id
+        }
+        if len(j.Rates) > 0 {
+            if err := tx.Create(&j.Rates).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (s *postgresService) DeleteJurisdiction(id string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("jurisdiction_id = ?", id).Delete(&Rate{}).Error; err != nil {
+            return err
+        }
+        res := tx.Delete(&Jurisdiction{}, "id = ?", id)
+        if res.Error != nil {
+            return res.Error
+        }
+        if res.RowsAffected == 0 {
+            return ErrJurisdictionNotFound
+        }
+        return nil
+    })
+}
+
+// placeTaken turns a unique violation on country and region into
+// ErrJurisdictionExists.
+func placeTaken(err error) error {
+    if err != nil && (errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "idx_jurisdiction_place")) {
+        return ErrJurisdictionExists
+    }
+    return err
+}
+
+func (s *postgresService) Report(sellerID string, from, to time.Time) (*Report, error) {
+    rep := &Report{SellerID: sellerID, From: from, To: to, Sales: []SalesTotal{}, Taxes: []TaxTotal{}}
+    completed := func(db *gorm.DB) *gorm.DB {
+        return db.Where("o.seller_id = ? AND o.status = ? AND o.completed_at >= ? AND o.completed_at < ? AND o.deleted_at IS NULL",
+            sellerID, "completed", from, to)
+    }
+
+    var sales []struct {
+        Currency string
+        Orders   int
+        Subtotal int64
+        Total    int64
+    }
+    err := s.db.Table("orders AS o").Scopes(completed).
+        Select("o.subtotal_currency AS currency, count(*) AS orders, sum(o.subtotal_amount) AS subtotal, sum(o.total_amount) AS total").
+        Group("o.subtotal_currency").Order("currency").Scan(&sales).Error
+    if err != nil {
+        return nil, err
+    }
+
+    var taxes []struct {
+        Jurisdiction string
+        Name         string
+        Percent      Percent
+        Currency     string
+        Orders       int
+        Taxable      int64
+        Amount       int64
+    }
+    err = s.db.Table("order_tax_lines AS t").Joins("JOIN orders AS o ON o.id = t.order_id").Scopes(completed).
+        Select("t.jurisdiction, t.name, t.percent, t.amount_currency AS currency, count(DISTINCT t.order_id) AS orders, " +
+            "sum(t.taxable_amount) AS taxable, sum(t.amount_amount) AS amount").
+        Group("t.jurisdiction, t.name, t.percent, t.amount_currency").
+        Order("currency, t.jurisdiction, t.name, t.percent").Scan(&taxes).Error
+    if err != nil {
+        return nil, err
+    }
+
+    taxByCurrency := make(map[string]int64)
+    for _, t := range taxes {
+        taxByCurrency[t.Currency] += t.Amount
+        rep.Taxes = append(rep.Taxes, TaxTotal{
+            Jurisdiction: t.Jurisdiction,
+            Name:         t.Name,
+            Percent:      t.Percent,
+            Orders:       t.Orders,
+            Taxable:      money.New(t.Taxable, t.Currency),
+            Amount:       money.New(t.Amount, t.Currency),
+        })
+    }
+    for _, st := range sales {
+        rep.Sales = append(rep.Sales, SalesTotal{
+            Currency: st.Currency,
+            Orders:   st.Orders,
+            Subtotal: money.New(st.Subtotal, st.Currency),
+            Tax:      money.New(taxByCurrency[st.Currency], st.Currency),
+            Total:    money.New(st.Total, st.Currency),
+        })
+    }
+    return rep, nil
+}
@@ -0,0 +1,38 @@
+package tax
+
+import (
+    "time"
+
+    "github.com/albus-droid/Capstone-Project-Backend/internal/money"
+)
+
+// Report sums a seller's sales and the taxes on them, over the orders
+// completed in [From, To). Amounts are as charged, before refunds, and
+// are kept apart per currency.
+type Report struct {
+    SellerID string       `json:"sellerId"`
+    From     time.Time    `json:"from"`
+    To       time.Time    `json:"to"`
+    Sales    []SalesTotal `json:"sales"`
+    Taxes    []TaxTotal   `json:"taxes"`
+}
+
+// SalesTotal is the seller's completed orders in one currency. Tax is
+// the part of Total that is tax, whether included in prices or not.
+type SalesTotal struct {
+    Currency string      `json:"currency"`
+    Orders   int         `json:"orders"`
+    Subtotal money.Money `json:"subtotal"`
+    Tax      money.Money `json:"tax"`
+    Total    money.Money `json:"total"`
+}
+
+// TaxTotal is one tax at one rate, summed over the orders it was charged on.
+type TaxTotal struct {
+    Jurisdiction string      `json:"jurisdiction"`
+    Name         string      `json:"name"`
+    Percent      Percent     `json:"percent"`
+    Orders       int         `json:"orders"`
+    Taxable      money.Money `json:"taxable"`
+    Amount       money.Money `json:"amount"`
+}
@@ -0,0 +1,16 @@
+package tax
+
+import "time"
+
+// Service manages jurisdictions and reports the taxes sellers collected.
+// Changing rates only affects orders placed afterwards; placed orders keep
+// their tax lines.
+type Service interface {
+    ListJurisdictions() ([]Jurisdiction, error)
+    GetJurisdiction(id string) (*Jurisdiction, error)
+    CreateJurisdiction(j *Jurisdiction) error
+    // UpdateJurisdiction replaces the jurisdiction with id, rates included.
+    UpdateJurisdiction(id string, j *Jurisdiction) error
+    DeleteJurisdiction(id string) error
+    Report(sellerID string, from, to time.Time) (*Report, error)
+}